@@ -4,7 +4,14 @@ import (
 	_ "backend/docs"
 	"backend/internal/database"
 	"backend/internal/middlewares"
+	"backend/internal/routes/workspace"
+	"backend/internal/routes/workspace/handlers"
+	"backend/internal/storage"
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -27,23 +34,61 @@ import (
 // @host localhost:3000
 // @BasePath /
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := storage.Init(); err != nil {
+		panic(err)
+	}
+
 	if err := database.InitDatabase(); err != nil {
 		panic(err) // failed to connect or migrate
 	}
 
-	app := fiber.New()
+	// Cancelled on SIGINT or SIGTERM: the server drains and background jobs stop
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	purger := make(chan struct{})
+	go func() {
+		defer close(purger)
+		handlers.RunTrashPurger(ctx)
+	}()
+
+	// Request bodies are streamed, so that middleware.BodyLimit can give routes
+	// their own limit; BodyLimit here only sets how much is buffered up front.
+	// Multipart forms are then parsed from the body it read, not beforehand.
+	app := fiber.New(fiber.Config{
+		BodyLimit:                    middleware.DefaultBodyLimit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
 
 	// set up middleware
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PATCH, DELETE",
+		AllowOrigins:  "*",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, If-Match",
+		AllowMethods:  "GET, POST, PATCH, DELETE",
+		ExposeHeaders: "ETag",
 	}))
 	// sets X-Request-ID header with uuids
 	app.Use(requestid.New())
 	app.Use(middleware.LoggingMiddleware())
+	workspace.SetupWorkspaceBodyLimits(app)
+	app.Use(middleware.BodyLimit(middleware.DefaultBodyLimit))
 	app.Use(middleware.JWTMiddleware)
 	CombineRoutes(app)
 
-	log.Fatal(app.Listen(":3000"))
+	go func() {
+		<-ctx.Done()
+		app.Shutdown()
+	}()
+	if err := app.Listen(":3000"); err != nil {
+		log.Fatal(err)
+	}
+	<-purger
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"backend/internal/models"
+	"backend/internal/routes/assets"
 	"backend/internal/routes/users"
 	"backend/internal/routes/workspace"
 	"embed"
@@ -16,6 +17,7 @@ var welcomePage embed.FS
 func CombineRoutes(app *fiber.App) {
 	users.SetupUserRoutes(app)
 	workspace.SetupWorkspaceRoutes(app)
+	assets.SetupAssetRoutes(app)
 	
 	app.Get("/swagger/*", swagger.WrapHandler)
 
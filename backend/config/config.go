@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/rs/zerolog/log"
 
 	"github.com/joho/godotenv"
@@ -16,6 +18,25 @@ type Config struct {
 	DbPassword string `envconfig:"DB_PASSWORD" default:"postgres" required:"true"`
 	DbName     string `envconfig:"DB_NAME"     default:"prodboardDB" required:"true"`
 	DbPort     string `envconfig:"DB_PORT"     default:"5432" required:"true"`
+
+	StorageBackend string `envconfig:"STORAGE_BACKEND" default:"fs"` // fs | s3
+	StoragePath    string `envconfig:"STORAGE_PATH"    default:"data/assets"`
+	S3Endpoint     string `envconfig:"S3_ENDPOINT"     default:"localhost:9000"`
+	S3Bucket       string `envconfig:"S3_BUCKET"       default:"assets"`
+	S3AccessKey    string `envconfig:"S3_ACCESS_KEY"`
+	S3SecretKey    string `envconfig:"S3_SECRET_KEY"`
+	S3Region       string `envconfig:"S3_REGION"       default:"us-east-1"`
+	S3UseSSL       bool   `envconfig:"S3_USE_SSL"      default:"false"`
+
+	HistoryDepth int `envconfig:"HISTORY_DEPTH" default:"100"` // undoable changes kept per workspace; 0 disables undo
+
+	SnapshotInterval  time.Duration `envconfig:"SNAPSHOT_INTERVAL"  default:"1h"`   // between automatic snapshots; 0 disables them
+	SnapshotKeep      int           `envconfig:"SNAPSHOT_KEEP"      default:"48"`   // automatic snapshots kept per workspace
+	SnapshotRetention time.Duration `envconfig:"SNAPSHOT_RETENTION" default:"720h"` // age at which automatic snapshots expire
+
+	TrashRetention time.Duration `envconfig:"TRASH_RETENTION" default:"720h"` // age at which trashed items are purged; 0 keeps them
+
+	StrokeTolerance float64 `envconfig:"STROKE_TOLERANCE" default:"0.5"` // how far a simplified stroke may stray from the drawn one; 0 keeps every point
 }
 
 var C Config
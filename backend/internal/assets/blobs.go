@@ -0,0 +1,179 @@
+// Package assets keeps the bytes of assets in the object store as
+// content-addressed blobs: identical files are stored once, under the SHA-256
+// of their content, and removed when the last asset using them goes away.
+package assets
+
+import (
+	"backend/internal/database/schemas"
+	"backend/internal/storage"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Point the asset at the blob holding data, taking a reference on it, and
+// write the object if the blob is new. Must run in the transaction that
+// creates the asset: the blob row stays locked until it commits, so that a
+// concurrent Release cannot remove the object in between. An object written
+// by a transaction that rolls back is left behind; it is keyed by its content
+// and will be reused.
+func Store(ctx context.Context, tx *gorm.DB, asset *schemas.Asset, data []byte) error {
+	sum := sha256.Sum256(data)
+	asset.BlobHash = hex.EncodeToString(sum[:])
+	asset.Size = int64(len(data))
+
+	blob := schemas.Blob{
+		Hash:        asset.BlobHash,
+		ContentType: asset.ContentType,
+		Size:        asset.Size,
+		RefCount:    1,
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"ref_count": gorm.Expr("blobs.ref_count + 1")}),
+	}).Create(&blob).Error; err != nil {
+		return err
+	}
+
+	if err := tx.First(&blob, "hash = ?", asset.BlobHash).Error; err != nil {
+		return err
+	}
+	if blob.RefCount > 1 {
+		return nil
+	}
+	return storage.Default.Put(ctx, blob.Hash, bytes.NewReader(data), blob.Size, blob.ContentType)
+}
+
+// Delete the given assets with their thumbnails, unless an image item, a
+// snapshot or a history entry still shows them, and drop their references on
+// blobs. Blobs left unused keep their row until CollectGarbage, which the
+// caller should run once the transaction has committed: an object deleted
+// here would be gone even if the transaction rolled back.
+func Release(ctx context.Context, tx *gorm.DB, assetIDs []string) error {
+	if len(assetIDs) == 0 {
+		return nil
+	}
+
+	var unused []schemas.Asset
+	if err := tx.
+		Where("id IN ?", assetIDs).
+		Where("NOT EXISTS (SELECT 1 FROM image_items WHERE image_items.asset_id = assets.id)").
+		Where("NOT EXISTS (SELECT 1 FROM snapshot_assets WHERE snapshot_assets.asset_id = assets.id)").
+		Where("NOT EXISTS (SELECT 1 FROM history_assets WHERE history_assets.asset_id = assets.id)").
+		Preload("Variants").
+		Find(&unused).Error; err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(unused))
+	refs := make(map[string]int64)
+	for _, asset := range unused {
+		for _, a := range append([]schemas.Asset{asset}, asset.Variants...) {
+			ids = append(ids, a.ID)
+			refs[a.BlobHash]++
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := tx.Where("id IN ?", ids).Delete(&schemas.Asset{}).Error; err != nil {
+		return err
+	}
+
+	for hash, n := range refs {
+		if err := tx.Model(&schemas.Blob{}).
+			Where("hash = ?", hash).
+			Update("ref_count", gorm.Expr("ref_count - ?", n)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete a workspace with everything in it, and release the images that only
+// its items, snapshots and history showed. Like Release, it leaves the blobs
+// to CollectGarbage.
+func DeleteWorkspace(ctx context.Context, tx *gorm.DB, workspaceID uint) error {
+	var assetIDs []string
+	for _, model := range []any{&schemas.ImageItem{}, &schemas.SnapshotAsset{}, &schemas.HistoryAsset{}} {
+		var ids []string
+		if err := tx.Model(model).
+			Where("workspace_id = ?", workspaceID).
+			Distinct().
+			Pluck("asset_id", &ids).Error; err != nil {
+			return err
+		}
+		assetIDs = append(assetIDs, ids...)
+	}
+
+	if err := schemas.DeleteWorkspace(tx, workspaceID); err != nil {
+		return err
+	}
+	return Release(ctx, tx, assetIDs)
+}
+
+// Remove the blobs no asset uses any more, objects included, and return how
+// many went. Each blob goes in a transaction of its own that deletes the row
+// before the object: a concurrent Store of the same content waits for it and
+// then writes the object anew. If the object cannot be deleted, the row stays
+// for the next run.
+func CollectGarbage(ctx context.Context, db *gorm.DB) (int, error) {
+	var hashes []string
+	if err := db.Model(&schemas.Blob{}).Where("ref_count <= 0").Pluck("hash", &hashes).Error; err != nil {
+		return 0, err
+	}
+
+	collected := 0
+	for _, hash := range hashes {
+		deleted := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Where("hash = ? AND ref_count <= 0", hash).Delete(&schemas.Blob{})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			deleted = true
+			return storage.Default.Delete(ctx, hash)
+		})
+		if err != nil {
+			return collected, err
+		}
+		if deleted {
+			collected++
+		}
+	}
+	return collected, nil
+}
+
+type Stats struct {
+	Assets       int64 // including thumbnails
+	Blobs        int64
+	LogicalBytes int64 // size of every asset counted on its own
+	StoredBytes  int64 // size of the blobs actually stored
+}
+
+func CollectStats(db *gorm.DB) (Stats, error) {
+	var stats Stats
+	if err := db.Model(&schemas.Asset{}).
+		Select("COUNT(*) AS assets, COALESCE(SUM(size), 0) AS logical_bytes").
+		Scan(&stats).Error; err != nil {
+		return stats, err
+	}
+
+	var blobs struct {
+		Blobs       int64
+		StoredBytes int64
+	}
+	if err := db.Model(&schemas.Blob{}).
+		Select("COUNT(*) AS blobs, COALESCE(SUM(size), 0) AS stored_bytes").
+		Scan(&blobs).Error; err != nil {
+		return stats, err
+	}
+	stats.Blobs = blobs.Blobs
+	stats.StoredBytes = blobs.StoredBytes
+	return stats, nil
+}
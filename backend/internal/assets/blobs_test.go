@@ -0,0 +1,123 @@
+package assets
+
+import (
+	"backend/internal/database/schemas"
+	"backend/internal/storage"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(
+		sqlite.Open("file::memory:"), &gorm.Config{
+			TranslateError: true,
+			Logger:         logger.Default.LogMode(logger.Silent),
+		},
+	)
+	if err != nil {
+		t.Fatal("failed to connect test database")
+	}
+
+	if err := db.AutoMigrate(&schemas.Asset{}, &schemas.Blob{}, &schemas.ImageItem{}, &schemas.SnapshotAsset{}, &schemas.HistoryAsset{}); err != nil {
+		t.Fatal("failed to migrate test database")
+	}
+	return db
+}
+
+func TestStoreAndRelease(t *testing.T) {
+	db := setupTestDB(t)
+
+	store, err := storage.NewFileStore(t.TempDir())
+	assert.NoError(t, err)
+	storage.Default = store
+	defer func() { storage.Default = nil }()
+
+	ctx := context.Background()
+	create := func(id string, data []byte, variants ...schemas.Asset) schemas.Asset {
+		asset := schemas.Asset{ID: id, ContentType: "image/png", Variants: variants}
+		assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+			for i := range asset.Variants {
+				if err := Store(ctx, tx, &asset.Variants[i], []byte("thumbnail")); err != nil {
+					return err
+				}
+			}
+			if err := Store(ctx, tx, &asset, data); err != nil {
+				return err
+			}
+			return tx.Create(&asset).Error
+		}))
+		return asset
+	}
+
+	first := create("first", []byte("logo"), schemas.Asset{ID: "first-small", ContentType: "image/png"})
+	second := create("second", []byte("logo"))
+	other := create("other", []byte("screenshot"))
+
+	assert.Equal(t, first.BlobHash, second.BlobHash)
+	assert.NotEqual(t, first.BlobHash, other.BlobHash)
+	assert.Equal(t, int64(4), first.Size)
+
+	var blob schemas.Blob
+	assert.NoError(t, db.First(&blob, "hash = ?", first.BlobHash).Error)
+	assert.Equal(t, int64(2), blob.RefCount)
+
+	// Shown on a board in another workspace
+	assert.NoError(t, db.Create(&schemas.ImageItem{ItemID: 1, WorkspaceID: 2, AssetID: "first"}).Error)
+
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return Release(ctx, tx, []string{"first", "second"})
+	}))
+
+	var ids []string
+	db.Model(&schemas.Asset{}).Order("id").Pluck("id", &ids)
+	assert.Equal(t, []string{"first", "first-small", "other"}, ids, "Shown assets should be kept")
+
+	assert.NoError(t, db.First(&blob, "hash = ?", first.BlobHash).Error)
+	assert.Equal(t, int64(1), blob.RefCount)
+
+	assert.NoError(t, db.Delete(&schemas.ImageItem{}, "asset_id = ?", "first").Error)
+
+	// A release that rolls back leaves the objects in place
+	assert.Error(t, db.Transaction(func(tx *gorm.DB) error {
+		if err := Release(ctx, tx, []string{"first"}); err != nil {
+			return err
+		}
+		return errors.New("rolled back")
+	}))
+	collected, err := CollectGarbage(ctx, db)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, collected)
+	r, err := store.Get(ctx, first.BlobHash)
+	if assert.NoError(t, err) {
+		r.Close()
+	}
+
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return Release(ctx, tx, []string{"first"})
+	}))
+
+	db.Model(&schemas.Asset{}).Order("id").Pluck("id", &ids)
+	assert.Equal(t, []string{"other"}, ids, "Thumbnails should go with their asset")
+
+	collected, err = CollectGarbage(ctx, db)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, collected)
+	for _, hash := range []string{first.BlobHash, first.Variants[0].BlobHash} {
+		_, err := store.Get(ctx, hash)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	}
+	r, err = store.Get(ctx, other.BlobHash)
+	if assert.NoError(t, err) {
+		r.Close()
+	}
+
+	stats, err := CollectStats(db)
+	assert.NoError(t, err)
+	assert.Equal(t, Stats{Assets: 1, Blobs: 1, LogicalBytes: 10, StoredBytes: 10}, stats)
+}
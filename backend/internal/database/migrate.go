@@ -0,0 +1,160 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// A schema change, applied and rolled back in its own transaction. Migrations
+// are compiled into the binary and must never change once released; add a
+// new one instead.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// A row of the schema_migrations table, one per applied migration
+type SchemaMigration struct {
+	Version   uint      `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+type MigrationStatus struct {
+	Version   uint
+	Name      string
+	AppliedAt *time.Time // nil while pending
+}
+
+// All migrations, ordered by version
+var migrations = []Migration{
+	{1, "initial_schema", upInitialSchema, downInitialSchema},
+	{2, "image_assets", upImageAssets, downImageAssets},
+	{3, "asset_variants", upAssetVariants, downAssetVariants},
+	{4, "content_addressed_blobs", upContentAddressedBlobs, downContentAddressedBlobs},
+	{5, "item_bounds", upItemBounds, downItemBounds},
+	{6, "item_revisions", upItemRevisions, downItemRevisions},
+	{7, "item_versions", upItemVersions, downItemVersions},
+	{8, "item_history", upItemHistory, downItemHistory},
+	{9, "workspace_snapshots", upWorkspaceSnapshots, downWorkspaceSnapshots},
+	{10, "item_trash", upItemTrash, downItemTrash},
+	{11, "packed_points", upPackedPoints, downPackedPoints},
+	{12, "stroke_style", upStrokeStyle, downStrokeStyle},
+	{13, "seed_workspace_counters", upSeedWorkspaceCounters, downSeedWorkspaceCounters},
+	{14, "history_assets", upHistoryAssets, downHistoryAssets},
+	{15, "recompute_item_bounds", upRecomputeItemBounds, downRecomputeItemBounds},
+}
+
+// Apply every pending migration in order and return the applied ones
+func MigrateUp(db *gorm.DB) ([]Migration, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   m.Version,
+				Name:      m.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Roll back the latest steps applied migrations, newest first, and return the
+// rolled back ones
+func MigrateDown(db *gorm.DB, steps int) ([]Migration, error) {
+	if _, err := appliedVersions(db); err != nil {
+		return nil, err
+	}
+
+	var rows []SchemaMigration
+	if err := db.Order("version DESC").Limit(steps).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, row := range rows {
+		m, ok := findMigration(row.Version)
+		if !ok {
+			return done, fmt.Errorf("migration %d_%s is not known to this binary", row.Version, row.Name)
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Report every known migration and when it was applied
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if row, ok := applied[m.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Load applied migrations by version, creating the bookkeeping table on first
+// use
+func appliedVersions(db *gorm.DB) (map[uint]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+
+	var rows []SchemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[uint]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func findMigration(version uint) (Migration, bool) {
+	for _, m := range migrations {
+		if m.Version == version {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
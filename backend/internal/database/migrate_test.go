@@ -0,0 +1,474 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"testing"
+	"time"
+
+	"backend/internal/database/schemas"
+	"backend/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupMigrationTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(
+		sqlite.Open("file::memory:"), &gorm.Config{
+			TranslateError: true,
+			Logger:         logger.Default.LogMode(logger.Silent),
+		},
+	)
+	if err != nil {
+		t.Fatal("failed to connect test database")
+	}
+	return db
+}
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	for i, m := range migrations {
+		assert.Equal(t, uint(i+1), m.Version, "Versions should be consecutive")
+		assert.NotEmpty(t, m.Name)
+		assert.NotNil(t, m.Up, "%d_%s has no up", m.Version, m.Name)
+		assert.NotNil(t, m.Down, "%d_%s has no down", m.Version, m.Name)
+	}
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	db := setupMigrationTestDB(t)
+
+	statuses, err := Status(db)
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations), len(statuses))
+	for _, s := range statuses {
+		assert.Nil(t, s.AppliedAt, "Nothing should be applied yet")
+	}
+
+	applied, err := MigrateUp(db)
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations), len(applied))
+
+	// The migrated schema serves the current models
+	user := schemas.User{Login: "user", PasswordHash: "hash"}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(db, &user))
+	item := schemas.Item{
+		WorkspaceID: user.WorkspaceID,
+		TextItem:    &schemas.TextItem{Content: "hello"},
+	}
+	assert.NoError(t, db.Create(&item).Error)
+
+	applied, err = MigrateUp(db)
+	assert.NoError(t, err)
+	assert.Empty(t, applied, "Applied migrations should not run again")
+
+	statuses, err = Status(db)
+	assert.NoError(t, err)
+	for _, s := range statuses {
+		assert.NotNil(t, s.AppliedAt)
+	}
+
+	rolledBack, err := MigrateDown(db, len(migrations))
+	assert.NoError(t, err)
+	if assert.Equal(t, len(migrations), len(rolledBack)) {
+		assert.Equal(t, migrations[len(migrations)-1].Version, rolledBack[0].Version, "Newest should roll back first")
+	}
+	assert.False(t, db.Migrator().HasTable("users"))
+	assert.False(t, db.Migrator().HasTable("items"))
+
+	rolledBack, err = MigrateDown(db, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, rolledBack)
+
+	_, err = MigrateUp(db)
+	assert.NoError(t, err)
+	assert.True(t, db.Migrator().HasTable("users"))
+}
+
+func TestMigrateUpFromAutoMigratedDatabase(t *testing.T) {
+	db := setupMigrationTestDB(t)
+
+	// Databases from before versioned migrations were set up by AutoMigrate
+	assert.NoError(t, db.AutoMigrate(
+		&schemas.User{},
+		&schemas.Workspace{},
+		&itemV1{}, // items have gained columns since
+		&schemas.TextItem{},
+	))
+	user := schemas.User{Login: "user", PasswordHash: "hash"}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(db, &user))
+
+	_, err := MigrateUp(db)
+	assert.NoError(t, err)
+
+	var count int64
+	db.Model(&schemas.User{}).Count(&count)
+	assert.Equal(t, int64(1), count, "Existing rows should be kept")
+	assert.True(t, db.Migrator().HasTable("workspace_counters"))
+}
+
+func TestImageAssetsMigration(t *testing.T) {
+	db := setupMigrationTestDB(t)
+
+	store, err := storage.NewFileStore(t.TempDir())
+	assert.NoError(t, err)
+	storage.Default = store
+	defer func() { storage.Default = nil }()
+
+	assert.NoError(t, upInitialSchema(db))
+	user := userV1{Login: "user", PasswordHash: "hash"}
+	assert.NoError(t, db.Create(&user).Error)
+	assert.NoError(t, db.Create(&workspaceV1{OwnerID: user.ID, Name: "My workspace"}).Error)
+
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+	encoded := base64.StdEncoding.EncodeToString(png)
+	legacy := []imageItemV1{
+		{ItemID: 1, WorkspaceID: 1, Bytes: encoded},
+		{ItemID: 2, WorkspaceID: 1, Bytes: "data:image/gif;base64," + encoded},
+		// Not trusted to be what they claim
+		{ItemID: 3, WorkspaceID: 1, Bytes: "data:text/html;base64," + encoded},
+		{ItemID: 4, WorkspaceID: 1, Bytes: "data:image/svg+xml;base64," + encoded},
+	}
+	for i := range legacy {
+		assert.NoError(t, db.Create(&itemV1{ID: legacy[i].ItemID, WorkspaceID: 1}).Error)
+		assert.NoError(t, db.Create(&legacy[i]).Error)
+	}
+
+	assert.NoError(t, db.Transaction(upImageAssets))
+	assert.False(t, db.Migrator().HasColumn("image_items", "bytes"))
+
+	var images []schemas.ImageItem
+	assert.NoError(t, db.Order("item_id").Find(&images).Error)
+	if assert.Equal(t, 4, len(images)) {
+		for i, contentType := range []string{"image/png", "image/gif", "application/octet-stream", "application/octet-stream"} {
+			var asset schemas.Asset
+			assert.NoError(t, db.First(&asset, "id = ?", images[i].AssetID).Error)
+			assert.Equal(t, contentType, asset.ContentType)
+			assert.Equal(t, user.ID, asset.OwnerID)
+			assert.Equal(t, int64(len(png)), asset.Size)
+
+			r, err := store.Get(context.Background(), asset.ID)
+			if assert.NoError(t, err) {
+				data, _ := io.ReadAll(r)
+				r.Close()
+				assert.Equal(t, png, data)
+			}
+		}
+	}
+
+	assert.NoError(t, db.Transaction(downImageAssets))
+	assert.False(t, db.Migrator().HasTable("assets"))
+
+	var restored []imageItemV1
+	assert.NoError(t, db.Order("item_id").Find(&restored).Error)
+	if assert.Equal(t, 4, len(restored)) {
+		for _, image := range restored {
+			assert.Equal(t, encoded, image.Bytes)
+		}
+	}
+}
+
+func TestContentAddressedBlobsMigration(t *testing.T) {
+	db := setupMigrationTestDB(t)
+
+	store, err := storage.NewFileStore(t.TempDir())
+	assert.NoError(t, err)
+	storage.Default = store
+	defer func() { storage.Default = nil }()
+
+	for _, up := range []func(*gorm.DB) error{upInitialSchema, upImageAssets, upAssetVariants} {
+		assert.NoError(t, db.Transaction(up))
+	}
+
+	ctx := context.Background()
+	objects := map[string]string{"a": "logo", "b": "logo", "c": "screenshot"}
+	for id, data := range objects {
+		assert.NoError(t, db.Create(&assetV3{ID: id, ContentType: "image/png", Size: int64(len(data))}).Error)
+		assert.NoError(t, store.Put(ctx, id, bytes.NewReader([]byte(data)), int64(len(data)), "image/png"))
+	}
+
+	assert.NoError(t, db.Transaction(upContentAddressedBlobs))
+
+	var assets []schemas.Asset
+	assert.NoError(t, db.Order("id").Find(&assets).Error)
+	if assert.Equal(t, 3, len(assets)) {
+		assert.Equal(t, assets[0].BlobHash, assets[1].BlobHash)
+		assert.NotEqual(t, assets[0].BlobHash, assets[2].BlobHash)
+
+		for _, asset := range assets {
+			data, err := readObject(ctx, asset.BlobHash)
+			assert.NoError(t, err)
+			assert.Equal(t, objects[asset.ID], string(data))
+		}
+
+		var blob schemas.Blob
+		assert.NoError(t, db.First(&blob, "hash = ?", assets[0].BlobHash).Error)
+		assert.Equal(t, int64(2), blob.RefCount)
+	}
+
+	// Uploaded after the migration, so only stored under its hash
+	assert.NoError(t, db.Create(&assetV4{ID: "d", BlobHash: assets[2].BlobHash, ContentType: "image/png"}).Error)
+
+	assert.NoError(t, db.Transaction(downContentAddressedBlobs))
+	assert.False(t, db.Migrator().HasTable("blobs"))
+	assert.False(t, db.Migrator().HasColumn("assets", "blob_hash"))
+	assert.True(t, db.Migrator().HasIndex(&assetV3{}, "OwnerID"))
+
+	data, err := readObject(ctx, "d")
+	assert.NoError(t, err)
+	assert.Equal(t, "screenshot", string(data))
+}
+
+func TestItemBoundsMigration(t *testing.T) {
+	db := setupMigrationTestDB(t)
+	assert.NoError(t, upInitialSchema(db))
+
+	assert.NoError(t, db.Create(&itemV1{ID: 1, WorkspaceID: 1, PositionX: 10, PositionY: 20, Width: 100, Height: 50, Scale: 2}).Error)
+	assert.NoError(t, db.Create(&itemV1{ID: 2, WorkspaceID: 1, PositionX: 10, PositionY: 20, Width: -30, Height: 40, Scale: 1}).Error)
+
+	assert.NoError(t, db.Transaction(upItemBounds))
+	assert.True(t, db.Migrator().HasIndex(&itemV5{}, "idx_items_bounds"))
+
+	var items []itemV5
+	assert.NoError(t, db.Order("id").Find(&items).Error)
+	if assert.Equal(t, 2, len(items)) {
+		assert.Equal(t, []float64{10, 20, 210, 120}, []float64{items[0].MinX, items[0].MinY, items[0].MaxX, items[0].MaxY})
+		assert.Equal(t, []float64{-20, 20, 10, 60}, []float64{items[1].MinX, items[1].MinY, items[1].MaxX, items[1].MaxY})
+	}
+
+	assert.NoError(t, db.Transaction(downItemBounds))
+	assert.False(t, db.Migrator().HasColumn("items", "min_x"))
+}
+
+func TestItemRevisionsMigration(t *testing.T) {
+	db := setupMigrationTestDB(t)
+	for _, up := range []func(*gorm.DB) error{upInitialSchema, upItemBounds} {
+		assert.NoError(t, db.Transaction(up))
+	}
+
+	assert.NoError(t, db.Create(&itemV5{ID: 1, WorkspaceID: 1}).Error)
+	assert.NoError(t, db.Exec("INSERT INTO workspace_counters (workspace_id, last_item_id) VALUES (1, 1)").Error)
+
+	assert.NoError(t, db.Transaction(upItemRevisions))
+	assert.True(t, db.Migrator().HasTable("item_tombstones"))
+	assert.True(t, db.Migrator().HasIndex(&itemV6{}, "idx_items_revision"))
+	assert.True(t, db.Migrator().HasIndex(&itemV6{}, "idx_items_bounds"))
+
+	var item itemV6
+	assert.NoError(t, db.First(&item).Error)
+	assert.Equal(t, uint64(1), item.Revision, "Existing items start at the first revision")
+
+	var counter workspaceCounterV6
+	assert.NoError(t, db.First(&counter).Error)
+	assert.Equal(t, uint64(1), counter.Revision)
+
+	assert.NoError(t, db.Transaction(downItemRevisions))
+	assert.False(t, db.Migrator().HasTable("item_tombstones"))
+	assert.False(t, db.Migrator().HasColumn("items", "revision"))
+	assert.True(t, db.Migrator().HasIndex(&itemV5{}, "idx_items_bounds"))
+}
+
+func TestItemVersionsMigration(t *testing.T) {
+	db := setupMigrationTestDB(t)
+	for _, up := range []func(*gorm.DB) error{upInitialSchema, upItemBounds, upItemRevisions} {
+		assert.NoError(t, db.Transaction(up))
+	}
+	assert.NoError(t, db.Create(&itemV6{ID: 1, WorkspaceID: 1}).Error)
+
+	assert.NoError(t, db.Transaction(upItemVersions))
+	var item itemV7
+	assert.NoError(t, db.First(&item).Error)
+	assert.Equal(t, uint64(1), item.Version, "Existing items start at version 1")
+
+	assert.NoError(t, db.Transaction(downItemVersions))
+	assert.False(t, db.Migrator().HasColumn("items", "version"))
+	assert.True(t, db.Migrator().HasIndex(&itemV6{}, "idx_items_revision"))
+}
+
+func TestItemTrashMigration(t *testing.T) {
+	db := setupMigrationTestDB(t)
+	for _, up := range []func(*gorm.DB) error{upInitialSchema, upItemBounds, upItemRevisions, upItemVersions} {
+		assert.NoError(t, db.Transaction(up))
+	}
+	assert.NoError(t, db.Create(&itemV7{ID: 1, WorkspaceID: 1}).Error)
+	assert.NoError(t, db.Create(&itemV7{ID: 2, WorkspaceID: 1}).Error)
+	assert.NoError(t, db.Exec("INSERT INTO text_items (item_id, workspace_id, content) VALUES (1, 1, 'kept'), (2, 1, 'trashed')").Error)
+
+	assert.NoError(t, db.Transaction(upItemTrash))
+	assert.True(t, db.Migrator().HasIndex(&itemV10{}, "idx_items_deleted_at"))
+	var count int64
+	db.Model(&itemV10{}).Count(&count)
+	assert.Equal(t, int64(2), count, "Existing items stay on the board")
+
+	assert.NoError(t, db.Exec("UPDATE items SET deleted_at = CURRENT_TIMESTAMP WHERE id = 2").Error)
+	assert.NoError(t, db.Transaction(downItemTrash))
+	assert.False(t, db.Migrator().HasColumn("items", "deleted_at"))
+	assert.True(t, db.Migrator().HasIndex(&itemV6{}, "idx_items_revision"))
+
+	var contents []string
+	assert.NoError(t, db.Table("text_items").Pluck("content", &contents).Error)
+	assert.Equal(t, []string{"kept"}, contents, "Trashed items are purged")
+	db.Table("items").Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestPackedPointsMigration(t *testing.T) {
+	db := setupMigrationTestDB(t)
+	for _, up := range []func(*gorm.DB) error{upInitialSchema, upItemBounds, upItemRevisions, upItemVersions, upItemTrash} {
+		assert.NoError(t, db.Transaction(up))
+	}
+	for _, workspaceID := range []uint{1, 2} {
+		assert.NoError(t, db.Create(&itemV10{ID: 1, WorkspaceID: workspaceID}).Error)
+		assert.NoError(t, db.Create(&drawingItemV1{ItemID: 1, WorkspaceID: workspaceID}).Error)
+	}
+	assert.NoError(t, db.Create(&[]pointV1{
+		{DrawingItemID: 1, WorkspaceID: 1, X: 0, Y: 0},
+		{DrawingItemID: 1, WorkspaceID: 2, X: 5, Y: 5},
+		{DrawingItemID: 1, WorkspaceID: 1, X: 10.5, Y: -3},
+		{DrawingItemID: 1, WorkspaceID: 1, X: 1.0 / 3, Y: 2},
+	}).Error)
+
+	assert.NoError(t, db.Transaction(upPackedPoints))
+	assert.False(t, db.Migrator().HasTable("points"))
+
+	var drawings []schemas.DrawingItem
+	assert.NoError(t, db.Order("workspace_id").Find(&drawings).Error)
+	if assert.Equal(t, 2, len(drawings)) {
+		assert.Equal(t, schemas.Points{{X: 0, Y: 0}, {X: 10.5, Y: -3}, {X: 1.0 / 3, Y: 2}}, drawings[0].Points,
+			"Points should keep their order and precision")
+		assert.Equal(t, schemas.Points{{X: 5, Y: 5}}, drawings[1].Points, "Points should stay with their workspace")
+	}
+
+	assert.NoError(t, db.Transaction(downPackedPoints))
+	assert.False(t, db.Migrator().HasColumn("drawing_items", "points"))
+
+	var restored []pointV1
+	assert.NoError(t, db.Where("workspace_id = 1").Order("id").Find(&restored).Error)
+	if assert.Equal(t, 3, len(restored)) {
+		assert.Equal(t, 10.5, restored[1].X)
+		assert.Equal(t, 1.0/3, restored[2].X)
+	}
+}
+
+func TestStrokeStyleMigration(t *testing.T) {
+	db := setupMigrationTestDB(t)
+	for _, up := range []func(*gorm.DB) error{upInitialSchema, upItemBounds, upItemRevisions, upItemVersions, upItemTrash, upPackedPoints} {
+		assert.NoError(t, db.Transaction(up))
+	}
+	points := schemas.EncodePoints(schemas.Points{{X: 1, Y: 2}})
+	assert.NoError(t, db.Create(&drawingItemV11{ItemID: 1, WorkspaceID: 1, Points: points}).Error)
+
+	assert.NoError(t, db.Transaction(upStrokeStyle))
+	var drawing schemas.DrawingItem
+	assert.NoError(t, db.First(&drawing).Error)
+	assert.Equal(t, schemas.Points{{X: 1, Y: 2}}, drawing.Points)
+	assert.Equal(t, float64(3), drawing.StrokeWidth, "Existing strokes keep the width they were drawn with")
+	assert.Equal(t, float64(1), drawing.Opacity)
+	assert.Equal(t, "round", drawing.LineCap)
+	assert.Equal(t, "round", drawing.LineJoin)
+	assert.Equal(t, "none", drawing.Smoothing)
+
+	assert.NoError(t, db.Transaction(downStrokeStyle))
+	for _, column := range []string{"stroke_width", "opacity", "line_cap", "line_join", "smoothing"} {
+		assert.False(t, db.Migrator().HasColumn("drawing_items", column))
+	}
+	var restored drawingItemV11
+	assert.NoError(t, db.First(&restored).Error)
+	assert.Equal(t, points, restored.Points)
+}
+
+func TestSeedWorkspaceCountersMigration(t *testing.T) {
+	db := setupMigrationTestDB(t)
+	for _, up := range []func(*gorm.DB) error{upInitialSchema, upItemBounds, upItemRevisions, upItemVersions, upItemTrash} {
+		assert.NoError(t, db.Transaction(up))
+	}
+
+	// One workspace never had a counter, with its newest item trashed and an
+	// older one deleted; the other's counter was seeded from its board alone
+	assert.NoError(t, db.Create(&[]workspaceV1{{ID: 1, Name: "Unseeded"}, {ID: 2, Name: "Seeded low"}}).Error)
+	assert.NoError(t, db.Create(&[]itemV10{
+		{ID: 1, WorkspaceID: 1, Revision: 1},
+		{ID: 3, WorkspaceID: 1, Revision: 4, DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}},
+		{ID: 1, WorkspaceID: 2, Revision: 1},
+	}).Error)
+	assert.NoError(t, db.Create(&[]itemTombstoneV6{
+		{WorkspaceID: 1, ItemID: 2, Revision: 5},
+		{WorkspaceID: 2, ItemID: 4, Revision: 7},
+	}).Error)
+	assert.NoError(t, db.Create(&workspaceCounterV6{WorkspaceID: 2, LastItemID: 1, Revision: 1}).Error)
+
+	assert.NoError(t, db.Transaction(upSeedWorkspaceCounters))
+
+	var counters []workspaceCounterV6
+	assert.NoError(t, db.Order("workspace_id").Find(&counters).Error)
+	assert.Equal(t, []workspaceCounterV6{
+		{WorkspaceID: 1, LastItemID: 3, Revision: 5},
+		{WorkspaceID: 2, LastItemID: 4, Revision: 7},
+	}, counters)
+
+	// Counters ahead of the items are left alone
+	assert.NoError(t, db.Model(&workspaceCounterV6{}).Where("workspace_id = 1").Update("last_item_id", 10).Error)
+	assert.NoError(t, db.Transaction(upSeedWorkspaceCounters))
+	var counter workspaceCounterV6
+	assert.NoError(t, db.First(&counter, "workspace_id = 1").Error)
+	assert.Equal(t, uint(10), counter.LastItemID)
+}
+
+func TestHistoryAssetsMigration(t *testing.T) {
+	db := setupMigrationTestDB(t)
+	for _, m := range migrations[:13] {
+		assert.NoError(t, db.Transaction(m.Up))
+	}
+
+	assert.NoError(t, db.Create(&schemas.Asset{ID: "kept", ContentType: "image/png"}).Error)
+	assert.NoError(t, db.Create(&schemas.HistoryEntry{
+		WorkspaceID: 1,
+		Changes: `[{"id": 1, "before": {"image": {"asset_id": "kept"}}, "after": {"image": {"asset_id": "gone"}}},
+			{"id": 2, "after": {"text": {"content": "Note"}}}]`,
+	}).Error)
+
+	assert.NoError(t, db.Transaction(upHistoryAssets))
+	var historyAssets []historyAssetV14
+	assert.NoError(t, db.Find(&historyAssets).Error)
+	assert.Equal(t, []historyAssetV14{{EntryID: 1, AssetID: "kept", WorkspaceID: 1}}, historyAssets,
+		"Only images still stored should be indexed")
+
+	assert.NoError(t, db.Transaction(downHistoryAssets))
+	assert.False(t, db.Migrator().HasTable("history_assets"))
+}
+
+func TestRecomputeItemBoundsMigration(t *testing.T) {
+	db := setupMigrationTestDB(t)
+	for _, up := range []func(*gorm.DB) error{upInitialSchema, upItemBounds, upItemRevisions, upItemVersions, upItemTrash, upPackedPoints, upStrokeStyle} {
+		assert.NoError(t, db.Transaction(up))
+	}
+
+	// A note boxed at scale 0, a drawing without a size and a trashed one
+	// with points outside its size
+	assert.NoError(t, db.Create(&[]itemV10{
+		{ID: 1, WorkspaceID: 1, PositionX: 10, PositionY: 20, Width: 100, Height: 50, Scale: 1, MinX: 10, MinY: 20, MaxX: 10, MaxY: 20},
+		{ID: 2, WorkspaceID: 1, PositionX: 1, PositionY: 1, Scale: 1, MinX: 1, MinY: 1, MaxX: 1, MaxY: 1},
+		{ID: 3, WorkspaceID: 1, Width: 10, Height: 10, Scale: 2, DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}},
+	}).Error)
+	assert.NoError(t, db.Create(&[]drawingItemV11{
+		{ItemID: 2, WorkspaceID: 1, Points: schemas.EncodePoints(schemas.Points{{X: 100, Y: 50}, {X: 120, Y: 40}})},
+		{ItemID: 3, WorkspaceID: 1, Points: schemas.EncodePoints(schemas.Points{{X: -5, Y: 2}, {X: 30, Y: 4}})},
+		{ItemID: 4, WorkspaceID: 1, Points: schemas.EncodePoints(schemas.Points{{X: 1, Y: 1}})},
+	}).Error)
+
+	assert.NoError(t, db.Transaction(upRecomputeItemBounds))
+
+	var items []itemV15
+	assert.NoError(t, db.Order("id").Find(&items).Error)
+	if assert.Equal(t, 3, len(items)) {
+		assert.Equal(t, []float64{10, 20, 110, 70}, []float64{items[0].MinX, items[0].MinY, items[0].MaxX, items[0].MaxY})
+		assert.Equal(t, []float64{101, 41, 121, 51}, []float64{items[1].MinX, items[1].MinY, items[1].MaxX, items[1].MaxY},
+			"Drawings without a size should be boxed by their points")
+		assert.Equal(t, []float64{-10, 0, 60, 20}, []float64{items[2].MinX, items[2].MinY, items[2].MaxX, items[2].MaxY},
+			"Drawings should cover both their size and their points")
+	}
+}
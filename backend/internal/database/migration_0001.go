@@ -0,0 +1,194 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The schema as it stood when versioned migrations were introduced. Databases
+// created by AutoMigrate at boot already have most of it, and AutoMigrate only
+// adds what is missing, so this also brings those up to date.
+
+type userV1 struct {
+	ID           uint   `gorm:"primaryKey"`
+	Login        string `gorm:"uniqueIndex;not null"`
+	PasswordHash string `gorm:"not null"`
+	Role         string `gorm:"not null;default:user"`
+	WorkspaceID  uint
+}
+
+func (userV1) TableName() string { return "users" }
+
+type workspaceV1 struct {
+	ID          uint   `gorm:"primaryKey"`
+	OwnerID     uint   `gorm:"not null;index"`
+	Name        string `gorm:"not null"`
+	Description string `gorm:"not null;default:''"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Items       []itemV1 `gorm:"foreignKey:WorkspaceID"`
+}
+
+func (workspaceV1) TableName() string { return "workspaces" }
+
+type workspaceMemberV1 struct {
+	WorkspaceID uint   `gorm:"primaryKey;autoIncrement:false"`
+	UserID      uint   `gorm:"primaryKey;autoIncrement:false;index"`
+	Role        string `gorm:"not null;default:editor"`
+	CreatedAt   time.Time
+}
+
+func (workspaceMemberV1) TableName() string { return "workspace_members" }
+
+type workspaceCounterV1 struct {
+	WorkspaceID uint `gorm:"primaryKey;autoIncrement:false"`
+	LastItemID  uint `gorm:"not null;default:0"`
+}
+
+func (workspaceCounterV1) TableName() string { return "workspace_counters" }
+
+type itemV1 struct {
+	ID          uint            `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID uint            `gorm:"primaryKey;autoIncrement:false"`
+	PositionX   float64         `gorm:"not null"`
+	PositionY   float64         `gorm:"not null"`
+	ZIndex      uint            `gorm:"not null"`
+	Width       float64         `gorm:"not null"`
+	Height      float64         `gorm:"not null"`
+	Color       string          `gorm:"not null;default:'#FFFFFF'"`
+	Scale       float64         `gorm:"not null;default:1.0"`
+	TextItem    *textItemV1     `gorm:"foreignKey:ItemID,WorkspaceID;references:ID,WorkspaceID"`
+	ImageItem   *imageItemV1    `gorm:"foreignKey:ItemID,WorkspaceID;references:ID,WorkspaceID"`
+	ListItem    *todoListItemV1 `gorm:"foreignKey:ItemID,WorkspaceID;references:ID,WorkspaceID"`
+	ShapeItem   *shapeItemV1    `gorm:"foreignKey:ItemID,WorkspaceID;references:ID,WorkspaceID"`
+	DrawingItem *drawingItemV1  `gorm:"foreignKey:ItemID,WorkspaceID;references:ID,WorkspaceID"`
+}
+
+func (itemV1) TableName() string { return "items" }
+
+type shapeItemV1 struct {
+	ItemID      uint   `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID uint   `gorm:"primaryKey;autoIncrement:false"`
+	Name        string `gorm:"not null"`
+}
+
+func (shapeItemV1) TableName() string { return "shape_items" }
+
+type textItemV1 struct {
+	ItemID      uint   `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID uint   `gorm:"primaryKey;autoIncrement:false"`
+	Content     string `gorm:"not null"`
+}
+
+func (textItemV1) TableName() string { return "text_items" }
+
+type imageItemV1 struct {
+	ItemID      uint   `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID uint   `gorm:"primaryKey;autoIncrement:false"`
+	Bytes       string `gorm:"not null"`
+}
+
+func (imageItemV1) TableName() string { return "image_items" }
+
+type todoListFieldV1 struct {
+	ID             uint   `gorm:"primaryKey;autoIncrement:false"`
+	TodoListItemID uint   `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID    uint   `gorm:"primaryKey;autoIncrement:false"`
+	Content        string `gorm:"not null"`
+	Done           bool   `gorm:"not null"`
+}
+
+func (todoListFieldV1) TableName() string { return "todo_list_fields" }
+
+type todoListItemV1 struct {
+	ItemID         uint              `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID    uint              `gorm:"primaryKey;autoIncrement:false"`
+	TodoListFields []todoListFieldV1 `gorm:"foreignKey:TodoListItemID,WorkspaceID;references:ItemID,WorkspaceID"`
+}
+
+func (todoListItemV1) TableName() string { return "todo_list_items" }
+
+type pointV1 struct {
+	ID            uint    `gorm:"primaryKey;autoIncrement"`
+	DrawingItemID uint    `gorm:"not null;index"`
+	WorkspaceID   uint    `gorm:"not null;index"`
+	X             float64 `gorm:"not null"`
+	Y             float64 `gorm:"not null"`
+}
+
+func (pointV1) TableName() string { return "points" }
+
+type drawingItemV1 struct {
+	ItemID      uint      `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID uint      `gorm:"primaryKey;autoIncrement:false"`
+	Points      []pointV1 `gorm:"foreignKey:DrawingItemID,WorkspaceID;references:ItemID,WorkspaceID"`
+}
+
+func (drawingItemV1) TableName() string { return "drawing_items" }
+
+type refreshTokenV1 struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	FamilyID  string    `gorm:"not null;index"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	AccessJTI string    `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+func (refreshTokenV1) TableName() string { return "refresh_tokens" }
+
+type revokedTokenV1 struct {
+	JTI       string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+func (revokedTokenV1) TableName() string { return "revoked_tokens" }
+
+func upInitialSchema(tx *gorm.DB) error {
+	if err := upgradeLegacyWorkspaces(tx); err != nil {
+		return err
+	}
+	if err := upgradeLegacyTodoFields(tx); err != nil {
+		return err
+	}
+
+	return tx.AutoMigrate(
+		&userV1{},
+		&workspaceV1{},
+		&workspaceMemberV1{},
+		&imageItemV1{},
+		&itemV1{},
+		&workspaceCounterV1{},
+		&textItemV1{},
+		&todoListFieldV1{},
+		&todoListItemV1{},
+		&shapeItemV1{},
+		&pointV1{},
+		&drawingItemV1{},
+		&refreshTokenV1{},
+		&revokedTokenV1{},
+	)
+}
+
+func downInitialSchema(tx *gorm.DB) error {
+	// Children before the tables their foreign keys point at
+	return tx.Migrator().DropTable(
+		"points",
+		"drawing_items",
+		"todo_list_fields",
+		"todo_list_items",
+		"shape_items",
+		"image_items",
+		"text_items",
+		"workspace_counters",
+		"items",
+		"workspace_members",
+		"workspaces",
+		"revoked_tokens",
+		"refresh_tokens",
+		"users",
+	)
+}
@@ -0,0 +1,165 @@
+package database
+
+import (
+	"backend/internal/storage"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Move image bytes out of image_items into the object store, leaving a
+// reference to an asset row behind. Objects written before a failure are not
+// removed when the transaction rolls back; they are unreferenced and harmless.
+
+type assetV2 struct {
+	ID          string `gorm:"primaryKey"`
+	OwnerID     uint   `gorm:"not null;index"`
+	WorkspaceID uint   `gorm:"not null;index"`
+	ContentType string `gorm:"not null"`
+	Size        int64  `gorm:"not null"`
+	CreatedAt   time.Time
+}
+
+func (assetV2) TableName() string { return "assets" }
+
+type imageItemV2 struct {
+	ItemID      uint   `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID uint   `gorm:"primaryKey;autoIncrement:false"`
+	AssetID     string `gorm:"not null;default:'';index"`
+	Bytes       string `gorm:"not null;default:''"` // dropped once moved
+}
+
+func (imageItemV2) TableName() string { return "image_items" }
+
+var errNoObjectStore = errors.New("image data needs moving but no object store is configured")
+
+func upImageAssets(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if err := m.CreateTable(&assetV2{}); err != nil {
+		return err
+	}
+	if err := m.AddColumn(&imageItemV2{}, "AssetID"); err != nil {
+		return err
+	}
+	var images []imageItemV2
+	if err := tx.Where("bytes <> ''").Find(&images).Error; err != nil {
+		return err
+	}
+	if len(images) > 0 && storage.Default == nil {
+		return errNoObjectStore
+	}
+
+	for _, image := range images {
+		data, contentType := decodeLegacyImage(image.Bytes)
+
+		var ownerID uint
+		if err := tx.Table("workspaces").
+			Select("owner_id").
+			Where("id = ?", image.WorkspaceID).
+			Scan(&ownerID).Error; err != nil {
+			return err
+		}
+
+		asset := assetV2{
+			ID:          uuid.NewString(),
+			OwnerID:     ownerID,
+			WorkspaceID: image.WorkspaceID,
+			ContentType: contentType,
+			Size:        int64(len(data)),
+		}
+		if err := storage.Default.Put(context.Background(), asset.ID, bytes.NewReader(data), asset.Size, contentType); err != nil {
+			return err
+		}
+		if err := tx.Create(&asset).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&imageItemV2{}).
+			Where("item_id = ? AND workspace_id = ?", image.ItemID, image.WorkspaceID).
+			Update("asset_id", asset.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	// SQLite drops indexes while rebuilding the table, so index afterwards
+	if err := m.DropColumn(&imageItemV2{}, "Bytes"); err != nil {
+		return err
+	}
+	return m.CreateIndex(&imageItemV2{}, "AssetID")
+}
+
+func downImageAssets(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if err := m.DropIndex(&imageItemV2{}, "AssetID"); err != nil {
+		return err
+	}
+	if err := m.AddColumn(&imageItemV2{}, "Bytes"); err != nil {
+		return err
+	}
+
+	var images []imageItemV2
+	if err := tx.Select("item_id", "workspace_id", "asset_id").
+		Where("asset_id <> ''").
+		Find(&images).Error; err != nil {
+		return err
+	}
+	if len(images) > 0 && storage.Default == nil {
+		return errNoObjectStore
+	}
+
+	for _, image := range images {
+		r, err := storage.Default.Get(context.Background(), image.AssetID)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&imageItemV2{}).
+			Where("item_id = ? AND workspace_id = ?", image.ItemID, image.WorkspaceID).
+			Update("bytes", base64.StdEncoding.EncodeToString(buf.Bytes())).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := m.DropColumn(&imageItemV2{}, "AssetID"); err != nil {
+		return err
+	}
+	return m.DropTable("assets")
+}
+
+// Image bytes used to be stored as plain base64 or as a base64 data URL.
+// Anything that does not decode is kept verbatim. Bytes that are not a raster
+// image, whatever they claim to be, are typed as opaque data so that they are
+// never served as a page.
+func decodeLegacyImage(encoded string) ([]byte, string) {
+	contentType := ""
+	if rest, ok := strings.CutPrefix(encoded, "data:"); ok {
+		if meta, payload, ok := strings.Cut(rest, ","); ok {
+			contentType, _, _ = strings.Cut(meta, ";")
+			encoded = payload
+		}
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		data = []byte(encoded)
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(contentType, "image/") || contentType == "image/svg+xml" {
+		contentType = "application/octet-stream"
+	}
+	return data, contentType
+}
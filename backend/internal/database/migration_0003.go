@@ -0,0 +1,56 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Record image dimensions on assets and let thumbnails point at the asset
+// they were rendered from. Existing assets keep zero dimensions.
+
+type assetV3 struct {
+	ID          string  `gorm:"primaryKey"`
+	OwnerID     uint    `gorm:"not null;index"`
+	WorkspaceID uint    `gorm:"not null;index"`
+	ContentType string  `gorm:"not null"`
+	Size        int64   `gorm:"not null"`
+	Width       int     `gorm:"not null;default:0"`
+	Height      int     `gorm:"not null;default:0"`
+	ParentID    *string `gorm:"index"`
+	Variant     string  `gorm:"not null;default:''"`
+	CreatedAt   time.Time
+}
+
+func (assetV3) TableName() string { return "assets" }
+
+var assetV3Columns = []string{"Width", "Height", "ParentID", "Variant"}
+
+func upAssetVariants(tx *gorm.DB) error {
+	m := tx.Migrator()
+	for _, column := range assetV3Columns {
+		if err := m.AddColumn(&assetV3{}, column); err != nil {
+			return err
+		}
+	}
+	return m.CreateIndex(&assetV3{}, "ParentID")
+}
+
+// Thumbnail rows are removed; their objects are left in the store
+func downAssetVariants(tx *gorm.DB) error {
+	if err := tx.Where("parent_id IS NOT NULL").Delete(&assetV3{}).Error; err != nil {
+		return err
+	}
+
+	m := tx.Migrator()
+	if err := m.DropIndex(&assetV3{}, "ParentID"); err != nil {
+		return err
+	}
+	for _, column := range assetV3Columns {
+		if err := m.DropColumn(&assetV3{}, column); err != nil {
+			return err
+		}
+	}
+	// SQLite loses every index of the table when dropping a column
+	return m.AutoMigrate(&assetV2{})
+}
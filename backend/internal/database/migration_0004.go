@@ -0,0 +1,161 @@
+package database
+
+import (
+	"backend/internal/storage"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Key stored objects by the SHA-256 of their content so that identical files
+// share one blob. Each asset's object is copied to its hash; the objects
+// under asset ids are left in place so that a failed migration loses nothing,
+// and can be deleted by hand once it has run.
+
+type blobV4 struct {
+	Hash        string `gorm:"primaryKey"`
+	ContentType string `gorm:"not null"`
+	Size        int64  `gorm:"not null"`
+	RefCount    int64  `gorm:"not null;default:0"`
+	CreatedAt   time.Time
+}
+
+func (blobV4) TableName() string { return "blobs" }
+
+type assetV4 struct {
+	ID          string  `gorm:"primaryKey"`
+	OwnerID     uint    `gorm:"not null;index"`
+	WorkspaceID uint    `gorm:"not null;index"`
+	BlobHash    string  `gorm:"not null;default:'';index"`
+	ContentType string  `gorm:"not null"`
+	Size        int64   `gorm:"not null"`
+	Width       int     `gorm:"not null;default:0"`
+	Height      int     `gorm:"not null;default:0"`
+	ParentID    *string `gorm:"index"`
+	Variant     string  `gorm:"not null;default:''"`
+	CreatedAt   time.Time
+}
+
+func (assetV4) TableName() string { return "assets" }
+
+func upContentAddressedBlobs(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if err := m.CreateTable(&blobV4{}); err != nil {
+		return err
+	}
+	if err := m.AddColumn(&assetV4{}, "BlobHash"); err != nil {
+		return err
+	}
+
+	var assets []assetV4
+	if err := tx.Find(&assets).Error; err != nil {
+		return err
+	}
+	if len(assets) > 0 && storage.Default == nil {
+		return errNoObjectStore
+	}
+
+	ctx := context.Background()
+	blobs := make(map[string]*blobV4)
+	for _, asset := range assets {
+		data, err := readObject(ctx, asset.ID)
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Warn().Str("asset", asset.ID).Msg("asset is missing from the object store")
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+
+		if blob, ok := blobs[hash]; ok {
+			blob.RefCount++
+		} else {
+			if err := storage.Default.Put(ctx, hash, bytes.NewReader(data), int64(len(data)), asset.ContentType); err != nil {
+				return err
+			}
+			blobs[hash] = &blobV4{
+				Hash:        hash,
+				ContentType: asset.ContentType,
+				Size:        int64(len(data)),
+				RefCount:    1,
+			}
+		}
+
+		if err := tx.Model(&assetV4{}).
+			Where("id = ?", asset.ID).
+			Update("blob_hash", hash).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, blob := range blobs {
+		if err := tx.Create(blob).Error; err != nil {
+			return err
+		}
+	}
+	return m.CreateIndex(&assetV4{}, "BlobHash")
+}
+
+// Copy objects back under the ids of assets uploaded since
+func downContentAddressedBlobs(tx *gorm.DB) error {
+	var assets []assetV4
+	if err := tx.Where("blob_hash <> ''").Find(&assets).Error; err != nil {
+		return err
+	}
+	if len(assets) > 0 && storage.Default == nil {
+		return errNoObjectStore
+	}
+
+	ctx := context.Background()
+	for _, asset := range assets {
+		r, err := storage.Default.Get(ctx, asset.ID)
+		if err == nil {
+			r.Close()
+			continue
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+
+		data, err := readObject(ctx, asset.BlobHash)
+		if err != nil {
+			return err
+		}
+		if err := storage.Default.Put(ctx, asset.ID, bytes.NewReader(data), int64(len(data)), asset.ContentType); err != nil {
+			return err
+		}
+	}
+
+	m := tx.Migrator()
+	if err := m.DropIndex(&assetV4{}, "BlobHash"); err != nil {
+		return err
+	}
+	if err := m.DropColumn(&assetV4{}, "BlobHash"); err != nil {
+		return err
+	}
+	// SQLite loses every index of the table when dropping a column
+	if err := m.AutoMigrate(&assetV3{}); err != nil {
+		return err
+	}
+	return m.DropTable(&blobV4{})
+}
+
+func readObject(ctx context.Context, key string) ([]byte, error) {
+	r, err := storage.Default.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(r)
+	return buf.Bytes(), err
+}
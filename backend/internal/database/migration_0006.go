@@ -0,0 +1,94 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Number every change to a workspace's items and keep tombstones of deleted
+// items, so that clients can fetch what changed since they last synced.
+// Existing items are given revision 1.
+
+type itemV6 struct {
+	ID          uint    `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID uint    `gorm:"primaryKey;autoIncrement:false;index:idx_items_bounds,priority:1;index:idx_items_revision,priority:1"`
+	PositionX   float64 `gorm:"not null"`
+	PositionY   float64 `gorm:"not null"`
+	ZIndex      uint    `gorm:"not null"`
+	Width       float64 `gorm:"not null"`
+	Height      float64 `gorm:"not null"`
+	Color       string  `gorm:"not null;default:'#FFFFFF'"`
+	Scale       float64 `gorm:"not null;default:1.0"`
+	MinX        float64 `gorm:"not null;default:0;index:idx_items_bounds,priority:2"`
+	MinY        float64 `gorm:"not null;default:0;index:idx_items_bounds,priority:3"`
+	MaxX        float64 `gorm:"not null;default:0"`
+	MaxY        float64 `gorm:"not null;default:0"`
+	Revision    uint64  `gorm:"not null;default:0;index:idx_items_revision,priority:2"`
+}
+
+func (itemV6) TableName() string { return "items" }
+
+type workspaceCounterV6 struct {
+	WorkspaceID    uint   `gorm:"primaryKey;autoIncrement:false"`
+	LastItemID     uint   `gorm:"not null;default:0"`
+	Revision       uint64 `gorm:"not null;default:0"`
+	PrunedRevision uint64 `gorm:"not null;default:0"`
+}
+
+func (workspaceCounterV6) TableName() string { return "workspace_counters" }
+
+type itemTombstoneV6 struct {
+	WorkspaceID uint   `gorm:"primaryKey;autoIncrement:false"`
+	ItemID      uint   `gorm:"primaryKey;autoIncrement:false"`
+	Revision    uint64 `gorm:"not null;index"`
+	DeletedAt   time.Time
+}
+
+func (itemTombstoneV6) TableName() string { return "item_tombstones" }
+
+func upItemRevisions(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if err := m.AddColumn(&itemV6{}, "Revision"); err != nil {
+		return err
+	}
+	if err := tx.Exec("UPDATE items SET revision = 1").Error; err != nil {
+		return err
+	}
+	if err := m.CreateIndex(&itemV6{}, "idx_items_revision"); err != nil {
+		return err
+	}
+
+	for _, column := range []string{"Revision", "PrunedRevision"} {
+		if err := m.AddColumn(&workspaceCounterV6{}, column); err != nil {
+			return err
+		}
+	}
+	if err := tx.Exec("UPDATE workspace_counters SET revision = 1").Error; err != nil {
+		return err
+	}
+
+	return m.CreateTable(&itemTombstoneV6{})
+}
+
+func downItemRevisions(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if err := m.DropTable(&itemTombstoneV6{}); err != nil {
+		return err
+	}
+
+	for _, column := range []string{"Revision", "PrunedRevision"} {
+		if err := m.DropColumn(&workspaceCounterV6{}, column); err != nil {
+			return err
+		}
+	}
+
+	if err := m.DropIndex(&itemV6{}, "idx_items_revision"); err != nil {
+		return err
+	}
+	if err := m.DropColumn(&itemV6{}, "Revision"); err != nil {
+		return err
+	}
+	// SQLite loses every index of the table when dropping a column
+	return m.AutoMigrate(&itemV5{})
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// Version items for optimistic concurrency control. Existing items start at
+// version 1.
+
+type itemV7 struct {
+	ID          uint    `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID uint    `gorm:"primaryKey;autoIncrement:false;index:idx_items_bounds,priority:1;index:idx_items_revision,priority:1"`
+	PositionX   float64 `gorm:"not null"`
+	PositionY   float64 `gorm:"not null"`
+	ZIndex      uint    `gorm:"not null"`
+	Width       float64 `gorm:"not null"`
+	Height      float64 `gorm:"not null"`
+	Color       string  `gorm:"not null;default:'#FFFFFF'"`
+	Scale       float64 `gorm:"not null;default:1.0"`
+	MinX        float64 `gorm:"not null;default:0;index:idx_items_bounds,priority:2"`
+	MinY        float64 `gorm:"not null;default:0;index:idx_items_bounds,priority:3"`
+	MaxX        float64 `gorm:"not null;default:0"`
+	MaxY        float64 `gorm:"not null;default:0"`
+	Revision    uint64  `gorm:"not null;default:0;index:idx_items_revision,priority:2"`
+	Version     uint64  `gorm:"not null;default:1"`
+}
+
+func (itemV7) TableName() string { return "items" }
+
+func upItemVersions(tx *gorm.DB) error {
+	return tx.Migrator().AddColumn(&itemV7{}, "Version")
+}
+
+func downItemVersions(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if err := m.DropColumn(&itemV7{}, "Version"); err != nil {
+		return err
+	}
+	// SQLite loses every index of the table when dropping a column
+	return m.AutoMigrate(&itemV6{})
+}
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Keep an undo history of item changes per workspace
+
+type historyEntryV8 struct {
+	ID          uint   `gorm:"primaryKey"`
+	WorkspaceID uint   `gorm:"not null;index"`
+	UserID      uint   `gorm:"not null"`
+	Changes     string `gorm:"not null"`
+	Undone      bool   `gorm:"not null;default:false"`
+	CreatedAt   time.Time
+}
+
+func (historyEntryV8) TableName() string { return "history_entries" }
+
+func upItemHistory(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&historyEntryV8{})
+}
+
+func downItemHistory(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&historyEntryV8{})
+}
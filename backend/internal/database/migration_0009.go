@@ -0,0 +1,39 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Named and automatic snapshots of whole workspaces, holding on to the images
+// they show
+
+type workspaceSnapshotV9 struct {
+	ID          uint   `gorm:"primaryKey"`
+	WorkspaceID uint   `gorm:"not null;index"`
+	Name        string `gorm:"not null;default:''"`
+	Automatic   bool   `gorm:"not null;default:false"`
+	Revision    uint64 `gorm:"not null;default:0"`
+	ItemCount   int    `gorm:"not null;default:0"`
+	Items       string `gorm:"not null"`
+	CreatedAt   time.Time
+}
+
+func (workspaceSnapshotV9) TableName() string { return "workspace_snapshots" }
+
+type snapshotAssetV9 struct {
+	SnapshotID  uint   `gorm:"primaryKey;autoIncrement:false"`
+	AssetID     string `gorm:"primaryKey;index"`
+	WorkspaceID uint   `gorm:"not null;index"`
+}
+
+func (snapshotAssetV9) TableName() string { return "snapshot_assets" }
+
+func upWorkspaceSnapshots(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&workspaceSnapshotV9{}, &snapshotAssetV9{})
+}
+
+func downWorkspaceSnapshots(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&snapshotAssetV9{}, &workspaceSnapshotV9{})
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Move deleted items to a trash instead of deleting them at once
+
+type itemV10 struct {
+	ID          uint           `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID uint           `gorm:"primaryKey;autoIncrement:false;index:idx_items_bounds,priority:1;index:idx_items_revision,priority:1"`
+	PositionX   float64        `gorm:"not null"`
+	PositionY   float64        `gorm:"not null"`
+	ZIndex      uint           `gorm:"not null"`
+	Width       float64        `gorm:"not null"`
+	Height      float64        `gorm:"not null"`
+	Color       string         `gorm:"not null;default:'#FFFFFF'"`
+	Scale       float64        `gorm:"not null;default:1.0"`
+	MinX        float64        `gorm:"not null;default:0;index:idx_items_bounds,priority:2"`
+	MinY        float64        `gorm:"not null;default:0;index:idx_items_bounds,priority:3"`
+	MaxX        float64        `gorm:"not null;default:0"`
+	MaxY        float64        `gorm:"not null;default:0"`
+	Revision    uint64         `gorm:"not null;default:0;index:idx_items_revision,priority:2"`
+	Version     uint64         `gorm:"not null;default:1"`
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (itemV10) TableName() string { return "items" }
+
+func upItemTrash(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if err := m.AddColumn(&itemV10{}, "DeletedAt"); err != nil {
+		return err
+	}
+	return m.CreateIndex(&itemV10{}, "DeletedAt")
+}
+
+// Trashed items are purged first, or they would come back on the board
+func downItemTrash(tx *gorm.DB) error {
+	trashed := "EXISTS (SELECT 1 FROM items WHERE items.deleted_at IS NOT NULL AND items.id = %s.%s AND items.workspace_id = %s.workspace_id)"
+	children := []struct{ table, column string }{
+		{"points", "drawing_item_id"},
+		{"drawing_items", "item_id"},
+		{"todo_list_fields", "todo_list_item_id"},
+		{"todo_list_items", "item_id"},
+		{"shape_items", "item_id"},
+		{"image_items", "item_id"},
+		{"text_items", "item_id"},
+	}
+	for _, child := range children {
+		where := fmt.Sprintf(trashed, child.table, child.column, child.table)
+		if err := tx.Exec("DELETE FROM " + child.table + " WHERE " + where).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Exec("DELETE FROM items WHERE deleted_at IS NOT NULL").Error; err != nil {
+		return err
+	}
+
+	m := tx.Migrator()
+	if err := m.DropIndex(&itemV10{}, "DeletedAt"); err != nil {
+		return err
+	}
+	if err := m.DropColumn(&itemV10{}, "DeletedAt"); err != nil {
+		return err
+	}
+	// SQLite loses every index of the table when dropping a column
+	return m.AutoMigrate(&itemV7{})
+}
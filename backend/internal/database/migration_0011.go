@@ -0,0 +1,113 @@
+package database
+
+import (
+	"backend/internal/database/schemas"
+
+	"gorm.io/gorm"
+)
+
+// Store the points of each drawing packed in a column of drawing_items
+// instead of a row per point
+
+type drawingItemV11 struct {
+	ItemID      uint `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID uint `gorm:"primaryKey;autoIncrement:false"`
+	Points      []byte
+}
+
+func (drawingItemV11) TableName() string { return "drawing_items" }
+
+// Drawings converted per statement
+const packPointsBatch = 500
+
+type drawingKey struct {
+	workspaceID, itemID uint
+}
+
+func upPackedPoints(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if err := m.AddColumn(&drawingItemV11{}, "Points"); err != nil {
+		return err
+	}
+
+	for offset := 0; ; offset += packPointsBatch {
+		var drawings []drawingItemV11
+		if err := tx.Select("item_id", "workspace_id").
+			Order("workspace_id, item_id").
+			Offset(offset).
+			Limit(packPointsBatch).
+			Find(&drawings).Error; err != nil {
+			return err
+		}
+		if len(drawings) == 0 {
+			break
+		}
+
+		workspaceIDs := make([]uint, 0, len(drawings))
+		itemIDs := make([]uint, 0, len(drawings))
+		for _, d := range drawings {
+			workspaceIDs = append(workspaceIDs, d.WorkspaceID)
+			itemIDs = append(itemIDs, d.ItemID)
+		}
+		var rows []pointV1
+		if err := tx.Where("workspace_id IN ? AND drawing_item_id IN ?", workspaceIDs, itemIDs).
+			Order("id").
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		// The query also matches items of other workspaces with the same id
+		points := make(map[drawingKey]schemas.Points, len(drawings))
+		for _, p := range rows {
+			key := drawingKey{p.WorkspaceID, p.DrawingItemID}
+			points[key] = append(points[key], schemas.Point{X: p.X, Y: p.Y})
+		}
+
+		for _, d := range drawings {
+			if err := tx.Model(&drawingItemV11{}).
+				Where("item_id = ? AND workspace_id = ?", d.ItemID, d.WorkspaceID).
+				Update("points", schemas.EncodePoints(points[drawingKey{d.WorkspaceID, d.ItemID}])).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return m.DropTable(&pointV1{})
+}
+
+func downPackedPoints(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if err := m.CreateTable(&pointV1{}); err != nil {
+		return err
+	}
+
+	for offset := 0; ; offset += packPointsBatch {
+		var drawings []drawingItemV11
+		if err := tx.Order("workspace_id, item_id").
+			Offset(offset).
+			Limit(packPointsBatch).
+			Find(&drawings).Error; err != nil {
+			return err
+		}
+		if len(drawings) == 0 {
+			break
+		}
+
+		var rows []pointV1
+		for _, d := range drawings {
+			points, err := schemas.DecodePoints(d.Points)
+			if err != nil {
+				return err
+			}
+			for _, p := range points {
+				rows = append(rows, pointV1{DrawingItemID: d.ItemID, WorkspaceID: d.WorkspaceID, X: p.X, Y: p.Y})
+			}
+		}
+		if len(rows) > 0 {
+			if err := tx.CreateInBatches(&rows, packPointsBatch).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return m.DropColumn(&drawingItemV11{}, "Points")
+}
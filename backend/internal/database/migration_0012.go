@@ -0,0 +1,43 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// Give drawings a stroke style. Existing strokes get the style they were
+// drawn with: 3 units wide, opaque, rounded and unsmoothed.
+
+type drawingItemV12 struct {
+	ItemID      uint `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID uint `gorm:"primaryKey;autoIncrement:false"`
+	Points      []byte
+	StrokeWidth float64 `gorm:"not null;default:3"`
+	Opacity     float64 `gorm:"not null;default:1"`
+	LineCap     string  `gorm:"not null;default:round"`
+	LineJoin    string  `gorm:"not null;default:round"`
+	Smoothing   string  `gorm:"not null;default:none"`
+}
+
+func (drawingItemV12) TableName() string { return "drawing_items" }
+
+var strokeStyleColumns = []string{"StrokeWidth", "Opacity", "LineCap", "LineJoin", "Smoothing"}
+
+func upStrokeStyle(tx *gorm.DB) error {
+	m := tx.Migrator()
+	for _, column := range strokeStyleColumns {
+		if err := m.AddColumn(&drawingItemV12{}, column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downStrokeStyle(tx *gorm.DB) error {
+	m := tx.Migrator()
+	for _, column := range strokeStyleColumns {
+		if err := m.DropColumn(&drawingItemV12{}, column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Give every workspace its counter row, so that none is seeded lazily from
+// the items left on its board: trashed and deleted items keep their ids and
+// revisions taken. Counters already seeded that way may have started too low
+// and are raised past everything the workspace has used.
+
+// Highest value of an item column in use by the workspace whose id is in
+// workspaceColumn, items in the trash and deleted ones included
+func usedByWorkspace(itemColumn, tombstoneColumn, workspaceColumn string) string {
+	return fmt.Sprintf(`(SELECT COALESCE(MAX(used), 0) FROM (
+		SELECT %[1]s AS used FROM items WHERE items.workspace_id = %[3]s
+		UNION ALL SELECT %[2]s FROM item_tombstones WHERE item_tombstones.workspace_id = %[3]s
+	) AS used_values)`, itemColumn, tombstoneColumn, workspaceColumn)
+}
+
+func upSeedWorkspaceCounters(tx *gorm.DB) error {
+	if err := tx.Exec(
+		`INSERT INTO workspace_counters (workspace_id, last_item_id, revision, pruned_revision)
+		SELECT id, 0, 0, 0 FROM workspaces
+		WHERE NOT EXISTS (SELECT 1 FROM workspace_counters WHERE workspace_counters.workspace_id = workspaces.id)`,
+	).Error; err != nil {
+		return err
+	}
+
+	for column, used := range map[string]string{
+		"last_item_id": usedByWorkspace("id", "item_id", "workspace_counters.workspace_id"),
+		"revision":     usedByWorkspace("revision", "revision", "workspace_counters.workspace_id"),
+	} {
+		if err := tx.Exec(fmt.Sprintf("UPDATE workspace_counters SET %[1]s = %[2]s WHERE %[1]s < %[2]s", column, used)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Counters are only ever ahead of what they would be seeded to; keep them
+func downSeedWorkspaceCounters(tx *gorm.DB) error {
+	return nil
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"encoding/json"
+
+	"gorm.io/gorm"
+)
+
+// Images shown in history entries, kept while the change can be undone or
+// redone. Existing entries are indexed for the images still stored.
+
+type historyAssetV14 struct {
+	EntryID     uint   `gorm:"primaryKey;autoIncrement:false"`
+	AssetID     string `gorm:"primaryKey;index"`
+	WorkspaceID uint   `gorm:"not null;index"`
+}
+
+func (historyAssetV14) TableName() string { return "history_assets" }
+
+// The part of a recorded change that names images
+type historyChangeV14 struct {
+	Before *historyStateV14 `json:"before"`
+	After  *historyStateV14 `json:"after"`
+}
+
+type historyStateV14 struct {
+	Image *struct {
+		AssetID string `json:"asset_id"`
+	} `json:"image"`
+}
+
+func upHistoryAssets(tx *gorm.DB) error {
+	if err := tx.Migrator().CreateTable(&historyAssetV14{}); err != nil {
+		return err
+	}
+
+	var entries []struct {
+		ID          uint
+		WorkspaceID uint
+		Changes     string
+	}
+	if err := tx.Table("history_entries").Select("id", "workspace_id", "changes").Find(&entries).Error; err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		var changes []historyChangeV14
+		if err := json.Unmarshal([]byte(entry.Changes), &changes); err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, change := range changes {
+			for _, state := range []*historyStateV14{change.Before, change.After} {
+				if state == nil || state.Image == nil || seen[state.Image.AssetID] {
+					continue
+				}
+				seen[state.Image.AssetID] = true
+				if err := tx.Exec(
+					`INSERT INTO history_assets (entry_id, asset_id, workspace_id)
+					SELECT ?, id, ? FROM assets WHERE id = ?`,
+					entry.ID, entry.WorkspaceID, state.Image.AssetID,
+				).Error; err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func downHistoryAssets(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&historyAssetV14{})
+}
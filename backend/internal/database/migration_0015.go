@@ -0,0 +1,99 @@
+package database
+
+import (
+	"backend/internal/database/schemas"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Recompute the bounding boxes of items. Items created without a scale were
+// boxed at scale 0 although stored at 1, and drawings were boxed by a width
+// and height that clients leave out instead of by their points.
+
+type itemV15 struct {
+	ID          uint `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID uint `gorm:"primaryKey;autoIncrement:false"`
+	PositionX   float64
+	PositionY   float64
+	Width       float64
+	Height      float64
+	Scale       float64
+	MinX        float64
+	MinY        float64
+	MaxX        float64
+	MaxY        float64
+}
+
+func (itemV15) TableName() string { return "items" }
+
+func upRecomputeItemBounds(tx *gorm.DB) error {
+	if err := tx.Exec(`UPDATE items SET scale = 1 WHERE scale = 0`).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec(`UPDATE items SET
+		min_x = CASE WHEN width * scale < 0 THEN position_x + width * scale ELSE position_x END,
+		max_x = CASE WHEN width * scale < 0 THEN position_x ELSE position_x + width * scale END,
+		min_y = CASE WHEN height * scale < 0 THEN position_y + height * scale ELSE position_y END,
+		max_y = CASE WHEN height * scale < 0 THEN position_y ELSE position_y + height * scale END`,
+	).Error; err != nil {
+		return err
+	}
+
+	// Drawings also cover their points; without a size, only them
+	for offset := 0; ; offset += packPointsBatch {
+		var drawings []drawingItemV11
+		if err := tx.Order("workspace_id, item_id").
+			Offset(offset).
+			Limit(packPointsBatch).
+			Find(&drawings).Error; err != nil {
+			return err
+		}
+		if len(drawings) == 0 {
+			break
+		}
+
+		for _, d := range drawings {
+			points, err := schemas.DecodePoints(d.Points)
+			if err != nil {
+				return err
+			}
+			if len(points) == 0 {
+				continue
+			}
+			var item itemV15
+			err = tx.First(&item, "id = ? AND workspace_id = ?", d.ItemID, d.WorkspaceID).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue // left behind by its item
+			}
+			if err != nil {
+				return err
+			}
+
+			pMinX, pMinY, pMaxX, pMaxY := points[0].X, points[0].Y, points[0].X, points[0].Y
+			for _, p := range points[1:] {
+				pMinX, pMinY = min(pMinX, p.X), min(pMinY, p.Y)
+				pMaxX, pMaxY = max(pMaxX, p.X), max(pMaxY, p.Y)
+			}
+			minX, maxX := item.PositionX+pMinX*item.Scale, item.PositionX+pMaxX*item.Scale
+			minY, maxY := item.PositionY+pMinY*item.Scale, item.PositionY+pMaxY*item.Scale
+			minX, maxX = min(minX, maxX), max(minX, maxX) // flipped by a negative scale
+			minY, maxY = min(minY, maxY), max(minY, maxY)
+			if item.Width != 0 || item.Height != 0 {
+				minX, minY = min(minX, item.MinX), min(minY, item.MinY)
+				maxX, maxY = max(maxX, item.MaxX), max(maxY, item.MaxY)
+			}
+			if err := tx.Model(&item).Updates(map[string]any{
+				"min_x": minX, "min_y": minY, "max_x": maxX, "max_y": maxY,
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// The boxes were wrong before, so they are left as they are
+func downRecomputeItemBounds(tx *gorm.DB) error {
+	return nil
+}
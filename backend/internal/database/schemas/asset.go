@@ -0,0 +1,30 @@
+package schemas
+
+import "time"
+
+// An uploaded binary object such as an image. The bytes live in the object
+// store as a blob, shared with every other asset of the same content.
+type Asset struct {
+	ID          string  `gorm:"primaryKey"`
+	OwnerID     uint    `gorm:"not null;index"` // uploader
+	WorkspaceID uint    `gorm:"not null;index"` // workspace it was uploaded to
+	BlobHash    string  `gorm:"not null;default:'';index"`
+	ContentType string  `gorm:"not null"`
+	Size        int64   `gorm:"not null"`
+	Width       int     `gorm:"not null;default:0"` // pixels, for images
+	Height      int     `gorm:"not null;default:0"`
+	ParentID    *string `gorm:"index"`               // asset this one was derived from
+	Variant     string  `gorm:"not null;default:''"` // e.g. "small" for a thumbnail
+	CreatedAt   time.Time
+	Variants    []Asset `gorm:"foreignKey:ParentID"`
+}
+
+// Stored content, keyed in the object store by the hex SHA-256 of its bytes.
+// RefCount is the number of assets using it.
+type Blob struct {
+	Hash        string `gorm:"primaryKey"`
+	ContentType string `gorm:"not null"`
+	Size        int64  `gorm:"not null"`
+	RefCount    int64  `gorm:"not null;default:0"`
+	CreatedAt   time.Time
+}
@@ -0,0 +1,79 @@
+package schemas
+
+import "gorm.io/gorm"
+
+// Per-workspace counters from which item ids and revisions are allocated.
+// Incrementing the row locks it until the surrounding transaction ends, so
+// concurrent changes to a workspace are serialized, ids of deleted items are
+// never reused and revisions commit in order.
+type WorkspaceCounter struct {
+	WorkspaceID    uint   `gorm:"primaryKey;autoIncrement:false"`
+	LastItemID     uint   `gorm:"not null;default:0"`
+	Revision       uint64 `gorm:"not null;default:0"` // of the latest change to an item
+	PrunedRevision uint64 `gorm:"not null;default:0"` // tombstones up to here are forgotten
+}
+
+// Reserve n consecutive item ids in a workspace and return the first one.
+// Must run inside the transaction that creates the items.
+func AllocateItemIDs(tx *gorm.DB, workspaceID uint, n uint) (uint, error) {
+	counter, err := incrementCounter(tx, workspaceID, "last_item_id", uint64(n))
+	if err != nil {
+		return 0, err
+	}
+	return counter.LastItemID - n + 1, nil
+}
+
+// Allocate the revision of a change to a workspace's items. Must run inside
+// the transaction making the change.
+func NextRevision(tx *gorm.DB, workspaceID uint) (uint64, error) {
+	counter, err := incrementCounter(tx, workspaceID, "revision", 1)
+	if err != nil {
+		return 0, err
+	}
+	return counter.Revision, nil
+}
+
+func incrementCounter(tx *gorm.DB, workspaceID uint, column string, n uint64) (WorkspaceCounter, error) {
+	var counter WorkspaceCounter
+	increment := func() (int64, error) {
+		result := tx.Model(&WorkspaceCounter{}).
+			Where("workspace_id = ?", workspaceID).
+			UpdateColumn(column, gorm.Expr(column+" + ?", n))
+		return result.RowsAffected, result.Error
+	}
+
+	updated, err := increment()
+	if err != nil {
+		return counter, err
+	}
+
+	if updated == 0 {
+		// Workspaces without a counter yet continue after the highest item id
+		// and revision they used, trashed and deleted items included; a
+		// concurrent seed wins and is incremented below
+		items := tx.Unscoped().Model(&Item{}).Where("workspace_id = ?", workspaceID)
+		tombstones := tx.Model(&ItemTombstone{}).Where("workspace_id = ?", workspaceID)
+		used := func(itemColumn, tombstoneColumn string) *gorm.DB {
+			return tx.Raw("SELECT COALESCE(MAX(used), 0) FROM (? UNION ALL ?) AS used_values",
+				items.Session(&gorm.Session{}).Select(itemColumn+" AS used"),
+				tombstones.Session(&gorm.Session{}).Select(tombstoneColumn),
+			)
+		}
+		err := tx.Exec(
+			"INSERT INTO workspace_counters (workspace_id, last_item_id, revision) VALUES (?, (?), (?)) ON CONFLICT DO NOTHING",
+			workspaceID,
+			used("id", "item_id"),
+			used("revision", "revision"),
+		).Error
+		if err != nil {
+			return counter, err
+		}
+
+		if _, err := increment(); err != nil {
+			return counter, err
+		}
+	}
+
+	err = tx.First(&counter, "workspace_id = ?", workspaceID).Error
+	return counter, err
+}
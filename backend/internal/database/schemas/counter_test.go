@@ -0,0 +1,166 @@
+package schemas
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Databases to run the counter tests against. SQLite always runs on a file so
+// that concurrent connections share it; Postgres runs when TEST_POSTGRES_DSN
+// is set.
+func counterTestDBs(t *testing.T) map[string]*gorm.DB {
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	dbs := map[string]*gorm.DB{}
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := gorm.Open(sqlite.Open(path+"?_busy_timeout=10000&_txlock=immediate"), config)
+	assert.NoError(t, err, "Failed to open SQLite DB")
+	dbs["sqlite"] = db
+
+	if dsn := os.Getenv("TEST_POSTGRES_DSN"); dsn != "" {
+		db, err := gorm.Open(postgres.Open(dsn), config)
+		assert.NoError(t, err, "Failed to open Postgres DB")
+		dbs["postgres"] = db
+	}
+
+	for _, db := range dbs {
+		assert.NoError(t, db.Migrator().DropTable(&WorkspaceCounter{}, &Item{}, &TextItem{}, &ItemTombstone{}))
+		assert.NoError(t, db.AutoMigrate(&WorkspaceCounter{}, &Item{}, &TextItem{}, &ItemTombstone{}))
+	}
+	return dbs
+}
+
+func TestConcurrentItemIDs(t *testing.T) {
+	const workers = 8
+	const perWorker = 25
+
+	for name, db := range counterTestDBs(t) {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			errs := make(chan error, workers*perWorker*2)
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < perWorker; i++ {
+						for _, workspaceID := range []uint{1, 2} {
+							errs <- db.Create(&Item{
+								WorkspaceID: workspaceID,
+								TextItem:    &TextItem{Content: "concurrent"},
+							}).Error
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				assert.NoError(t, err)
+			}
+
+			for _, workspaceID := range []uint{1, 2} {
+				var ids []uint
+				db.Model(&Item{}).Where("workspace_id = ?", workspaceID).Order("id").Pluck("id", &ids)
+				if assert.Equal(t, workers*perWorker, len(ids)) {
+					for i, id := range ids {
+						assert.Equal(t, uint(i+1), id, "Ids should be dense and unique")
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestItemIDsAreNotReused(t *testing.T) {
+	for name, db := range counterTestDBs(t) {
+		t.Run(name, func(t *testing.T) {
+			// Items created before the counter existed
+			for id := uint(1); id <= 3; id++ {
+				assert.NoError(t, db.Create(&Item{ID: id, WorkspaceID: 1}).Error)
+			}
+			// Saving them allocated revisions; forget the counter they seeded
+			assert.NoError(t, db.Where("workspace_id = ?", 1).Delete(&WorkspaceCounter{}).Error)
+
+			item := Item{WorkspaceID: 1}
+			assert.NoError(t, db.Create(&item).Error)
+			assert.Equal(t, uint(4), item.ID, "Counter should be seeded from existing items")
+
+			assert.NoError(t, db.Delete(&item).Error)
+
+			item = Item{WorkspaceID: 1}
+			assert.NoError(t, db.Create(&item).Error)
+			assert.Equal(t, uint(5), item.ID, "Ids of deleted items should not be reused")
+
+			first, err := AllocateItemIDs(db, 1, 10)
+			assert.NoError(t, err)
+			assert.Equal(t, uint(6), first)
+
+			item = Item{WorkspaceID: 1}
+			assert.NoError(t, db.Create(&item).Error)
+			assert.Equal(t, uint(16), item.ID)
+			assert.Equal(t, uint64(6), item.Revision, "Seeding should continue after existing revisions")
+		})
+	}
+}
+
+func TestCounterSeedCountsRemovedItems(t *testing.T) {
+	for name, db := range counterTestDBs(t) {
+		t.Run(name, func(t *testing.T) {
+			for id := uint(1); id <= 3; id++ {
+				assert.NoError(t, db.Create(&Item{ID: id, WorkspaceID: 1}).Error)
+			}
+			assert.NoError(t, db.Where("workspace_id = ?", 1).Delete(&WorkspaceCounter{}).Error)
+
+			// The newest item goes to the trash while there is no counter
+			trashed, err := TrashItem(db, 1, 3)
+			assert.NoError(t, err)
+			assert.True(t, trashed)
+
+			item := Item{WorkspaceID: 1}
+			assert.NoError(t, db.Create(&item).Error)
+			assert.Equal(t, uint(4), item.ID, "Ids of trashed items should not be reused")
+
+			// Purged for good, the items leave only their tombstones
+			_, err = TrashItem(db, 1, 4)
+			assert.NoError(t, err)
+			assert.NoError(t, db.Unscoped().Where("workspace_id = ? AND id IN ?", 1, []uint{3, 4}).Delete(&Item{}).Error)
+			assert.NoError(t, db.Where("workspace_id = ?", 1).Delete(&WorkspaceCounter{}).Error)
+
+			item = Item{WorkspaceID: 1}
+			assert.NoError(t, db.Create(&item).Error)
+			assert.Equal(t, uint(5), item.ID, "Ids of purged items should not be reused")
+		})
+	}
+}
+
+func TestTodoListFieldIDs(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err, "Failed to open in-memory DB")
+	assert.NoError(t, db.AutoMigrate(&WorkspaceCounter{}, &Item{}, &TodoListItem{}, &TodoListField{}, &ItemTombstone{}))
+
+	for i := 0; i < 2; i++ {
+		item := Item{
+			WorkspaceID: 1,
+			ListItem: &TodoListItem{
+				TodoListFields: []TodoListField{{Content: "a"}, {Content: "b"}, {Content: "c"}},
+			},
+		}
+		assert.NoError(t, db.Create(&item).Error)
+
+		var ids []uint
+		db.Model(&TodoListField{}).
+			Where("workspace_id = ? AND todo_list_item_id = ?", 1, item.ID).
+			Order("id").
+			Pluck("id", &ids)
+		assert.Equal(t, []uint{1, 2, 3}, ids, "Field ids should be scoped to their list")
+	}
+}
@@ -0,0 +1,24 @@
+package schemas
+
+import "time"
+
+// An undoable change to a workspace's items, with the state of every item it
+// touched before and after as JSON. Recording a change drops the entries that
+// were undone, and only the latest entries up to the history depth are kept.
+type HistoryEntry struct {
+	ID          uint   `gorm:"primaryKey"`
+	WorkspaceID uint   `gorm:"not null;index"`
+	UserID      uint   `gorm:"not null"` // who made the change
+	Changes     string `gorm:"not null"`
+	Undone      bool   `gorm:"not null;default:false"`
+	CreatedAt   time.Time
+	Assets      []HistoryAsset `gorm:"foreignKey:EntryID"`
+}
+
+// An image shown in a state of a history entry, which keeps it from being
+// collected while the change can be undone or redone
+type HistoryAsset struct {
+	EntryID     uint   `gorm:"primaryKey;autoIncrement:false"`
+	AssetID     string `gorm:"primaryKey;index"`
+	WorkspaceID uint   `gorm:"not null;index"`
+}
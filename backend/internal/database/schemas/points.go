@@ -0,0 +1,256 @@
+package schemas
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// A point of a drawing, relative to the top left corner of its item
+type Point struct {
+	X float64
+	Y float64
+	// How hard the pen pressed, from 0 to 1; 0 when it was not reported
+	Pressure float64
+}
+
+// The points of a stroke, stored packed in a single column. The first byte
+// tells how the rest is encoded:
+//
+//   - pointsDelta: coordinates in thousandths of a unit, each the zigzag
+//     varint of its difference from the same coordinate of the previous
+//     point, x before y
+//   - pointsRaw: every coordinate as a little-endian float64, for strokes
+//     that thousandths cannot hold exactly
+//
+// With pointsPressure set in the first byte, the pressure of each point
+// follows its y, encoded like the coordinates.
+type Points []Point
+
+const (
+	pointsRaw      byte = 0
+	pointsDelta    byte = 1
+	pointsPressure byte = 0x10
+
+	// Steps of delta encoded coordinates per unit
+	pointsPrecision = 1000
+)
+
+// Report whether any point of the stroke has a pressure
+func (p Points) HasPressure() bool {
+	for _, point := range p {
+		if point.Pressure != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Report the rectangle the points of a non-empty stroke lie in
+func (p Points) Extent() (minX, minY, maxX, maxY float64) {
+	minX, minY, maxX, maxY = p[0].X, p[0].Y, p[0].X, p[0].Y
+	for _, point := range p[1:] {
+		minX, minY = min(minX, point.X), min(minY, point.Y)
+		maxX, maxY = max(maxX, point.X), max(maxY, point.Y)
+	}
+	return minX, minY, maxX, maxY
+}
+
+// The values a point is encoded as: its coordinates, then its pressure if the
+// stroke has one
+func (p Point) values(pressure bool) []float64 {
+	if pressure {
+		return []float64{p.X, p.Y, p.Pressure}
+	}
+	return []float64{p.X, p.Y}
+}
+
+func pointFromValues(values []float64) Point {
+	p := Point{X: values[0], Y: values[1]}
+	if len(values) > 2 {
+		p.Pressure = values[2]
+	}
+	return p
+}
+
+// Encode points, in the delta form unless that would lose precision
+func EncodePoints(points Points) []byte {
+	pressure := points.HasPressure()
+	format, stride := pointsDelta, 2
+	if pressure {
+		format, stride = format|pointsPressure, 3
+	}
+
+	steps := make([]int64, 0, 3*len(points))
+	for _, p := range points {
+		for _, v := range p.values(pressure) {
+			q := math.Round(v * pointsPrecision)
+			if math.Abs(q) > 1<<53 || q/pointsPrecision != v {
+				return encodeRawPoints(points, pressure)
+			}
+			steps = append(steps, int64(q))
+		}
+	}
+
+	data := make([]byte, 1, 1+len(steps)*2)
+	data[0] = format
+	prev := make([]int64, stride)
+	for i, step := range steps {
+		data = binary.AppendVarint(data, step-prev[i%stride])
+		prev[i%stride] = step
+	}
+	return data
+}
+
+func encodeRawPoints(points Points, pressure bool) []byte {
+	format := pointsRaw
+	if pressure {
+		format |= pointsPressure
+	}
+	data := make([]byte, 1, 1+24*len(points))
+	data[0] = format
+	for _, p := range points {
+		for _, v := range p.values(pressure) {
+			data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v))
+		}
+	}
+	return data
+}
+
+// Decode points written by EncodePoints; empty data holds no points
+func DecodePoints(data []byte) (Points, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	stride := 2
+	if data[0]&pointsPressure != 0 {
+		stride = 3
+	}
+	values := make([]float64, stride)
+
+	switch data[0] &^ pointsPressure {
+	case pointsRaw:
+		data = data[1:]
+		if len(data)%(8*stride) != 0 {
+			return nil, errors.New("points: truncated coordinates")
+		}
+		points := make(Points, 0, len(data)/(8*stride))
+		for len(data) > 0 {
+			for i := range values {
+				values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data))
+				data = data[8:]
+			}
+			points = append(points, pointFromValues(values))
+		}
+		return points, nil
+
+	case pointsDelta:
+		var points Points
+		steps := make([]int64, stride)
+		for i, rest := 0, data[1:]; len(rest) > 0; i++ {
+			delta, n := binary.Varint(rest)
+			if n <= 0 {
+				return nil, errors.New("points: invalid varint")
+			}
+			rest = rest[n:]
+			steps[i%stride] += delta
+			if i%stride == stride-1 {
+				for j, step := range steps {
+					values[j] = float64(step) / pointsPrecision
+				}
+				points = append(points, pointFromValues(values))
+			} else if len(rest) == 0 {
+				return nil, errors.New("points: truncated point")
+			}
+		}
+		return points, nil
+	}
+	return nil, fmt.Errorf("points: unknown encoding %d", data[0])
+}
+
+func (Points) GormDataType() string {
+	return "bytes"
+}
+
+func (p Points) Value() (driver.Value, error) {
+	return EncodePoints(p), nil
+}
+
+func (p *Points) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("points: cannot scan %T", value)
+	}
+
+	points, err := DecodePoints(data)
+	if err != nil {
+		return err
+	}
+	*p = points
+	return nil
+}
+
+// How far the pressure of a dropped point may be from the one drawn in its
+// place, which goes linearly from one kept point to the next
+const pressureTolerance = 0.05
+
+// Drop the points of a stroke that lie within tolerance of the line through
+// the points kept around them (Ramer-Douglas-Peucker), with a pressure within
+// pressureTolerance of that line's. The ends are always kept; a tolerance of
+// 0 or less keeps every point.
+func (p Points) Simplify(tolerance float64) Points {
+	if tolerance <= 0 || len(p) < 3 {
+		return p
+	}
+
+	keep := make([]bool, len(p))
+	keep[0], keep[len(p)-1] = true, true
+	// Ranges left to simplify, as indexes of their kept ends. Strokes can be
+	// long, so this does not recurse.
+	stack := [][2]int{{0, len(p) - 1}}
+	for len(stack) > 0 {
+		first, last := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+
+		farthest, distance := 0, 1.0
+		for i := first + 1; i < last; i++ {
+			if d := deviation(p[i], p[first], p[last], tolerance); d > distance {
+				farthest, distance = i, d
+			}
+		}
+		if farthest != 0 {
+			keep[farthest] = true
+			stack = append(stack, [2]int{first, farthest}, [2]int{farthest, last})
+		}
+	}
+
+	simplified := make(Points, 0, len(p))
+	for i, point := range p {
+		if keep[i] {
+			simplified = append(simplified, point)
+		}
+	}
+	return simplified
+}
+
+// How far p is from the segment from a to b, in tolerances: in distance, or
+// in pressure from that of the segment where p is closest to it
+func deviation(p, a, b Point, tolerance float64) float64 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = max(0, min(1, ((p.X-a.X)*dx+(p.Y-a.Y)*dy)/length))
+	}
+	distance := math.Hypot(p.X-(a.X+t*dx), p.Y-(a.Y+t*dy)) / tolerance
+	pressure := math.Abs(p.Pressure-(a.Pressure+t*(b.Pressure-a.Pressure))) / pressureTolerance
+	return max(distance, pressure)
+}
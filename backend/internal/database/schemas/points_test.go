@@ -0,0 +1,97 @@
+package schemas
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestEncodePoints(t *testing.T) {
+	for name, points := range map[string]Points{
+		"empty":     nil,
+		"integers":  {{X: 0, Y: 0}, {X: 10, Y: -5}, {X: 12, Y: 3}},
+		"decimals":  {{X: 0.125, Y: 10.5}, {X: -0.001, Y: 1e6}},
+		"fractions": {{X: 1.0 / 3, Y: 0}, {X: 2, Y: math.Pi}},
+		"huge":      {{X: 1e300, Y: -1e300}},
+		"pressure":  {{X: 0, Y: 0, Pressure: 0.5}, {X: 1.5, Y: 2, Pressure: 0.75}, {X: 3, Y: 4}},
+		"precise":   {{X: 1, Y: 1, Pressure: 0.52734375}},
+	} {
+		decoded, err := DecodePoints(EncodePoints(points))
+		assert.NoError(t, err, name)
+		assert.Equal(t, points, decoded, name)
+	}
+
+	// Small steps in thousandths take a few bytes, not the 16 of two float64
+	stroke := make(Points, 0, 100)
+	for i := range 100 {
+		stroke = append(stroke, Point{X: float64(i) * 1.5, Y: float64(i%7) - 3})
+	}
+	data := EncodePoints(stroke)
+	assert.Equal(t, pointsDelta, data[0])
+	assert.LessOrEqual(t, len(data), 1+4*len(stroke))
+	assert.Equal(t, pointsRaw, EncodePoints(Points{{X: 1.0 / 3}})[0], "Inexact coordinates should be kept as they are")
+	assert.Equal(t, pointsDelta|pointsPressure, EncodePoints(Points{{X: 1, Pressure: 0.5}})[0])
+	assert.Equal(t, []byte{pointsDelta, 2, 4}, EncodePoints(Points{{X: 0.001, Y: 0.002}}), "Strokes without pressure should not store it")
+
+	for name, data := range map[string][]byte{
+		"unknown encoding": {9},
+		"truncated raw":    {pointsRaw, 1, 2, 3},
+		"missing y":        {pointsDelta, 2},
+		"missing pressure": {pointsDelta | pointsPressure, 2, 2},
+		"broken varint":    {pointsDelta, 0x80},
+	} {
+		_, err := DecodePoints(data)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestPointsColumn(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&DrawingItem{}))
+
+	drawing := DrawingItem{ItemID: 1, WorkspaceID: 1, Points: Points{{X: 1, Y: 2}, {X: 3.5, Y: 4}}}
+	assert.NoError(t, db.Create(&drawing).Error)
+	assert.NoError(t, db.Create(&DrawingItem{ItemID: 2, WorkspaceID: 1}).Error)
+
+	var drawings []DrawingItem
+	assert.NoError(t, db.Order("item_id").Find(&drawings).Error)
+	if assert.Equal(t, 2, len(drawings)) {
+		assert.Equal(t, drawing.Points, drawings[0].Points)
+		assert.Empty(t, drawings[1].Points)
+	}
+}
+
+func TestSimplifyPoints(t *testing.T) {
+	// A straight line keeps its ends only
+	line := Points{{X: 0, Y: 0}, {X: 1, Y: 0.1}, {X: 2, Y: -0.1}, {X: 3, Y: 0}}
+	assert.Equal(t, Points{{X: 0, Y: 0}, {X: 3, Y: 0}}, line.Simplify(0.5))
+
+	// Corners stay
+	corner := Points{{X: 0, Y: 0}, {X: 5, Y: 0.2}, {X: 10, Y: 0}, {X: 10, Y: 5}, {X: 10.2, Y: 10}}
+	assert.Equal(t, Points{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10.2, Y: 10}}, corner.Simplify(0.5))
+
+	// A closed loop is measured against its start, not an empty chord
+	loop := Points{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}, {X: 0, Y: 0}}
+	assert.Equal(t, loop, loop.Simplify(0.5))
+
+	// Pressure changes stay, even along a straight line; steady ones do not
+	pressed := Points{{X: 0, Y: 0, Pressure: 0.2}, {X: 1, Y: 0, Pressure: 0.8}, {X: 2, Y: 0, Pressure: 0.2}}
+	assert.Equal(t, pressed, pressed.Simplify(0.5))
+	steady := Points{{X: 0, Y: 0, Pressure: 0.2}, {X: 1, Y: 0, Pressure: 0.31}, {X: 2, Y: 0, Pressure: 0.4}}
+	assert.Equal(t, Points{steady[0], steady[2]}, steady.Simplify(0.5))
+
+	assert.Equal(t, line, line.Simplify(0), "A tolerance of 0 keeps every point")
+	assert.Equal(t, 2, len(line[:2].Simplify(100)))
+
+	// Long strokes do not recurse
+	long := make(Points, 2000)
+	for i := range long {
+		long[i] = Point{X: float64(i), Y: float64(i % 2)}
+	}
+	assert.Equal(t, len(long), len(long.Simplify(0.1)))
+	assert.Equal(t, 2, len(long.Simplify(1)))
+}
@@ -0,0 +1,25 @@
+package schemas
+
+import "time"
+
+// A copy of every item of a workspace with its typed sub-record, as JSON.
+// Named snapshots are taken on request and kept until deleted; automatic ones
+// are taken before the first change after a quiet interval and expire.
+type WorkspaceSnapshot struct {
+	ID          uint   `gorm:"primaryKey"`
+	WorkspaceID uint   `gorm:"not null;index"`
+	Name        string `gorm:"not null;default:''"`
+	Automatic   bool   `gorm:"not null;default:false"`
+	Revision    uint64 `gorm:"not null;default:0"` // of the workspace when taken
+	ItemCount   int    `gorm:"not null;default:0"`
+	Items       string `gorm:"not null"`
+	CreatedAt   time.Time
+	Assets      []SnapshotAsset `gorm:"foreignKey:SnapshotID"`
+}
+
+// An image shown in a snapshot, which keeps it from being collected
+type SnapshotAsset struct {
+	SnapshotID  uint   `gorm:"primaryKey;autoIncrement:false"`
+	AssetID     string `gorm:"primaryKey;index"`
+	WorkspaceID uint   `gorm:"not null;index"`
+}
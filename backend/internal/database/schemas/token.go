@@ -0,0 +1,22 @@
+package schemas
+
+import "time"
+
+// A rotating refresh token; every token descending from one login shares a
+// family, so reuse of a rotated token can revoke the whole chain
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	FamilyID  string    `gorm:"not null;index"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	AccessJTI string    `gorm:"not null"` // access token issued alongside
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// An access token rejected before its expiry
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
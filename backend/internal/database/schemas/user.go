@@ -2,14 +2,20 @@ package schemas
 
 import "gorm.io/gorm"
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID           uint   `gorm:"primaryKey"`
 	Login        string `gorm:"uniqueIndex;not null"`
 	PasswordHash string `gorm:"not null" json:"-"`
-	WorkspaceID  uint
+	Role         string `gorm:"not null;default:user"`
+	WorkspaceID  uint   // default workspace, addressed as /workspaces/my
 }
 
-// Create a workspace for the user
+// Create a user along with their default workspace
 func CreateUserWithWorkspace(db *gorm.DB, user *User) error {
     return db.Transaction(func(tx *gorm.DB) error {
         // 1. First create the user (without workspace reference)
@@ -19,14 +25,15 @@ func CreateUserWithWorkspace(db *gorm.DB, user *User) error {
 
         // 2. Create the workspace
         workspace := Workspace{
-            UserID: user.ID,
+            OwnerID: user.ID,
+            Name:    DefaultWorkspaceName,
         }
         if err := tx.Create(&workspace).Error; err != nil {
             return err
         }
 
         // 3. Update user with workspace reference
-        user.WorkspaceID = user.ID// Since workspace.UserID = user.ID
+        user.WorkspaceID = workspace.ID
         return tx.Model(user).Update("WorkspaceID", user.WorkspaceID).Error
     })
-}
\ No newline at end of file
+}
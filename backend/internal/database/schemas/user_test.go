@@ -29,7 +29,8 @@ func TestCreateUserWithWorkspace(t *testing.T) {
 
 	// Verify workspace created and linked
 	var workspace Workspace
-	err = db.First(&workspace, "user_id = ?", user.ID).Error
+	err = db.First(&workspace, user.WorkspaceID).Error
 	assert.NoError(t, err, "Workspace should exist for user")
-	assert.Equal(t, user.ID, workspace.UserID, "Workspace UserID should match User ID")
+	assert.Equal(t, user.ID, workspace.OwnerID, "Workspace OwnerID should match User ID")
+	assert.Equal(t, DefaultWorkspaceName, workspace.Name, "Workspace should get the default name")
 }
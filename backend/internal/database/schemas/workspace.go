@@ -1,15 +1,62 @@
 package schemas
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const DefaultWorkspaceName = "My workspace"
 
 type Workspace struct {
-	UserID uint `gorm:"primaryKey;autoIncrement:false"`
-	Items  []Item `gorm:"foreignKey:WorkspaceID"`
+	ID          uint   `gorm:"primaryKey"`
+	OwnerID     uint   `gorm:"not null;index"`
+	Name        string `gorm:"not null"`
+	Description string `gorm:"not null;default:''"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Items       []Item `gorm:"foreignKey:WorkspaceID"`
+}
+
+// Roles a workspace can be shared with, from least to most privileged.
+// Commenters currently have the same rights as viewers.
+const (
+	WorkspaceRoleViewer    = "viewer"
+	WorkspaceRoleCommenter = "commenter"
+	WorkspaceRoleEditor    = "editor"
+	WorkspaceRoleOwner     = "owner"
+)
+
+var workspaceRoleRanks = map[string]int{
+	WorkspaceRoleViewer:    1,
+	WorkspaceRoleCommenter: 2,
+	WorkspaceRoleEditor:    3,
+	WorkspaceRoleOwner:     4,
+}
+
+func IsWorkspaceRole(role string) bool {
+	_, ok := workspaceRoleRanks[role]
+	return ok
+}
+
+// Report whether role grants at least the rights of required
+func WorkspaceRoleAtLeast(role, required string) bool {
+	rank, ok := workspaceRoleRanks[role]
+	return ok && rank >= workspaceRoleRanks[required]
+}
+
+// A user other than the owner who may access a workspace. Members added
+// before roles existed had full edit rights, hence the default.
+type WorkspaceMember struct {
+	WorkspaceID uint   `gorm:"primaryKey;autoIncrement:false"`
+	UserID      uint   `gorm:"primaryKey;autoIncrement:false;index"`
+	Role        string `gorm:"not null;default:editor"`
+	CreatedAt   time.Time
 }
 
 type Item struct {
 	ID          uint          `gorm:"primaryKey;autoIncrement:false"`
-	WorkspaceID uint          `gorm:"primaryKey;autoIncrement:false"` // Part of composite PK
+	WorkspaceID uint          `gorm:"primaryKey;autoIncrement:false;index:idx_items_bounds,priority:1;index:idx_items_revision,priority:1"` // Part of composite PK
 	PositionX   float64       `gorm:"not null"`
 	PositionY   float64       `gorm:"not null"`
 	ZIndex      uint          `gorm:"not null"`
@@ -17,6 +64,19 @@ type Item struct {
 	Height      float64       `gorm:"not null"`
 	Color       string        `gorm:"not null;default:'#FFFFFF'"`
 	Scale       float64       `gorm:"not null;default:1.0"`
+	// Rectangle the item covers on the board, derived from the geometry above
+	// by BeforeSave so that viewport queries can use an index
+	MinX        float64       `gorm:"not null;default:0;index:idx_items_bounds,priority:2"`
+	MinY        float64       `gorm:"not null;default:0;index:idx_items_bounds,priority:3"`
+	MaxX        float64       `gorm:"not null;default:0"`
+	MaxY        float64       `gorm:"not null;default:0"`
+	Revision    uint64        `gorm:"not null;default:0;index:idx_items_revision,priority:2"` // set by BeforeSave
+	// Bumped on every edit of the item; clients send back the version they
+	// edited so that stale writes are refused instead of overwriting others
+	Version     uint64        `gorm:"not null;default:1"`
+	// Set while the item is in the trash, which hides it from every query
+	// that is not Unscoped
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
 	TextItem    *TextItem     `gorm:"foreignKey:ItemID,WorkspaceID;references:ID,WorkspaceID"`
 	ImageItem   *ImageItem    `gorm:"foreignKey:ItemID,WorkspaceID;references:ID,WorkspaceID"`
 	ListItem    *TodoListItem `gorm:"foreignKey:ItemID,WorkspaceID;references:ID,WorkspaceID"`
@@ -24,6 +84,17 @@ type Item struct {
 	DrawingItem *DrawingItem  `gorm:"foreignKey:ItemID,WorkspaceID;references:ID,WorkspaceID"`
 }
 
+// Left behind by a deleted item so that clients syncing changes learn about
+// the deletion. Tombstones older than TombstoneRetention are pruned.
+type ItemTombstone struct {
+	WorkspaceID uint   `gorm:"primaryKey;autoIncrement:false"`
+	ItemID      uint   `gorm:"primaryKey;autoIncrement:false"`
+	Revision    uint64 `gorm:"not null;index"`
+	DeletedAt   time.Time
+}
+
+var TombstoneRetention = 30 * 24 * time.Hour
+
 type ShapeItem struct {
 	ItemID      uint   `gorm:"primaryKey;autoIncrement:false"`
 	WorkspaceID uint   `gorm:"primaryKey;autoIncrement:false"`
@@ -39,16 +110,16 @@ type TextItem struct {
 type ImageItem struct {
 	ItemID      uint   `gorm:"primaryKey;autoIncrement:false"`
 	WorkspaceID uint   `gorm:"primaryKey;autoIncrement:false"`
-	Bytes       string `gorm:"not null"`
+	AssetID     string `gorm:"not null;default:'';index"`
+	Asset       *Asset `gorm:"foreignKey:AssetID"`
 }
 
 type TodoListField struct {
-	ID             uint `gorm:"primaryKey;autoIncrement:false"`
-	TodoListItemID uint `gorm:"primaryKey;autoIncrement:false"`
-	WorkspaceID    uint `gorm:"primaryKey;autoIncrement:false"`
-	TextItemID     uint
-	TextItem       *TextItem `gorm:"foreignKey:TextItemID,WorkspaceID;references:ItemID,WorkspaceID"`
-	Done           bool      `gorm:"not null"`
+	ID             uint   `gorm:"primaryKey;autoIncrement:false"`
+	TodoListItemID uint   `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID    uint   `gorm:"primaryKey;autoIncrement:false"`
+	Content        string `gorm:"not null"`
+	Done           bool   `gorm:"not null"`
 }
 
 type TodoListItem struct {
@@ -57,57 +128,188 @@ type TodoListItem struct {
 	TodoListFields []TodoListField `gorm:"foreignKey:TodoListItemID,WorkspaceID;references:ItemID,WorkspaceID"`
 }
 
-type Point struct {
-	ID            uint    `gorm:"primaryKey;autoIncrement"`
-	DrawingItemID uint    `gorm:"not null;index"`
-	WorkspaceID   uint    `gorm:"not null;index"`
-	X             float64 `gorm:"not null"`
-	Y             float64 `gorm:"not null"`
-}
-
 type DrawingItem struct {
 	ItemID      uint `gorm:"primaryKey;autoIncrement:false"`
 	WorkspaceID uint `gorm:"primaryKey;autoIncrement:false"`
-	Points      []Point `gorm:"foreignKey:DrawingItemID,WorkspaceID;references:ItemID,WorkspaceID"`
+	Points      Points
+	// Style of the stroke, in the item's color. The defaults are how strokes
+	// were drawn before they had a style.
+	StrokeWidth float64 `gorm:"not null;default:3"` // in units of the item
+	Opacity     float64 `gorm:"not null;default:1"`
+	LineCap     string  `gorm:"not null;default:round"` // round, butt or square
+	LineJoin    string  `gorm:"not null;default:round"` // round, miter or bevel
+	Smoothing   string  `gorm:"not null;default:none"`  // none, quadratic or catmull-rom
 }
 
-// Assign a local, scoped within a workspace id to the item
-func (i *Item) BeforeCreate(tx *gorm.DB) error {
-	if i.ID != 0 {
-		return nil
+// Delete a workspace with its items, trashed or not, their typed sub-records
+// and its members
+func DeleteWorkspace(db *gorm.DB, workspaceID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		children := []interface{}{
+			&DrawingItem{},
+			&TodoListField{},
+			&TodoListItem{},
+			&ShapeItem{},
+			&ImageItem{},
+			&TextItem{},
+			&Item{},
+			&ItemTombstone{},
+			&HistoryAsset{},
+			&HistoryEntry{},
+			&SnapshotAsset{},
+			&WorkspaceSnapshot{},
+			&WorkspaceCounter{},
+			&WorkspaceMember{},
+		}
+		for _, child := range children {
+			if err := tx.Unscoped().Where("workspace_id = ?", workspaceID).Delete(child).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&Workspace{}, workspaceID).Error
+	})
+}
+
+// Move an item to the trash and report whether it was on the board. Its
+// typed sub-record is kept until the item is purged.
+func TrashItem(db *gorm.DB, workspaceID, itemID uint) (bool, error) {
+	// Allocated first, so that a counter seeded here still counts the item
+	revision, err := NextRevision(db, workspaceID)
+	if err != nil {
+		return false, err
+	}
+
+	result := db.Where("id = ? AND workspace_id = ?", itemID, workspaceID).Delete(&Item{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	tombstone := ItemTombstone{
+		WorkspaceID: workspaceID,
+		ItemID:      itemID,
+		Revision:    revision,
+		DeletedAt:   time.Now(),
 	}
+	if err := db.Create(&tombstone).Error; err != nil {
+		return false, err
+	}
+	return true, pruneTombstones(db, workspaceID, time.Now().Add(-TombstoneRetention))
+}
 
-	lastItem := &Item{}
-	if err := tx.Where("workspace_id = ?", i.WorkspaceID).Last(lastItem).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			i.ID = 1 // first item in the workspace
-			return nil
+// Permanently delete an item, trashed or not, with its typed sub-record
+func PurgeItem(db *gorm.DB, workspaceID, itemID uint) error {
+	children := []struct {
+		model  interface{}
+		column string
+	}{
+		{&DrawingItem{}, "item_id"},
+		{&TodoListField{}, "todo_list_item_id"},
+		{&TodoListItem{}, "item_id"},
+		{&ShapeItem{}, "item_id"},
+		{&ImageItem{}, "item_id"},
+		{&TextItem{}, "item_id"},
+	}
+	for _, child := range children {
+		if err := db.
+			Where(child.column+" = ? AND workspace_id = ?", itemID, workspaceID).
+			Delete(child.model).Error; err != nil {
+			return err
 		}
+	}
+	return db.Unscoped().Where("id = ? AND workspace_id = ?", itemID, workspaceID).Delete(&Item{}).Error
+}
+
+// Forget the deletions made before a point in time, remembering the revision
+// up to which they are gone: clients that synced before it must reload
+func pruneTombstones(db *gorm.DB, workspaceID uint, before time.Time) error {
+	var pruned uint64
+	if err := db.Model(&ItemTombstone{}).
+		Select("COALESCE(MAX(revision), 0)").
+		Where("workspace_id = ? AND deleted_at < ?", workspaceID, before).
+		Scan(&pruned).Error; err != nil {
+		return err
+	}
+	if pruned == 0 {
+		return nil
+	}
+
+	if err := db.Where("workspace_id = ? AND revision <= ?", workspaceID, pruned).
+		Delete(&ItemTombstone{}).Error; err != nil {
 		return err
 	}
+	return db.Model(&WorkspaceCounter{}).
+		Where("workspace_id = ? AND pruned_revision < ?", workspaceID, pruned).
+		UpdateColumn("pruned_revision", pruned).Error
+}
+
+// Report the rectangle covered by an item at the given position, scaled from
+// its width and height. Negative sizes extend to the left or top.
+func ItemBounds(x, y, width, height, scale float64) (minX, minY, maxX, maxY float64) {
+	w, h := width*scale, height*scale
+	return min(x, x+w), min(y, y+h), max(x, x+w), max(y, y+h)
+}
+
+// Report the rectangle an item covers on the board. A drawing also covers its
+// points, which clients do not always size it to; without a size, only them.
+func (i *Item) Bounds() (minX, minY, maxX, maxY float64) {
+	minX, minY, maxX, maxY = ItemBounds(i.PositionX, i.PositionY, i.Width, i.Height, i.Scale)
+	if i.DrawingItem == nil || len(i.DrawingItem.Points) == 0 {
+		return minX, minY, maxX, maxY
+	}
+
+	pMinX, pMinY, pMaxX, pMaxY := i.DrawingItem.Points.Extent()
+	dMinX, dMinY, dMaxX, dMaxY := ItemBounds(i.PositionX+pMinX*i.Scale, i.PositionY+pMinY*i.Scale, pMaxX-pMinX, pMaxY-pMinY, i.Scale)
+	if i.Width == 0 && i.Height == 0 {
+		return dMinX, dMinY, dMaxX, dMaxY
+	}
+	return min(minX, dMinX), min(minY, dMinY), max(maxX, dMaxX), max(maxY, dMaxY)
+}
 
-	i.ID = lastItem.ID + 1
+// Derive the bounding box and record the change in the workspace's revision.
+// A drawing's points must be loaded for its box to take them in.
+func (i *Item) BeforeSave(tx *gorm.DB) error {
+	i.MinX, i.MinY, i.MaxX, i.MaxY = i.Bounds()
+
+	revision, err := NextRevision(tx, i.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	i.Revision = revision
 	return nil
 }
 
-// Assign a local, scoped within a list in a workspace id to the todo list field
-func (f *TodoListField) BeforeCreate(tx *gorm.DB) error {
-	if f.ID != 0 {
+// Assign an id, scoped within the workspace, to the item
+func (i *Item) BeforeCreate(tx *gorm.DB) error {
+	if i.Version == 0 {
+		i.Version = 1
+	}
+	if i.ID != 0 {
 		return nil
 	}
 
-	var maxID uint
-	err := tx.Model(&TodoListField{}).
-		Where("todo_list_item_id = ? AND workspace_id = ?",
-			f.TodoListItemID,
-			f.WorkspaceID).
-		Select("COALESCE(MAX(id), 0)").
-		Scan(&maxID).Error
-
+	id, err := AllocateItemIDs(tx, i.WorkspaceID, 1)
 	if err != nil {
 		return err
 	}
 
-	f.ID = maxID + 1
+	i.ID = id
+	return nil
+}
+
+// Number the fields of a new list, scoped within the list. Fields added to an
+// existing list must be given their ids explicitly.
+func (l *TodoListItem) BeforeCreate(tx *gorm.DB) error {
+	var last uint
+	for _, f := range l.TodoListFields {
+		if f.ID > last {
+			last = f.ID
+		}
+	}
+
+	for i := range l.TodoListFields {
+		if l.TodoListFields[i].ID == 0 {
+			last++
+			l.TodoListFields[i].ID = last
+		}
+	}
 	return nil
 }
@@ -2,15 +2,17 @@ package database
 
 import (
 	"backend/config"
-	"backend/internal/database/schemas"
 
+	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"time"
+	"strings"
 
-	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/crypto/argon2"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -29,30 +31,106 @@ var (
 	dbPort     = config.C.DbPort
 )
 
-func Hash(login, password string) string {
+// argon2id parameters for newly created hashes
+const (
+	argonTime    = 2
+	argonMemory  = 19 * 1024 // KiB
+	argonThreads = 1
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+// Hash a password with argon2id into the PHC string format
+// $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>
+func Hash(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Unsalted sha256 hash used before argon2id; bound to the login
+func legacyHash(login, password string) string {
 	data := login + ":" + password + ":" + secret
 	hash := sha256.Sum256([]byte(data))
 	return hex.EncodeToString(hash[:])
 }
 
-func VerifyPassword(storedHash, login, inputPassword string) bool {
-    inputHash := Hash(login, inputPassword)
-    return subtle.ConstantTimeCompare([]byte(storedHash), []byte(inputHash)) == 1
+type argonParams struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
 }
 
-func CreateTokenForUser(user schemas.User) (string, error) {
-	claims := jwt.MapClaims{
-		"id":    user.ID,
-		"login": user.Login,
-		"role":  "user",
-		"exp":   time.Now().Add(time.Hour * 72).Unix(),
+func decodeArgonHash(encoded string) (*argonParams, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, errors.New("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, errors.New("unsupported argon2 version")
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.C.JwtSecret))
+
+	p := &argonParams{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return nil, fmt.Errorf("malformed argon2 parameters: %w", err)
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("malformed argon2 salt: %w", err)
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, fmt.Errorf("malformed argon2 key: %w", err)
+	}
+	return p, nil
 }
 
+// Check a password against a stored argon2id hash, falling back to the
+// legacy sha256 format, which also needs the login the hash was made with
+func VerifyPassword(storedHash, login, inputPassword string) bool {
+	if !strings.HasPrefix(storedHash, "$") {
+		inputHash := legacyHash(login, inputPassword)
+		return subtle.ConstantTimeCompare([]byte(storedHash), []byte(inputHash)) == 1
+	}
 
-func InitDatabase() error {
+	p, err := decodeArgonHash(storedHash)
+	if err != nil {
+		return false
+	}
+
+	inputKey := argon2.IDKey([]byte(inputPassword), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+	return subtle.ConstantTimeCompare(p.key, inputKey) == 1
+}
+
+// Report whether a stored hash is in the legacy format or was made with
+// weaker parameters than the current ones
+func NeedsRehash(storedHash string) bool {
+	p, err := decodeArgonHash(storedHash)
+	if err != nil {
+		return true
+	}
+	return p.memory < argonMemory ||
+		p.time < argonTime ||
+		p.threads < argonThreads ||
+		len(p.key) < argonKeyLen
+}
+
+// Connect to the database of the current environment: the SQLite dev DB when
+// APP_ENV is DEV, Postgres when it is PROD
+func Connect() error {
 	var err error
 
 	dsn := fmt.Sprintf(
@@ -72,36 +150,32 @@ func InitDatabase() error {
 		DB = DB.Debug() // debug postgres queries if needed
 	case "DEV":
 		DB, err = gorm.Open(
-			sqlite.Open("devDb.db"),
+			sqlite.Open("devDb.db?_busy_timeout=5000&_txlock=immediate"),
 			&gorm.Config{
 				TranslateError: true, // fix to properly return errors
 				// Logger: logger.Default.LogMode(logger.Silent), // silence the gorm logger
 			},
 		)
 		DB = DB.Debug() // outputs generated sql to stdout
+	default:
+		return fmt.Errorf("unknown APP_ENV %q; expected DEV or PROD", config.C.AppEnv)
 	}
 
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
+	return nil
+}
 
-	err = DB.AutoMigrate(
-		&schemas.User{},
-		&schemas.Workspace{},
-		&schemas.ImageItem{},
-		&schemas.Item{},
-		&schemas.TextItem{},
-		&schemas.TodoListField{},
-		&schemas.TodoListItem{},
-		&schemas.ShapeItem{},
-		&schemas.Point{},
-		&schemas.DrawingItem{},
-	)
-	
-	if err != nil {
+// Connect and apply pending migrations
+func InitDatabase() error {
+	if err := Connect(); err != nil {
+		return err
+	}
+
+	if _, err := MigrateUp(DB); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
-	
+
 	return nil
 }
-
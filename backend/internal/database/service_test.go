@@ -1,6 +1,7 @@
 package database
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -15,16 +16,48 @@ func TestHashAndVerifyPassword(t *testing.T) {
 	login := "testuser"
 	password := "testpassword"
 
-	hashed := Hash(login, password)
-	assert.NotEmpty(t, hashed, "Hash should not be empty")
+	hashed, err := Hash(password)
+	assert.NoError(t, err, "Hashing should not error")
+	assert.True(t, strings.HasPrefix(hashed, "$argon2id$"), "Hash should be in the argon2id format")
+	assert.False(t, NeedsRehash(hashed), "Fresh hash should not need a rehash")
+
+	// Salted, so the same password hashes differently
+	other, err := Hash(password)
+	assert.NoError(t, err)
+	assert.NotEqual(t, hashed, other, "Hashes of the same password should differ")
 
 	// Verify correct password
 	ok := VerifyPassword(hashed, login, password)
 	assert.True(t, ok, "Password verification should succeed for correct password")
 
+	// Login is not part of the hash
+	ok = VerifyPassword(hashed, "renamed", password)
+	assert.True(t, ok, "Password verification should not depend on the login")
+
 	// Verify incorrect password
 	ok = VerifyPassword(hashed, login, "wrongpassword")
 	assert.False(t, ok, "Password verification should fail for incorrect password")
+
+	// Malformed hash
+	ok = VerifyPassword("$argon2id$garbage", login, password)
+	assert.False(t, ok, "Password verification should fail for a malformed hash")
+}
+
+func TestLegacyPasswordHash(t *testing.T) {
+	login := "testuser"
+	password := "testpassword"
+
+	hashed := legacyHash(login, password)
+	assert.True(t, NeedsRehash(hashed), "Legacy hash should need a rehash")
+
+	ok := VerifyPassword(hashed, login, password)
+	assert.True(t, ok, "Legacy verification should succeed for correct password")
+
+	ok = VerifyPassword(hashed, login, "wrongpassword")
+	assert.False(t, ok, "Legacy verification should fail for incorrect password")
+
+	ok = VerifyPassword(hashed, "renamed", password)
+	assert.False(t, ok, "Legacy verification should fail for a different login")
 }
 
 func TestCreateTokenForUser(t *testing.T) {
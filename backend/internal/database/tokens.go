@@ -0,0 +1,238 @@
+package database
+
+import (
+	"backend/config"
+	"backend/internal/database/schemas"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+type TokenPair struct {
+	UserID       uint
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration // of the access token
+}
+
+// Mint a short-lived access token with a unique jti
+func CreateTokenForUser(user schemas.User) (string, error) {
+	token, _, err := createAccessToken(user)
+	return token, err
+}
+
+func createAccessToken(user schemas.User) (string, string, error) {
+	role := user.Role
+	if role == "" {
+		role = schemas.RoleUser
+	}
+
+	jti := uuid.NewString()
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"jti":   jti,
+		"id":    user.ID,
+		"login": user.Login,
+		"role":  role,
+		"iat":   now.Unix(),
+		"exp":   now.Add(AccessTokenTTL).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(config.C.JwtSecret))
+	return signed, jti, err
+}
+
+func hashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// Mint an access token and a refresh token in the given family; only the
+// hash of the refresh token is stored
+func issueTokenPair(tx *gorm.DB, user schemas.User, familyID string) (*TokenPair, error) {
+	accessToken, jti, err := createAccessToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(raw)
+
+	err = tx.Create(&schemas.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashRefreshToken(refreshToken),
+		AccessJTI: jti,
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		UserID:       user.ID,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    AccessTokenTTL,
+	}, nil
+}
+
+// Start a new token family, e.g. on login
+func IssueTokenPair(user schemas.User) (*TokenPair, error) {
+	return issueTokenPair(DB, user, uuid.NewString())
+}
+
+// Exchange a refresh token for a new pair. Presenting a token that was
+// already rotated or revoked revokes its whole family.
+func RotateRefreshToken(refreshToken string) (*TokenPair, error) {
+	var pair *TokenPair
+	reused := false
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var current schemas.RefreshToken
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&current, "token_hash = ?", hashRefreshToken(refreshToken)).
+			Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+
+		if current.RevokedAt != nil {
+			reused = true
+			return revokeFamily(tx, current.FamilyID)
+		}
+
+		if time.Now().After(current.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		now := time.Now()
+		result := tx.Model(&schemas.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Update("revoked_at", &now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Lost a race with a concurrent rotation of the same token
+			reused = true
+			return revokeFamily(tx, current.FamilyID)
+		}
+
+		var user schemas.User
+		if err := tx.First(&user, current.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+
+		var err error
+		pair, err = issueTokenPair(tx, user, current.FamilyID)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrRefreshTokenReused
+	}
+	return pair, nil
+}
+
+// Revoke the family of a refresh token, ending that session
+func RevokeRefreshToken(refreshToken string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var current schemas.RefreshToken
+		if err := tx.First(&current, "token_hash = ?", hashRefreshToken(refreshToken)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		return revokeFamily(tx, current.FamilyID)
+	})
+}
+
+// Revoke every refresh token of a user and the access tokens issued with
+// them, in db or a transaction the caller runs
+func RevokeAllUserTokens(db *gorm.DB, userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return revokeTokens(tx, "user_id = ?", userID)
+	})
+}
+
+func revokeFamily(tx *gorm.DB, familyID string) error {
+	return revokeTokens(tx, "family_id = ?", familyID)
+}
+
+// Revoke the refresh tokens matching the condition, and blacklist the access
+// tokens issued with them that may still be live
+func revokeTokens(tx *gorm.DB, query string, arg any) error {
+	now := time.Now()
+
+	var tokens []schemas.RefreshToken
+	if err := tx.Where(query, arg).
+		Where("created_at > ?", now.Add(-AccessTokenTTL)).
+		Find(&tokens).Error; err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		if err := revokeAccessToken(tx, t.AccessJTI, t.CreatedAt.Add(AccessTokenTTL)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&schemas.RefreshToken{}).
+		Where(query, arg).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", &now).Error
+}
+
+// Blacklist an access token until it would have expired anyway
+func RevokeAccessToken(jti string, expiresAt time.Time) error {
+	return revokeAccessToken(DB, jti, expiresAt)
+}
+
+func revokeAccessToken(tx *gorm.DB, jti string, expiresAt time.Time) error {
+	// Entries past their expiry are useless; drop them while we are here
+	if err := tx.Where("expires_at < ?", time.Now()).Delete(&schemas.RevokedToken{}).Error; err != nil {
+		return err
+	}
+
+	return tx.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&schemas.RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+func IsTokenRevoked(jti string) (bool, error) {
+	var count int64
+	err := DB.Model(&schemas.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, err
+}
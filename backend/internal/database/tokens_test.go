@@ -0,0 +1,108 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"backend/internal/database/schemas"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTokenTestDB(t *testing.T) schemas.User {
+	db, err := gorm.Open(
+		sqlite.Open("file::memory:"), &gorm.Config{
+			TranslateError: true,
+			Logger:         logger.Default.LogMode(logger.Silent),
+		},
+	)
+	if err != nil {
+		t.Fatal("failed to connect test database")
+	}
+	if err := db.AutoMigrate(&schemas.User{}, &schemas.RefreshToken{}, &schemas.RevokedToken{}); err != nil {
+		t.Fatal("failed to migrate test database")
+	}
+	DB = db
+
+	user := schemas.User{Login: "testuser", PasswordHash: "hash"}
+	if err := DB.Create(&user).Error; err != nil {
+		t.Fatal("failed to create test user")
+	}
+	return user
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	user := setupTokenTestDB(t)
+
+	first, err := IssueTokenPair(user)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, first.AccessToken)
+	assert.NotEmpty(t, first.RefreshToken)
+	assert.Equal(t, user.ID, first.UserID)
+
+	second, err := RotateRefreshToken(first.RefreshToken)
+	assert.NoError(t, err, "Rotating a fresh token should succeed")
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	// Unknown token
+	_, err = RotateRefreshToken("not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// Replaying the rotated token kills the whole family
+	_, err = RotateRefreshToken(first.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	_, err = RotateRefreshToken(second.RefreshToken)
+	assert.Error(t, err, "Descendants of a reused token should be revoked")
+
+	var tokens []schemas.RefreshToken
+	DB.Find(&tokens)
+	for _, token := range tokens {
+		assert.NotNil(t, token.RevokedAt)
+		revoked, err := IsTokenRevoked(token.AccessJTI)
+		assert.NoError(t, err)
+		assert.True(t, revoked, "Access tokens of the family should be revoked")
+	}
+}
+
+func TestRevokeAllUserTokens(t *testing.T) {
+	user := setupTokenTestDB(t)
+
+	laptop, err := IssueTokenPair(user)
+	assert.NoError(t, err)
+	phone, err := IssueTokenPair(user)
+	assert.NoError(t, err)
+
+	assert.NoError(t, RevokeAllUserTokens(DB, user.ID))
+
+	_, err = RotateRefreshToken(laptop.RefreshToken)
+	assert.Error(t, err)
+	_, err = RotateRefreshToken(phone.RefreshToken)
+	assert.Error(t, err)
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	setupTokenTestDB(t)
+
+	assert.NoError(t, RevokeAccessToken("live", time.Now().Add(time.Hour)))
+	assert.NoError(t, RevokeAccessToken("stale", time.Now().Add(-time.Hour)))
+	// Revoking twice is harmless
+	assert.NoError(t, RevokeAccessToken("live", time.Now().Add(time.Hour)))
+
+	revoked, err := IsTokenRevoked("live")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = IsTokenRevoked("unknown")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// Expired entries are purged on the next revocation
+	assert.NoError(t, RevokeAccessToken("other", time.Now().Add(time.Hour)))
+	var count int64
+	DB.Model(&schemas.RevokedToken{}).Where("jti = ?", "stale").Count(&count)
+	assert.Equal(t, int64(0), count)
+}
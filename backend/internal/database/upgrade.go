@@ -0,0 +1,103 @@
+package database
+
+import (
+	"backend/internal/database/schemas"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Workspaces used to be keyed by their owner's user_id, one per user. Turn
+// each of them into a named workspace, keeping the key as its id so that
+// items and users.workspace_id still point at it.
+func upgradeLegacyWorkspaces(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable("workspaces") || !m.HasColumn("workspaces", "user_id") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.RenameTable("workspaces", "legacy_workspaces"); err != nil {
+			return err
+		}
+		if tx.Dialector.Name() == "postgres" {
+			// Index names are schema-wide, so the new table's pkey would clash
+			if err := tx.Exec("ALTER INDEX workspaces_pkey RENAME TO legacy_workspaces_pkey").Error; err != nil {
+				return err
+			}
+		}
+
+		if err := m.CreateTable(&workspaceV1{}); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Exec(
+			`INSERT INTO workspaces (id, owner_id, name, description, created_at, updated_at)
+			SELECT user_id, user_id, ?, '', ?, ? FROM legacy_workspaces`,
+			schemas.DefaultWorkspaceName, now, now,
+		).Error; err != nil {
+			return err
+		}
+
+		if err := m.DropTable("legacy_workspaces"); err != nil {
+			return err
+		}
+
+		if tx.Dialector.Name() == "postgres" {
+			// Ids were inserted explicitly; move the sequence past them
+			return tx.Exec(
+				`SELECT setval(pg_get_serial_sequence('workspaces', 'id'),
+				(SELECT COALESCE(MAX(id), 0) + 1 FROM workspaces), false)`,
+			).Error
+		}
+		return nil
+	})
+}
+
+// Todo fields used to keep their text in a text_items row of their own,
+// pointed at by text_item_id. Move it into the field's content column; the
+// column is only made NOT NULL once every row has a value.
+func upgradeLegacyTodoFields(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable("todo_list_fields") || !m.HasColumn("todo_list_fields", "text_item_id") ||
+		m.HasColumn("todo_list_fields", "content") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := tx.Exec("ALTER TABLE todo_list_fields ADD COLUMN content TEXT").Error; err != nil {
+			return err
+		}
+		if m.HasTable("text_items") && m.HasTable("items") {
+			if err := tx.Exec(
+				`UPDATE todo_list_fields SET content = (SELECT text_items.content FROM text_items
+				WHERE text_items.item_id = todo_list_fields.text_item_id
+				AND text_items.workspace_id = todo_list_fields.workspace_id)`,
+			).Error; err != nil {
+				return err
+			}
+			// The text rows belong to no item; left behind, they would
+			// collide with items later given their ids
+			if err := tx.Exec(
+				`DELETE FROM text_items WHERE EXISTS (SELECT 1 FROM todo_list_fields
+				WHERE todo_list_fields.text_item_id = text_items.item_id
+				AND todo_list_fields.workspace_id = text_items.workspace_id)
+				AND NOT EXISTS (SELECT 1 FROM items
+				WHERE items.id = text_items.item_id AND items.workspace_id = text_items.workspace_id)`,
+			).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("UPDATE todo_list_fields SET content = '' WHERE content IS NULL").Error; err != nil {
+			return err
+		}
+
+		if err := m.AlterColumn(&todoListFieldV1{}, "Content"); err != nil {
+			return err
+		}
+		return m.DropColumn(&todoListFieldV1{}, "text_item_id")
+	})
+}
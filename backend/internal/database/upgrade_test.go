@@ -0,0 +1,117 @@
+package database
+
+import (
+	"testing"
+
+	"backend/internal/database/schemas"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Workspace as stored before workspaces had their own ids
+type legacyWorkspace struct {
+	UserID uint `gorm:"primaryKey;autoIncrement:false"`
+}
+
+func (legacyWorkspace) TableName() string {
+	return "workspaces"
+}
+
+func TestUpgradeLegacyWorkspaces(t *testing.T) {
+	db, err := gorm.Open(
+		sqlite.Open("file::memory:"), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		},
+	)
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.AutoMigrate(&schemas.User{}, &legacyWorkspace{}))
+	for _, login := range []string{"first", "second"} {
+		user := schemas.User{Login: login, PasswordHash: "hash"}
+		assert.NoError(t, db.Create(&user).Error)
+		user.WorkspaceID = user.ID
+		assert.NoError(t, db.Save(&user).Error)
+		assert.NoError(t, db.Create(&legacyWorkspace{UserID: user.ID}).Error)
+	}
+
+	assert.NoError(t, upgradeLegacyWorkspaces(db))
+	assert.False(t, db.Migrator().HasColumn("workspaces", "user_id"))
+
+	var workspaces []schemas.Workspace
+	assert.NoError(t, db.Order("id").Find(&workspaces).Error)
+	if assert.Equal(t, 2, len(workspaces)) {
+		for i, workspace := range workspaces {
+			assert.Equal(t, uint(i+1), workspace.ID, "Workspace ids should be kept")
+			assert.Equal(t, workspace.ID, workspace.OwnerID)
+			assert.Equal(t, schemas.DefaultWorkspaceName, workspace.Name)
+		}
+	}
+
+	// New workspaces get fresh ids
+	workspace := schemas.Workspace{OwnerID: 1, Name: "Project"}
+	assert.NoError(t, db.Create(&workspace).Error)
+	assert.Equal(t, uint(3), workspace.ID)
+
+	// Running again is a no-op
+	assert.NoError(t, upgradeLegacyWorkspaces(db))
+	var count int64
+	db.Model(&schemas.Workspace{}).Count(&count)
+	assert.Equal(t, int64(3), count)
+}
+
+// Todo field as stored when its text was a text item of its own
+type legacyTodoListField struct {
+	ID             uint `gorm:"primaryKey;autoIncrement:false"`
+	TodoListItemID uint `gorm:"primaryKey;autoIncrement:false"`
+	WorkspaceID    uint `gorm:"primaryKey;autoIncrement:false"`
+	TextItemID     uint
+	Done           bool `gorm:"not null"`
+}
+
+func (legacyTodoListField) TableName() string {
+	return "todo_list_fields"
+}
+
+func TestUpgradeLegacyTodoFields(t *testing.T) {
+	db := setupMigrationTestDB(t)
+
+	assert.NoError(t, db.AutoMigrate(&itemV1{}, &textItemV1{}, &legacyTodoListField{}))
+	assert.NoError(t, db.Create(&[]itemV1{{ID: 1, WorkspaceID: 1}, {ID: 2, WorkspaceID: 1}}).Error)
+	assert.NoError(t, db.Create(&[]textItemV1{
+		{ItemID: 1, WorkspaceID: 1, Content: "A text item"},
+		{ItemID: 10, WorkspaceID: 1, Content: "Buy milk"},
+		{ItemID: 11, WorkspaceID: 1, Content: "Call back"},
+	}).Error)
+	assert.NoError(t, db.Create(&[]legacyTodoListField{
+		{ID: 1, TodoListItemID: 2, WorkspaceID: 1, TextItemID: 10},
+		{ID: 2, TodoListItemID: 2, WorkspaceID: 1, TextItemID: 11, Done: true},
+		{ID: 3, TodoListItemID: 2, WorkspaceID: 1, TextItemID: 12},
+	}).Error)
+
+	assert.NoError(t, upgradeLegacyTodoFields(db))
+	assert.False(t, db.Migrator().HasColumn("todo_list_fields", "text_item_id"))
+
+	var fields []todoListFieldV1
+	assert.NoError(t, db.Order("id").Find(&fields).Error)
+	if assert.Equal(t, 3, len(fields)) {
+		assert.Equal(t, "Buy milk", fields[0].Content, "Todo text should be kept")
+		assert.Equal(t, "Call back", fields[1].Content)
+		assert.True(t, fields[1].Done)
+		assert.Equal(t, "", fields[2].Content, "Fields without text should be left empty")
+	}
+
+	var texts []textItemV1
+	assert.NoError(t, db.Find(&texts).Error)
+	assert.Equal(t, []textItemV1{{ItemID: 1, WorkspaceID: 1, Content: "A text item"}}, texts,
+		"Only the text of items should be left")
+
+	// The content column is NOT NULL from now on
+	assert.Error(t, db.Exec("INSERT INTO todo_list_fields (id, todo_list_item_id, workspace_id, content, done) VALUES (4, 2, 1, NULL, false)").Error)
+
+	// Running again is a no-op
+	assert.NoError(t, upgradeLegacyTodoFields(db))
+	assert.NoError(t, db.Transaction(upInitialSchema))
+}
@@ -0,0 +1,172 @@
+package excalidraw
+
+import (
+	"backend/internal/database/schemas"
+	"backend/internal/export"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Styling of exported elements, close to what Excalidraw draws by default
+const (
+	strokeColor      = "#1e1e1e"
+	placeholderColor = "#d0d0d0"
+	textPadding      = 8
+	lineHeight       = 1.25
+	fontFamily       = 2 // Helvetica
+)
+
+// Convert items to a scene, bottom first. Text items and todo lists become
+// rectangles with their text bound to them. Images that cannot be loaded are
+// left as dashed placeholders.
+func FromItems(ctx context.Context, items []schemas.Item, images export.ImageLoader) Scene {
+	scene := Scene{
+		Type:     sceneType,
+		Version:  2,
+		Source:   "prodspace",
+		Elements: []Element{},
+		AppState: map[string]any{"viewBackgroundColor": "#ffffff", "gridSize": nil},
+		Files:    map[string]File{},
+	}
+	now := time.Now().UnixMilli()
+
+	for _, item := range export.NewBoard(items).Items {
+		minX, minY, maxX, maxY := schemas.ItemBounds(item.PositionX, item.PositionY, item.Width, item.Height, item.Scale)
+		e := Element{
+			ID:              fmt.Sprintf("item-%d", item.ID),
+			X:               minX,
+			Y:               minY,
+			Width:           maxX - minX,
+			Height:          maxY - minY,
+			StrokeColor:     strokeColor,
+			BackgroundColor: cssColor(item.Color),
+			FillStyle:       "solid",
+			StrokeWidth:     1,
+			StrokeStyle:     "solid",
+			Opacity:         percent(100),
+			GroupIDs:        []string{},
+			Seed:            int64(item.ID),
+			Version:         int64(item.Version),
+			VersionNonce:    int64(item.Revision),
+			Updated:         now,
+		}
+
+		switch {
+		case item.ShapeItem != nil:
+			e.Type = "rectangle"
+			if export.IsRound(item.ShapeItem.Name) {
+				e.Type = "ellipse"
+			}
+			e.StrokeWidth = 2
+			scene.Elements = append(scene.Elements, e)
+
+		case item.TextItem != nil || item.ListItem != nil:
+			var content string
+			if item.TextItem != nil {
+				content = item.TextItem.Content
+			} else {
+				lines := make([]string, 0, len(item.ListItem.TodoListFields))
+				for _, field := range item.ListItem.TodoListFields {
+					box := uncheckedBox
+					if field.Done {
+						box = checkedBox
+					}
+					lines = append(lines, box+field.Content)
+				}
+				content = strings.Join(lines, "\n")
+			}
+			scene.Elements = append(scene.Elements, boundText(e, content, item.Scale)...)
+
+		case item.DrawingItem != nil:
+			e.Type = "freedraw"
+			e.X, e.Y = item.PositionX, item.PositionY
+			e.StrokeColor = cssColor(item.Color)
+			e.BackgroundColor = "transparent"
+			e.StrokeWidth = item.DrawingItem.StrokeWidth * item.Scale
+			e.Opacity = percent(math.Round(item.DrawingItem.Opacity * 100))
+			e.Points = make([][2]float64, 0, len(item.DrawingItem.Points))
+			e.Pressures = []float64{}
+			pressure := item.DrawingItem.Points.HasPressure()
+			for _, p := range item.DrawingItem.Points {
+				e.Points = append(e.Points, [2]float64{p.X * item.Scale, p.Y * item.Scale})
+				if pressure {
+					e.Pressures = append(e.Pressures, p.Pressure)
+				}
+			}
+			e.SimulatePressure = len(e.Pressures) == 0
+			scene.Elements = append(scene.Elements, e)
+
+		case item.ImageItem != nil:
+			e.BackgroundColor = "transparent"
+			err := fmt.Errorf("image %s is not stored", item.ImageItem.AssetID)
+			if item.ImageItem.Asset != nil {
+				var data []byte
+				var contentType string
+				if data, contentType, err = images(ctx, item.ImageItem.Asset); err == nil {
+					fileID := item.ImageItem.AssetID
+					if _, ok := scene.Files[fileID]; !ok {
+						scene.Files[fileID] = newFile(fileID, contentType, data, item.ImageItem.Asset.CreatedAt.UnixMilli())
+					}
+					e.Type = "image"
+					e.FileID = &fileID
+					e.Status = "saved"
+					e.Scale = &[2]float64{1, 1}
+				}
+			}
+			if err != nil {
+				e.Type = "rectangle"
+				e.StrokeColor = placeholderColor
+				e.StrokeStyle = "dashed"
+			}
+			scene.Elements = append(scene.Elements, e)
+		}
+	}
+	return scene
+}
+
+// A rectangle with a text element laid out in it, from its top left corner
+func boundText(container Element, content string, scale float64) []Element {
+	text := container
+	text.ID = container.ID + "-text"
+	text.Type = "text"
+	text.X += textPadding * scale
+	text.Y += textPadding * scale
+	text.Width = max(container.Width-2*textPadding*scale, 0)
+	text.Height = max(container.Height-2*textPadding*scale, 0)
+	text.BackgroundColor = "transparent"
+	text.Seed++
+	text.Text = content
+	text.OriginalText = content
+	text.FontSize = itemFontSize * scale
+	text.FontFamily = fontFamily
+	text.TextAlign = "left"
+	text.VerticalAlign = "top"
+	text.ContainerID = &container.ID
+	text.LineHeight = lineHeight
+
+	container.Type = "rectangle"
+	container.BoundElements = []BoundElement{{ID: text.ID, Type: "text"}}
+	return []Element{container, text}
+}
+
+func percent(v float64) *float64 {
+	return &v
+}
+
+// Convert an item color to CSS. Colors that are not painted are transparent.
+func cssColor(s string) string {
+	if s == "" {
+		return "transparent"
+	}
+	c := export.ParseColor(s)
+	switch c.A {
+	case 0:
+		return "transparent"
+	case 0xFF:
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+}
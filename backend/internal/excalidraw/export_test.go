@@ -0,0 +1,119 @@
+package excalidraw
+
+import (
+	"backend/internal/database/schemas"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromItems(t *testing.T) {
+	items := []schemas.Item{
+		{ID: 1, ZIndex: 2, PositionX: 10, PositionY: 20, Width: 100, Height: 50, Scale: 1, Color: "4294953417",
+			TextItem: &schemas.TextItem{Content: "Plan"}},
+		{ID: 2, ZIndex: 1, Width: 100, Height: 60, Scale: 2, Color: "0",
+			ListItem: &schemas.TodoListItem{TodoListFields: []schemas.TodoListField{{Content: "Ship", Done: true}, {Content: "Rest"}}}},
+		{ID: 3, ZIndex: 1, PositionX: 50, Width: -10, Height: 10, Scale: 1, Color: "#1971C280",
+			ShapeItem: &schemas.ShapeItem{Name: "circle"}},
+		{ID: 4, ZIndex: 3, PositionX: 5, PositionY: 5, Width: 10, Height: 10, Scale: 2, Color: "4292882737",
+			DrawingItem: &schemas.DrawingItem{Points: []schemas.Point{{X: 0, Y: 0, Pressure: 0.5}, {X: 10, Y: 5, Pressure: 1}},
+				StrokeWidth: 3, Opacity: 0.4}},
+		{ID: 5, ZIndex: 4, Width: 30, Height: 20, Scale: 1,
+			ImageItem: &schemas.ImageItem{AssetID: "a1", Asset: &schemas.Asset{ID: "a1"}}},
+		{ID: 6, ZIndex: 4, Width: 30, Height: 20, Scale: 1,
+			ImageItem: &schemas.ImageItem{AssetID: "a2", Asset: &schemas.Asset{ID: "a2"}}},
+	}
+	images := func(ctx context.Context, asset *schemas.Asset) ([]byte, string, error) {
+		if asset.ID == "a2" {
+			return nil, "", errors.New("blob is gone")
+		}
+		return []byte("png"), "image/png", nil
+	}
+
+	scene := FromItems(context.Background(), items, images)
+	assert.NoError(t, scene.Validate())
+
+	var ids, types []string
+	byID := make(map[string]Element)
+	for _, e := range scene.Elements {
+		ids = append(ids, e.ID)
+		types = append(types, e.Type)
+		byID[e.ID] = e
+	}
+	assert.Equal(t, []string{"item-2", "item-2-text", "item-3", "item-1", "item-1-text", "item-4", "item-5", "item-6"}, ids)
+	assert.Equal(t, []string{"rectangle", "text", "ellipse", "rectangle", "text", "freedraw", "image", "rectangle"}, types)
+
+	note, label := byID["item-1"], byID["item-1-text"]
+	assert.Equal(t, "#ffc9c9", note.BackgroundColor)
+	assert.Equal(t, []BoundElement{{ID: "item-1-text", Type: "text"}}, note.BoundElements)
+	assert.Equal(t, "item-1", *label.ContainerID)
+	assert.Equal(t, "Plan", label.Text)
+
+	todo := byID["item-2-text"]
+	assert.Equal(t, "☑ Ship\n☐ Rest", todo.Text)
+	assert.Equal(t, 32.0, todo.FontSize, "Text should be scaled with its item")
+	assert.Equal(t, "transparent", byID["item-2"].BackgroundColor)
+
+	circle := byID["item-3"]
+	assert.Equal(t, []float64{40, 0, 10, 10}, []float64{circle.X, circle.Y, circle.Width, circle.Height})
+	assert.Equal(t, "#1971c280", circle.BackgroundColor)
+
+	drawing := byID["item-4"]
+	assert.Equal(t, [][2]float64{{0, 0}, {20, 10}}, drawing.Points)
+	assert.Equal(t, "#e03131", drawing.StrokeColor)
+	assert.Equal(t, []float64{6, 40}, []float64{drawing.StrokeWidth, drawing.opacity()})
+	assert.Equal(t, []float64{0.5, 1}, drawing.Pressures)
+	assert.False(t, drawing.SimulatePressure)
+
+	if assert.NotNil(t, byID["item-5"].FileID) {
+		file := scene.Files[*byID["item-5"].FileID]
+		assert.Equal(t, "image/png", file.MimeType)
+		data, err := file.Data()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("png"), data)
+	}
+	assert.Equal(t, "dashed", byID["item-6"].StrokeStyle, "Missing images should be left as placeholders")
+	assert.Equal(t, 1, len(scene.Files))
+}
+
+func TestRoundTrip(t *testing.T) {
+	items := []schemas.Item{
+		{ID: 1, PositionX: 10, PositionY: 20, Width: 100, Height: 50, Scale: 1.5, Color: "4294953417",
+			TextItem: &schemas.TextItem{Content: "Plan\nthe launch"}},
+		{ID: 2, ZIndex: 1, Width: 100, Height: 60, Scale: 1, Color: "4278190335",
+			ListItem: &schemas.TodoListItem{TodoListFields: []schemas.TodoListField{{Content: "Ship", Done: true}, {Content: "Rest"}}}},
+		{ID: 3, ZIndex: 2, PositionX: -40, PositionY: 8, Width: 40, Height: 30, Scale: 1, Color: "2149151170",
+			ShapeItem: &schemas.ShapeItem{Name: "rectangle"}},
+		{ID: 4, ZIndex: 3, PositionX: 5, PositionY: 5, Width: 10, Height: 5, Scale: 1, Color: "4292882737",
+			DrawingItem: &schemas.DrawingItem{Points: []schemas.Point{{X: 0, Y: 0}, {X: 10, Y: 5}},
+				StrokeWidth: 8, Opacity: 0.4, Smoothing: "quadratic"}},
+	}
+
+	converted, unsupported := ToItems(FromItems(context.Background(), items, nil))
+	assert.Empty(t, unsupported)
+	if !assert.Equal(t, len(items), len(converted)) {
+		return
+	}
+	for i, item := range items {
+		got := converted[i]
+		assert.Equal(t, item.Color, got.Color)
+		assert.Equal(t, []float64{item.PositionX, item.PositionY}, []float64{got.PositionX, got.PositionY})
+		assert.InDelta(t, item.Width*item.Scale, got.Width*got.Scale, 1e-9)
+		assert.InDelta(t, item.Height*item.Scale, got.Height*got.Scale, 1e-9)
+	}
+	assert.Equal(t, "Plan\nthe launch", converted[0].TextItem.Content)
+	assert.Equal(t, 1.5, converted[0].Scale, "Text should keep its size")
+	if assert.NotNil(t, converted[1].TodoList) {
+		assert.Equal(t, "Ship", (*converted[1].TodoList)[0].TextItem.Content)
+		assert.True(t, (*converted[1].TodoList)[0].Done)
+	}
+	assert.Equal(t, "rectangle", converted[2].ShapeItem.Name)
+	if drawing := converted[3].DrawingItem; assert.NotNil(t, drawing) {
+		assert.Equal(t, 2, len(drawing.Points))
+		assert.Equal(t, []float64{8, 0.4}, []float64{drawing.StrokeWidth, drawing.Opacity})
+		assert.Equal(t, "quadratic", drawing.Smoothing)
+		assert.Zero(t, drawing.Points[1].Pressure)
+	}
+}
@@ -0,0 +1,235 @@
+package excalidraw
+
+import (
+	"backend/internal/export"
+	"backend/internal/models"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Size of the text of items, which text elements are scaled against
+const itemFontSize = 16
+
+// Boxes that start the lines of a todo list written out as text
+const (
+	checkedBox   = "☑ "
+	uncheckedBox = "☐ "
+)
+
+// An item converted from an element. Image items show the file of the scene
+// whose id is their asset id.
+type Item struct {
+	ElementID string
+	models.ItemCreate
+}
+
+// An element that was left out, or imported without some of its features
+type Unsupported struct {
+	ElementID string
+	Type      string
+	Reason    string
+	Skipped   bool
+}
+
+// Convert the elements of a scene to items, bottom first. Text bound to a
+// rectangle is imported with it as a text item, or as a todo list when every
+// line starts with a box. Deleted elements are ignored.
+func ToItems(scene Scene) ([]Item, []Unsupported) {
+	elements := make(map[string]*Element, len(scene.Elements))
+	for i := range scene.Elements {
+		if !scene.Elements[i].IsDeleted {
+			elements[scene.Elements[i].ID] = &scene.Elements[i]
+		}
+	}
+	bound := make(map[string]*Element)
+	for _, e := range elements {
+		if e.Type != "text" || e.ContainerID == nil {
+			continue
+		}
+		if container := elements[*e.ContainerID]; container != nil && container.Type == "rectangle" {
+			bound[container.ID] = e
+		}
+	}
+
+	var items []Item
+	var unsupported []Unsupported
+	for i := range scene.Elements {
+		e := &scene.Elements[i]
+		if e.IsDeleted || (e.ContainerID != nil && bound[*e.ContainerID] == e) {
+			continue
+		}
+
+		item, reason := toItem(e, bound[e.ID], scene.Files)
+		if item == nil {
+			unsupported = append(unsupported, Unsupported{ElementID: e.ID, Type: e.Type, Reason: reason, Skipped: true})
+			continue
+		}
+		if e.Angle != 0 {
+			unsupported = append(unsupported, Unsupported{ElementID: e.ID, Type: e.Type, Reason: "rotation is not supported, imported upright"})
+		}
+		item.ZIndex = uint(len(items))
+		items = append(items, Item{ElementID: e.ID, ItemCreate: *item})
+	}
+	return items, unsupported
+}
+
+// Convert one element, or tell why it cannot be
+func toItem(e *Element, text *Element, files map[string]File) (*models.ItemCreate, string) {
+	// Items cannot be invisible: a drawing's opacity of 0 stands for the default
+	if e.opacity() <= 0 {
+		return nil, "element is fully transparent"
+	}
+
+	item := &models.ItemCreate{
+		PositionX: e.X,
+		PositionY: e.Y,
+		Width:     e.Width,
+		Height:    e.Height,
+		Scale:     1,
+		Color:     appColor(e.BackgroundColor, e.opacity()),
+	}
+
+	switch e.Type {
+	case "rectangle":
+		if text == nil || textContent(text) == "" {
+			item.ShapeItem = &models.ShapeItemCreate{Name: "rectangle"}
+			return item, ""
+		}
+		content := textContent(text)
+		scaleToText(item, text)
+		if fields, ok := todoFields(content); ok {
+			item.TodoList = &fields
+		} else {
+			item.TextItem = &models.TextItemCreate{Content: content}
+		}
+
+	case "ellipse":
+		item.ShapeItem = &models.ShapeItemCreate{Name: "circle"}
+
+	case "text":
+		content := textContent(e)
+		if content == "" {
+			return nil, "text is empty"
+		}
+		scaleToText(item, e)
+		item.Color = appColor("transparent", e.opacity())
+		item.TextItem = &models.TextItemCreate{Content: content}
+
+	case "freedraw", "line":
+		if len(e.Points) == 0 {
+			return nil, "drawing has no points"
+		}
+		// Points are kept relative to the top left corner of the drawing
+		minX, minY := math.Inf(1), math.Inf(1)
+		maxX, maxY := math.Inf(-1), math.Inf(-1)
+		for _, p := range e.Points {
+			minX, minY = min(minX, p[0]), min(minY, p[1])
+			maxX, maxY = max(maxX, p[0]), max(maxY, p[1])
+		}
+		// Pressures recorded by the pen, unless Excalidraw made them up
+		pressures := e.Pressures
+		if e.SimulatePressure || len(pressures) != len(e.Points) {
+			pressures = nil
+		}
+		points := make([]models.DrawingPointCreate, 0, len(e.Points))
+		for i, p := range e.Points {
+			point := models.DrawingPointCreate{X: p[0] - minX, Y: p[1] - minY}
+			if pressures != nil {
+				// A pressure of 0 would mean none was recorded
+				point.Pressure = min(max(pressures[i], 0.001), 1)
+			}
+			points = append(points, point)
+		}
+		item.PositionX, item.PositionY = e.X+minX, e.Y+minY
+		item.Width, item.Height = maxX-minX, maxY-minY
+		item.Color = appColor(e.StrokeColor, 100)
+		item.DrawingItem = &models.DrawingItemCreate{
+			Points:      points,
+			StrokeWidth: max(e.StrokeWidth, 0),
+			Opacity:     min(max(e.opacity(), 0), 100) / 100,
+			Smoothing:   smoothing(e),
+		}
+
+	case "image":
+		if e.FileID == nil || files[*e.FileID].DataURL == "" {
+			return nil, "image file is missing from the scene"
+		}
+		item.Color = ""
+		item.ImageItem = &models.ImageItemCreate{AssetID: *e.FileID}
+
+	default:
+		return nil, fmt.Sprintf("%s elements are not supported", e.Type)
+	}
+	return item, ""
+}
+
+// How Excalidraw smooths a free drawing or line: free drawings are always
+// smoothed, lines only when rounded
+func smoothing(e *Element) string {
+	switch {
+	case e.Type == "freedraw":
+		return "quadratic"
+	case e.Roundness != nil:
+		return "catmull-rom"
+	}
+	return "none"
+}
+
+// Items have one text size, so larger text is a scaled up item
+func scaleToText(item *models.ItemCreate, text *Element) {
+	if text.FontSize > 0 {
+		item.Scale = text.FontSize / itemFontSize
+		item.Width, item.Height = item.Width/item.Scale, item.Height/item.Scale
+	}
+}
+
+// The text as typed, before it was wrapped to fit
+func textContent(e *Element) string {
+	if e.OriginalText != "" {
+		return e.OriginalText
+	}
+	return e.Text
+}
+
+// Read a todo list written out as text, one field per line
+func todoFields(content string) ([]models.TodoItemFieldCreate, bool) {
+	var fields []models.TodoItemFieldCreate
+	for _, line := range strings.Split(content, "\n") {
+		switch {
+		case strings.HasPrefix(line, checkedBox):
+			fields = append(fields, models.TodoItemFieldCreate{
+				TextItem: models.TextItemCreate{Content: strings.TrimPrefix(line, checkedBox)},
+				Done:     true,
+			})
+		case strings.HasPrefix(line, uncheckedBox):
+			fields = append(fields, models.TodoItemFieldCreate{
+				TextItem: models.TextItemCreate{Content: strings.TrimPrefix(line, uncheckedBox)},
+			})
+		default:
+			return nil, false
+		}
+	}
+	return fields, true
+}
+
+// Opacity from 0 to 100. Excalidraw writes it on every element, but scenes
+// made by other tools may leave it out.
+func (e *Element) opacity() float64 {
+	if e.Opacity == nil {
+		return 100
+	}
+	return *e.Opacity
+}
+
+// Convert a CSS color of a scene, faded by the opacity of its element, to
+// the ARGB integer the app stores
+func appColor(css string, opacity float64) string {
+	if css == "" || css == "transparent" {
+		return "0"
+	}
+	c := export.ParseColor(css)
+	alpha := uint64(math.Round(float64(c.A) * min(max(opacity, 0), 100) / 100))
+	return strconv.FormatUint(alpha<<24|uint64(c.R)<<16|uint64(c.G)<<8|uint64(c.B), 10)
+}
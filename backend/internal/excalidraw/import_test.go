@@ -0,0 +1,124 @@
+package excalidraw
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sceneJSON = `{
+	"type": "excalidraw",
+	"version": 2,
+	"source": "https://excalidraw.com",
+	"elements": [
+		{"id": "box", "type": "rectangle", "x": 10, "y": 20, "width": 100, "height": 50,
+			"backgroundColor": "#ffc9c9", "boundElements": [{"id": "label", "type": "text"}]},
+		{"id": "label", "type": "text", "x": 15, "y": 25, "width": 90, "height": 40, "opacity": 100,
+			"text": "Plan the", "originalText": "Plan the launch", "fontSize": 20, "containerId": "box"},
+		{"id": "todo", "type": "rectangle", "x": 0, "y": 100, "width": 100, "height": 50,
+			"backgroundColor": "transparent", "opacity": 100},
+		{"id": "todo-text", "type": "text", "x": 0, "y": 100, "width": 100, "height": 50, "opacity": 100,
+			"text": "☑ Ship\n☐ Rest", "fontSize": 16, "containerId": "todo"},
+		{"id": "dot", "type": "ellipse", "x": -5, "y": -5, "width": 10, "height": 10,
+			"backgroundColor": "#1971c2", "opacity": 50, "angle": 0.5},
+		{"id": "title", "type": "text", "x": 200, "y": 0, "width": 64, "height": 40, "opacity": 100,
+			"text": "Title", "fontSize": 32},
+		{"id": "scribble", "type": "freedraw", "x": 300, "y": 300, "width": 20, "height": 10, "opacity": 40,
+			"strokeColor": "#e03131", "strokeWidth": 4, "points": [[0, 0], [-10, 5], [10, -5]], "pressures": [0, 0.5, 1]},
+		{"id": "photo", "type": "image", "x": 0, "y": 0, "width": 30, "height": 20, "opacity": 100,
+			"fileId": "f1"},
+		{"id": "lost", "type": "image", "x": 0, "y": 0, "width": 30, "height": 20, "opacity": 100,
+			"fileId": "f2"},
+		{"id": "pointer", "type": "arrow", "x": 0, "y": 0, "width": 30, "height": 20, "opacity": 100,
+			"points": [[0, 0], [30, 20]]},
+		{"id": "ghost", "type": "freedraw", "x": 0, "y": 0, "width": 10, "height": 10, "opacity": 0,
+			"strokeColor": "#e03131", "points": [[0, 0], [10, 10]]},
+		{"id": "hidden", "type": "ellipse", "x": 0, "y": 0, "width": 10, "height": 10, "opacity": 0,
+			"backgroundColor": "#1971c2"},
+		{"id": "gone", "type": "rectangle", "x": 0, "y": 0, "width": 30, "height": 20, "isDeleted": true}
+	],
+	"appState": {"viewBackgroundColor": "#ffffff"},
+	"files": {"f1": {"id": "f1", "mimeType": "image/png", "dataURL": "data:image/png;base64,iVBORw0KGgo="}}
+}`
+
+func TestToItems(t *testing.T) {
+	var scene Scene
+	assert.NoError(t, json.Unmarshal([]byte(sceneJSON), &scene))
+	assert.NoError(t, scene.Validate())
+
+	items, unsupported := ToItems(scene)
+	if !assert.Equal(t, 6, len(items)) {
+		return
+	}
+	for i, item := range items {
+		assert.Equal(t, uint(i), item.ZIndex, "Items should keep the order of the scene")
+	}
+
+	note := items[0]
+	assert.Equal(t, "box", note.ElementID)
+	assert.Equal(t, "Plan the launch", note.TextItem.Content, "Text should be imported as typed, not as wrapped")
+	assert.Equal(t, "4294953417", note.Color, "Elements without an opacity should be opaque")
+	assert.Equal(t, 1.25, note.Scale)
+	assert.Equal(t, []float64{10, 20, 80, 40}, []float64{note.PositionX, note.PositionY, note.Width, note.Height})
+
+	todo := items[1]
+	if assert.NotNil(t, todo.TodoList) && assert.Equal(t, 2, len(*todo.TodoList)) {
+		assert.Equal(t, "Ship", (*todo.TodoList)[0].TextItem.Content)
+		assert.True(t, (*todo.TodoList)[0].Done)
+		assert.False(t, (*todo.TodoList)[1].Done)
+	}
+	assert.Equal(t, "0", todo.Color)
+
+	dot := items[2]
+	assert.Equal(t, "circle", dot.ShapeItem.Name)
+	assert.Equal(t, "2149151170", dot.Color, "Opacity should fade the color")
+
+	title := items[3]
+	assert.Equal(t, "Title", title.TextItem.Content)
+	assert.Equal(t, 2.0, title.Scale)
+	assert.Equal(t, []float64{32, 20}, []float64{title.Width, title.Height})
+
+	scribble := items[4]
+	assert.Equal(t, []float64{290, 295, 20, 10}, []float64{scribble.PositionX, scribble.PositionY, scribble.Width, scribble.Height})
+	if assert.Equal(t, 3, len(scribble.DrawingItem.Points)) {
+		assert.Equal(t, 10.0, scribble.DrawingItem.Points[0].X)
+		assert.Equal(t, 5.0, scribble.DrawingItem.Points[0].Y)
+	}
+	assert.Equal(t, "4292882737", scribble.Color, "Opacity should go to the stroke")
+	assert.Equal(t, []float64{4, 0.4}, []float64{scribble.DrawingItem.StrokeWidth, scribble.DrawingItem.Opacity})
+	assert.Equal(t, "quadratic", scribble.DrawingItem.Smoothing)
+	pressures := make([]float64, 0, len(scribble.DrawingItem.Points))
+	for _, p := range scribble.DrawingItem.Points {
+		pressures = append(pressures, p.Pressure)
+	}
+	assert.Equal(t, []float64{0.001, 0.5, 1}, pressures, "Every point should keep a pressure")
+
+	assert.Equal(t, "f1", items[5].ImageItem.AssetID)
+
+	reasons := make(map[string]Unsupported)
+	for _, u := range unsupported {
+		reasons[u.ElementID] = u
+	}
+	assert.Equal(t, 5, len(unsupported))
+	assert.Equal(t, "arrow elements are not supported", reasons["pointer"].Reason)
+	for _, id := range []string{"ghost", "hidden"} {
+		assert.True(t, reasons[id].Skipped, "Invisible elements should not come out opaque")
+		assert.Equal(t, "element is fully transparent", reasons[id].Reason)
+	}
+	assert.True(t, reasons["pointer"].Skipped)
+	assert.True(t, reasons["lost"].Skipped)
+	assert.False(t, reasons["dot"].Skipped, "Rotated elements should be imported upright")
+}
+
+func TestSceneValidate(t *testing.T) {
+	var scene Scene
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "prodspace-workspace", "elements": []}`), &scene))
+	assert.Error(t, scene.Validate())
+
+	_, err := File{DataURL: "https://example.com/cat.png"}.Data()
+	assert.Error(t, err)
+	data, err := newFile("f", "image/png", []byte("png"), 0).Data()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("png"), data)
+}
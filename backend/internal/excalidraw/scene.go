@@ -0,0 +1,114 @@
+// Package excalidraw converts boards to and from the .excalidraw file format
+// of the Excalidraw whiteboard: a JSON scene of elements, with the images
+// they show embedded as data URLs.
+package excalidraw
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+const sceneType = "excalidraw"
+
+type Scene struct {
+	Type     string          `json:"type"`
+	Version  int             `json:"version"`
+	Source   string          `json:"source"`
+	Elements []Element       `json:"elements"`
+	AppState map[string]any  `json:"appState"`
+	Files    map[string]File `json:"files"`
+}
+
+// An element of a scene. Only the fields of its type are used; coordinates
+// are those of the board.
+type Element struct {
+	ID              string         `json:"id"`
+	Type            string         `json:"type"`
+	X               float64        `json:"x"`
+	Y               float64        `json:"y"`
+	Width           float64        `json:"width"`
+	Height          float64        `json:"height"`
+	Angle           float64        `json:"angle"` // radians, clockwise
+	StrokeColor     string         `json:"strokeColor"`
+	BackgroundColor string         `json:"backgroundColor"`
+	FillStyle       string         `json:"fillStyle"`
+	StrokeWidth     float64        `json:"strokeWidth"`
+	StrokeStyle     string         `json:"strokeStyle"`
+	Roughness       float64        `json:"roughness"`
+	Opacity         *float64       `json:"opacity"` // 0 to 100, opaque if missing
+	GroupIDs        []string       `json:"groupIds"`
+	FrameID         *string        `json:"frameId"`
+	Roundness       *Roundness     `json:"roundness"`
+	Seed            int64          `json:"seed"`
+	Version         int64          `json:"version"`
+	VersionNonce    int64          `json:"versionNonce"`
+	IsDeleted       bool           `json:"isDeleted"`
+	BoundElements   []BoundElement `json:"boundElements"`
+	Updated         int64          `json:"updated"` // Unix milliseconds
+	Link            *string        `json:"link"`
+	Locked          bool           `json:"locked"`
+
+	// Text, possibly laid out in a container
+	Text          string  `json:"text,omitempty"`
+	OriginalText  string  `json:"originalText,omitempty"`
+	FontSize      float64 `json:"fontSize,omitempty"`
+	FontFamily    int     `json:"fontFamily,omitempty"`
+	TextAlign     string  `json:"textAlign,omitempty"`
+	VerticalAlign string  `json:"verticalAlign,omitempty"`
+	ContainerID   *string `json:"containerId,omitempty"`
+	LineHeight    float64 `json:"lineHeight,omitempty"` // times the font size
+
+	// Free drawings and lines, relative to X and Y
+	Points           [][2]float64 `json:"points,omitempty"`
+	Pressures        []float64    `json:"pressures,omitempty"`
+	SimulatePressure bool         `json:"simulatePressure,omitempty"`
+
+	// Images
+	FileID *string     `json:"fileId,omitempty"`
+	Status string      `json:"status,omitempty"`
+	Scale  *[2]float64 `json:"scale,omitempty"` // negative to flip
+}
+
+type Roundness struct {
+	Type int `json:"type"`
+}
+
+type BoundElement struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// An image of the scene, shown by image elements through its id
+type File struct {
+	ID       string `json:"id"`
+	MimeType string `json:"mimeType"`
+	DataURL  string `json:"dataURL"`
+	Created  int64  `json:"created"` // Unix milliseconds
+}
+
+// Decode the content of a file from its base64 data URL
+func (f File) Data() ([]byte, error) {
+	header, data, ok := strings.Cut(f.DataURL, ",")
+	if !ok || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+		return nil, errors.New("not a base64 data URL")
+	}
+	return base64.StdEncoding.DecodeString(data)
+}
+
+func newFile(id, contentType string, data []byte, created int64) File {
+	return File{
+		ID:       id,
+		MimeType: contentType,
+		DataURL:  "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data),
+		Created:  created,
+	}
+}
+
+// Check that a scene is an Excalidraw scene
+func (s *Scene) Validate() error {
+	if s.Type != sceneType {
+		return errors.New("not an Excalidraw scene")
+	}
+	return nil
+}
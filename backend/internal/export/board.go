@@ -0,0 +1,85 @@
+// Package export renders workspaces into formats that other tools can open
+package export
+
+import (
+	"backend/internal/database/schemas"
+	"backend/internal/storage"
+	"context"
+	"io"
+	"math"
+	"sort"
+)
+
+// Space left around the items of an exported board
+const boardMargin = 16
+
+// Items laid out for export, in drawing order, with the area they cover
+type Board struct {
+	Items                  []schemas.Item
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// Order items by z-index, oldest first among equals, and frame them
+func NewBoard(items []schemas.Item) Board {
+	board := Board{Items: append([]schemas.Item(nil), items...)}
+	sort.SliceStable(board.Items, func(i, j int) bool {
+		a, b := board.Items[i], board.Items[j]
+		if a.ZIndex != b.ZIndex {
+			return a.ZIndex < b.ZIndex
+		}
+		return a.ID < b.ID
+	})
+
+	if len(items) == 0 {
+		board.MaxX, board.MaxY = 2*boardMargin, 2*boardMargin
+		return board
+	}
+	board.MinX, board.MinY = math.Inf(1), math.Inf(1)
+	board.MaxX, board.MaxY = math.Inf(-1), math.Inf(-1)
+	for i := range board.Items {
+		minX, minY, maxX, maxY := board.Items[i].Bounds()
+		board.MinX, board.MinY = min(board.MinX, minX), min(board.MinY, minY)
+		board.MaxX, board.MaxY = max(board.MaxX, maxX), max(board.MaxY, maxY)
+	}
+	board.MinX -= boardMargin
+	board.MinY -= boardMargin
+	board.MaxX += boardMargin
+	board.MaxY += boardMargin
+	return board
+}
+
+// Frame the board to a region instead of its items
+func (b Board) Crop(minX, minY, maxX, maxY float64) Board {
+	b.MinX, b.MinY, b.MaxX, b.MaxY = minX, minY, maxX, maxY
+	return b
+}
+
+func (b Board) Width() float64 {
+	return b.MaxX - b.MinX
+}
+
+func (b Board) Height() float64 {
+	return b.MaxY - b.MinY
+}
+
+// Loads the content of an image asset and its content type
+type ImageLoader func(ctx context.Context, asset *schemas.Asset) ([]byte, string, error)
+
+// Load an image from the object store, preferring its medium thumbnail to
+// keep exports small. The asset must be loaded with its variants.
+func StoredImage(ctx context.Context, asset *schemas.Asset) ([]byte, string, error) {
+	stored := asset
+	for i := range asset.Variants {
+		if asset.Variants[i].Variant == "medium" {
+			stored = &asset.Variants[i]
+		}
+	}
+
+	r, err := storage.Default.Get(ctx, stored.BlobHash)
+	if err != nil {
+		return nil, "", err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	return data, stored.ContentType, err
+}
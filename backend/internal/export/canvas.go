@@ -0,0 +1,186 @@
+package export
+
+import (
+	"backend/internal/database/schemas"
+	"context"
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Styling of the parts of items that have no color of their own
+const (
+	inkColor    = "#1F1F1F"
+	borderColor = "#D0D0D0"
+	fontSize    = 16
+	lineHeight  = 20
+	textPadding = 8
+	strokeWidth = 2
+	checkboxGap = 4
+)
+
+// How a shape is painted; empty colors are not painted
+type style struct {
+	fill, stroke string
+	strokeWidth  float64
+	dashed       bool
+}
+
+type point struct {
+	x, y float64
+}
+
+// A surface items are drawn on, one format each. Between begin and end,
+// coordinates are those of an item, moved and scaled into place on the board.
+type canvas interface {
+	begin(x, y, scale float64)
+	end()
+	rect(x, y, width, height float64, s style)
+	ellipse(cx, cy, rx, ry float64, s style)
+	// An open path with round caps and joins
+	polyline(points []point, stroke string, width float64)
+	// A line of text whose top left corner is at x, y
+	text(x, y float64, text string, struck bool)
+	// Fails if the image cannot be decoded
+	image(x, y, width, height float64, data []byte, contentType string) error
+}
+
+// Draw every item of the board, bottom first
+func drawBoard(ctx context.Context, c canvas, board Board, images ImageLoader) {
+	for _, item := range board.Items {
+		c.begin(item.PositionX, item.PositionY, item.Scale)
+		drawItem(ctx, c, item, images)
+		c.end()
+	}
+}
+
+// Draw an item in its own coordinates, from (0,0) to (width,height). Images
+// that cannot be loaded are drawn as placeholders.
+func drawItem(ctx context.Context, c canvas, item schemas.Item, images ImageLoader) {
+	x, y, width, height := localBox(item)
+
+	switch {
+	case item.ShapeItem != nil:
+		s := style{fill: item.Color, stroke: inkColor, strokeWidth: strokeWidth}
+		if IsRound(item.ShapeItem.Name) {
+			c.ellipse(x+width/2, y+height/2, width/2, height/2, s)
+		} else {
+			c.rect(x, y, width, height, s)
+		}
+
+	case item.TextItem != nil:
+		c.rect(x, y, width, height, style{fill: item.Color, stroke: borderColor, strokeWidth: 1})
+		for i, line := range strings.Split(item.TextItem.Content, "\n") {
+			c.text(x+textPadding, y+textPadding+float64(i)*lineHeight, line, false)
+		}
+
+	case item.ListItem != nil:
+		c.rect(x, y, width, height, style{fill: item.Color, stroke: borderColor, strokeWidth: 1})
+		box := float64(fontSize - checkboxGap)
+		for i, field := range item.ListItem.TodoListFields {
+			left := x + textPadding
+			top := y + textPadding + float64(i)*lineHeight + checkboxGap
+			c.rect(left, top, box, box, style{stroke: inkColor, strokeWidth: 1})
+			if field.Done {
+				c.polyline([]point{
+					{left + box*0.2, top + box*0.5},
+					{left + box*0.4, top + box*0.8},
+					{left + box*0.8, top + box*0.2},
+				}, inkColor, strokeWidth)
+			}
+			c.text(left+box+checkboxGap*2, top-checkboxGap, field.Content, field.Done)
+		}
+
+	case item.DrawingItem != nil:
+		points := make([]point, 0, len(item.DrawingItem.Points))
+		for _, p := range item.DrawingItem.Points {
+			points = append(points, point{p.X, p.Y})
+		}
+		width, stroke := drawingStroke(item)
+		if len(points) == 1 {
+			c.ellipse(points[0].x, points[0].y, width/2.0, width/2.0, style{fill: stroke})
+		} else if len(points) > 1 {
+			c.polyline(points, stroke, width)
+		}
+
+	case item.ImageItem != nil:
+		err := fmt.Errorf("image %s is not stored", item.ImageItem.AssetID)
+		if item.ImageItem.Asset != nil {
+			var data []byte
+			var contentType string
+			if data, contentType, err = images(ctx, item.ImageItem.Asset); err == nil {
+				err = c.image(x, y, width, height, data, contentType)
+			}
+		}
+		if err != nil {
+			c.rect(x, y, width, height, style{stroke: borderColor, strokeWidth: 1, dashed: true})
+		}
+	}
+}
+
+// The width and color a drawing is stroked with, its opacity fading the item
+// color. A stroke without a width is as thick as the outlines of shapes.
+// Caps, joins, pressure and smoothing are left to the app.
+func drawingStroke(item schemas.Item) (float64, string) {
+	width, stroke := item.DrawingItem.StrokeWidth, item.Color
+	if width <= 0 {
+		width = strokeWidth
+	}
+	if opacity := item.DrawingItem.Opacity; opacity > 0 && opacity < 1 {
+		c := ParseColor(stroke)
+		c.A = uint8(math.Round(float64(c.A) * opacity))
+		stroke = fmt.Sprintf("#%02X%02X%02X%02X", c.R, c.G, c.B, c.A)
+	}
+	return width, stroke
+}
+
+// The rectangle an item covers in its own coordinates. Negative sizes extend
+// to the left or top.
+func localBox(item schemas.Item) (x, y, width, height float64) {
+	x, y, width, height = 0, 0, item.Width, item.Height
+	if width < 0 {
+		x, width = width, -width
+	}
+	if height < 0 {
+		y, height = height, -height
+	}
+	return x, y, width, height
+}
+
+// Report whether a shape is drawn as an ellipse rather than a rectangle
+func IsRound(name string) bool {
+	switch strings.ToLower(name) {
+	case "circle", "ellipse", "oval":
+		return true
+	}
+	return false
+}
+
+// Parse an item color: #RGB, #RRGGBB or #RRGGBBAA, or the ARGB integer in
+// decimal that the app stores. Colors that cannot be parsed are painted in
+// ink.
+func ParseColor(s string) color.NRGBA {
+	if argb, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return color.NRGBA{R: uint8(argb >> 16), G: uint8(argb >> 8), B: uint8(argb), A: uint8(argb >> 24)}
+	}
+
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "FF"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || !strings.HasPrefix(s, "#") || err != nil {
+		return ParseColor(inkColor)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}
+}
+
+// Format a color as #RRGGBB, with its opacity apart
+func hexColor(c color.NRGBA) (string, float64) {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B), float64(c.A) / 255
+}
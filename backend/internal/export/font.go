@@ -0,0 +1,49 @@
+package export
+
+import (
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Text of raster and print exports is set in Go Regular, which ships with
+// the binary
+var textFont = sync.OnceValue(func() *sfnt.Font {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		panic(err) // embedded font
+	}
+	return f
+})
+
+// A face of the text font, size in pixels
+func textFace(size float64) font.Face {
+	face, err := opentype.NewFace(textFont(), &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingNone,
+	})
+	if err != nil {
+		panic(err) // only fails on invalid options
+	}
+	return face
+}
+
+// Width of a character of the text font, in thousandths of the font size
+func glyphWidth(r rune) int {
+	f := textFont()
+	var buf sfnt.Buffer
+	index, err := f.GlyphIndex(&buf, r)
+	if err != nil || index == 0 {
+		return 0
+	}
+	advance, err := f.GlyphAdvance(&buf, index, fixed.I(1000), font.HintingNone)
+	if err != nil {
+		return 0
+	}
+	return advance.Round()
+}
@@ -0,0 +1,310 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Largest page side viewers accept, in points
+const pdfMaxPage = 14400
+
+// Object numbers of the fixed parts of the document; images follow
+const (
+	pdfCatalog = iota + 1
+	pdfPages
+	pdfPage
+	pdfContent
+	pdfFont
+	pdfFontDescriptor
+	pdfFontFile
+)
+
+// Write the board as a single page PDF document, one point per board unit
+// unless the page would be too large to open. Text is set in an embedded
+// font and limited to Latin-1.
+func WritePDF(ctx context.Context, w io.Writer, board Board, images ImageLoader) error {
+	k := min(1, pdfMaxPage/max(board.Width(), board.Height()))
+	width, height := board.Width()*k, board.Height()*k
+
+	doc := &pdfDocument{objects: make([][]byte, pdfFontFile)}
+	c := &pdfCanvas{doc: doc, states: make(map[string]int)}
+	// Flip the page so that y grows downwards as on the board
+	fmt.Fprintf(&c.content, "%s 0 0 %s 0 %s cm 1 0 0 1 %s %s cm\n",
+		num(k), num(-k), num(height), num(-board.MinX), num(-board.MinY))
+	drawBoard(ctx, c, board, images)
+
+	xobjects := ""
+	for i, object := range c.images {
+		xobjects += fmt.Sprintf(" /Im%d %d 0 R", i+1, object)
+	}
+	names := make([]string, 0, len(c.states))
+	for name := range c.states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	states := ""
+	for _, name := range names {
+		states += fmt.Sprintf(" /%s %d 0 R", name, c.states[name])
+	}
+	doc.set(pdfCatalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPages))
+	doc.set(pdfPages, fmt.Sprintf("<< /Type /Pages /Kids [%d 0 R] /Count 1 >>", pdfPage))
+	doc.set(pdfPage, fmt.Sprintf(
+		"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 %d 0 R >> /XObject <<%s >> /ExtGState <<%s >> >> /Contents %d 0 R >>",
+		pdfPages, num(width), num(height), pdfFont, xobjects, states, pdfContent))
+	doc.setStream(pdfContent, "", c.content.Bytes())
+	doc.setFont()
+
+	return doc.write(w)
+}
+
+// Objects of a document, numbered from 1
+type pdfDocument struct {
+	objects [][]byte
+}
+
+func (d *pdfDocument) set(number int, body string) {
+	d.objects[number-1] = []byte(body)
+}
+
+func (d *pdfDocument) add(body string) int {
+	d.objects = append(d.objects, []byte(body))
+	return len(d.objects)
+}
+
+// Compress a stream; dict holds its entries besides the length and filter
+func (d *pdfDocument) setStream(number int, dict string, data []byte) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(data)
+	zw.Close()
+	d.set(number, fmt.Sprintf("<<%s /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream",
+		dict, compressed.Len(), compressed.Bytes()))
+}
+
+func (d *pdfDocument) addStream(dict string, data []byte) int {
+	number := d.add("")
+	d.setStream(number, dict, data)
+	return number
+}
+
+// Embed the text font with the widths of its WinAnsi characters
+func (d *pdfDocument) setFont() {
+	f := textFont()
+	var buf sfnt.Buffer
+	em := fixed.I(1000)
+	metrics, _ := f.Metrics(&buf, em, font.HintingNone)
+	bounds, _ := f.Bounds(&buf, em, font.HintingNone)
+
+	widths := make([]string, 0, 224)
+	for code := 32; code <= 255; code++ {
+		widths = append(widths, fmt.Sprint(glyphWidth(rune(code))))
+	}
+	d.set(pdfFont, fmt.Sprintf(
+		"<< /Type /Font /Subtype /TrueType /BaseFont /GoRegular /FirstChar 32 /LastChar 255 /Widths [%s] /Encoding /WinAnsiEncoding /FontDescriptor %d 0 R >>",
+		strings.Join(widths, " "), pdfFontDescriptor))
+	d.set(pdfFontDescriptor, fmt.Sprintf(
+		"<< /Type /FontDescriptor /FontName /GoRegular /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		bounds.Min.X.Round(), -bounds.Max.Y.Round(), bounds.Max.X.Round(), -bounds.Min.Y.Round(),
+		metrics.Ascent.Round(), -metrics.Descent.Round(), metrics.CapHeight.Round(), pdfFontFile))
+	d.setStream(pdfFontFile, fmt.Sprintf(" /Length1 %d", len(goregular.TTF)), goregular.TTF)
+}
+
+// Write the objects with their cross-reference table
+func (d *pdfDocument) write(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	offset := 0
+	count := func(n int, _ error) { offset += n }
+
+	count(out.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n"))
+	offsets := make([]int, len(d.objects))
+	for i, body := range d.objects {
+		offsets[i] = offset
+		count(fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", i+1, body))
+	}
+
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(d.objects)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.objects)+1, pdfCatalog, offset)
+	return out.Flush()
+}
+
+// Items are drawn into the page's content stream, each in a saved graphics
+// state moved and scaled into place. Translucent colors get their alpha from
+// graphics states, one per value, named after it.
+type pdfCanvas struct {
+	doc     *pdfDocument
+	content bytes.Buffer
+	images  []int
+	states  map[string]int
+}
+
+func (c *pdfCanvas) begin(x, y, scale float64) {
+	fmt.Fprintf(&c.content, "q %s 0 0 %s %s %s cm\n", num(scale), num(scale), num(x), num(y))
+}
+
+func (c *pdfCanvas) end() {
+	c.content.WriteString("Q\n")
+}
+
+func (c *pdfCanvas) rect(x, y, width, height float64, s style) {
+	c.paint(fmt.Sprintf("%s %s %s %s re", num(x), num(y), num(width), num(height)), s)
+}
+
+func (c *pdfCanvas) ellipse(cx, cy, rx, ry float64, s style) {
+	// Four Bézier quarters, off by at most 0.03% of the radius
+	const k = 0.5522847498
+	ox, oy := rx*k, ry*k
+	path := fmt.Sprintf("%s %s m %s %s %s %s %s %s c %s %s %s %s %s %s c %s %s %s %s %s %s c %s %s %s %s %s %s c h",
+		num(cx+rx), num(cy),
+		num(cx+rx), num(cy+oy), num(cx+ox), num(cy+ry), num(cx), num(cy+ry),
+		num(cx-ox), num(cy+ry), num(cx-rx), num(cy+oy), num(cx-rx), num(cy),
+		num(cx-rx), num(cy-oy), num(cx-ox), num(cy-ry), num(cx), num(cy-ry),
+		num(cx+ox), num(cy-ry), num(cx+rx), num(cy-oy), num(cx+rx), num(cy))
+	c.paint(path, s)
+}
+
+func (c *pdfCanvas) polyline(points []point, stroke string, width float64) {
+	if ParseColor(stroke).A == 0 {
+		return
+	}
+	var path strings.Builder
+	for i, p := range points {
+		op := "l"
+		if i == 0 {
+			op = "m"
+		}
+		fmt.Fprintf(&path, "%s %s %s ", num(p.x), num(p.y), op)
+	}
+	fmt.Fprintf(&c.content, "q 1 J 1 j %s%s%s S Q\n", c.alpha(stroke, "CA"), pdfColor(stroke, "RG", width), path.String())
+}
+
+func (c *pdfCanvas) text(x, y float64, text string, struck bool) {
+	var encoded strings.Builder
+	width := 0
+	for _, r := range text {
+		if r < 32 || (r > 126 && r < 160) || r > 255 {
+			r = '?'
+		}
+		width += glyphWidth(r)
+		if r == '(' || r == ')' || r == '\\' {
+			encoded.WriteByte('\\')
+		}
+		encoded.WriteByte(byte(r))
+	}
+	ink := ParseColor(inkColor)
+	baseline := y + fontSize
+	fmt.Fprintf(&c.content, "q %s BT /F1 %d Tf 1 0 0 -1 %s %s Tm (%s) Tj ET Q\n",
+		pdfRGB(ink, "rg"), fontSize, num(x), num(baseline), encoded.String())
+	if struck {
+		c.rect(x, baseline-fontSize*0.3-0.5, float64(width)*fontSize/1000, 1, style{fill: inkColor})
+	}
+}
+
+func (c *pdfCanvas) image(x, y, width, height float64, data []byte, contentType string) error {
+	src, err := decodeImage(data)
+	if err != nil {
+		return err
+	}
+	bounds := src.Bounds()
+	rgb := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	alpha := make([]byte, 0, bounds.Dx()*bounds.Dy())
+	opaque := true
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			p := color.NRGBAModel.Convert(src.At(px, py)).(color.NRGBA)
+			rgb = append(rgb, p.R, p.G, p.B)
+			alpha = append(alpha, p.A)
+			opaque = opaque && p.A == 0xFF
+		}
+	}
+
+	size := fmt.Sprintf(" /Type /XObject /Subtype /Image /Width %d /Height %d /BitsPerComponent 8", bounds.Dx(), bounds.Dy())
+	mask := ""
+	if !opaque {
+		mask = fmt.Sprintf(" /SMask %d 0 R", c.doc.addStream(size+" /ColorSpace /DeviceGray", alpha))
+	}
+	c.images = append(c.images, c.doc.addStream(size+" /ColorSpace /DeviceRGB"+mask, rgb))
+	// Images fill the unit square top row first, against the flipped page
+	fmt.Fprintf(&c.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n",
+		num(width), num(-height), num(x), num(y+height), len(c.images))
+	return nil
+}
+
+// Fill and stroke a path in a style
+func (c *pdfCanvas) paint(path string, s style) {
+	// What is invisible is not painted at all
+	if s.fill != "" && ParseColor(s.fill).A == 0 {
+		s.fill = ""
+	}
+	if s.stroke != "" && ParseColor(s.stroke).A == 0 {
+		s.stroke = ""
+	}
+	op := ""
+	state := ""
+	switch {
+	case s.fill != "" && s.stroke != "":
+		op = "B"
+	case s.fill != "":
+		op = "f"
+	case s.stroke != "":
+		op = "S"
+	default:
+		return
+	}
+	if s.fill != "" {
+		state += c.alpha(s.fill, "ca") + pdfColor(s.fill, "rg", 0)
+	}
+	if s.stroke != "" {
+		state += c.alpha(s.stroke, "CA") + pdfColor(s.stroke, "RG", s.strokeWidth)
+		if s.dashed {
+			state += "[4] 0 d "
+		}
+	}
+	fmt.Fprintf(&c.content, "q %s%s %s Q\n", state, path, op)
+}
+
+// Operator setting the alpha of fills (ca) or strokes (CA) to that of a
+// color, if it has one
+func (c *pdfCanvas) alpha(s, key string) string {
+	a := ParseColor(s).A
+	if a == 0xFF {
+		return ""
+	}
+	name := fmt.Sprintf("%s%d", key, a)
+	if _, ok := c.states[name]; !ok {
+		c.states[name] = c.doc.add(fmt.Sprintf("<< /Type /ExtGState /%s %s >>", key, num(math.Round(float64(a)/255*1000)/1000)))
+	}
+	return "/" + name + " gs "
+}
+
+// Operators setting a fill (rg) or stroke (RG) color, and the line width
+// of strokes
+func pdfColor(s, op string, width float64) string {
+	state := pdfRGB(ParseColor(s), op)
+	if op == "RG" {
+		state += num(width) + " w "
+	}
+	return state
+}
+
+func pdfRGB(c color.NRGBA, op string) string {
+	component := func(v uint8) string {
+		return num(math.Round(float64(v)/255*1000) / 1000)
+	}
+	return fmt.Sprintf("%s %s %s %s ", component(c.R), component(c.G), component(c.B), op)
+}
@@ -0,0 +1,86 @@
+package export
+
+import (
+	"backend/internal/database/schemas"
+	"bytes"
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWritePDF(t *testing.T) {
+	items := []schemas.Item{
+		{ID: 1, PositionX: 0, PositionY: 0, Width: 40, Height: 40, Scale: 1, Color: "#FF0000",
+			ShapeItem: &schemas.ShapeItem{Name: "circle"}},
+		{ID: 2, PositionX: 100, PositionY: 0, Width: 100, Height: 40, Scale: 1, Color: "#FFFFFF",
+			TextItem: &schemas.TextItem{Content: "Fish (and) chips"}},
+		{ID: 3, PositionX: 0, PositionY: 100, Width: 40, Height: 40, Scale: 1,
+			ImageItem: &schemas.ImageItem{AssetID: "broken", Asset: &schemas.Asset{ID: "broken"}}},
+	}
+	images := func(ctx context.Context, asset *schemas.Asset) ([]byte, string, error) {
+		return []byte("not an image"), "image/png", nil
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, WritePDF(context.Background(), &out, NewBoard(items), images))
+	pdf := out.Bytes()
+
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+	assert.Contains(t, string(pdf), "/MediaBox [0 0 232 172]")
+	assert.Contains(t, string(pdf), "/Count 1")
+	assert.Contains(t, string(pdf), "/FontFile2 7 0 R")
+	assert.NotContains(t, string(pdf), "/Subtype /Image", "Undecodable images should be left out")
+
+	// Every object should start where the cross-reference table says
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if assert.NotNil(t, startxref) {
+		offset, _ := strconv.Atoi(string(startxref[1]))
+		assert.True(t, bytes.HasPrefix(pdf[offset:], []byte("xref\n0 8\n")))
+	}
+	for i, entry := range regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf, -1) {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		assert.True(t, bytes.HasPrefix(pdf[offset:], []byte(strconv.Itoa(i+1)+" 0 obj\n")), "object %d", i+1)
+	}
+}
+
+func TestWritePDFImage(t *testing.T) {
+	items := []schemas.Item{
+		{ID: 1, PositionX: 0, PositionY: 0, Width: 40, Height: 40, Scale: 1,
+			ImageItem: &schemas.ImageItem{AssetID: "stored", Asset: &schemas.Asset{ID: "stored"}}},
+	}
+	images := func(ctx context.Context, asset *schemas.Asset) ([]byte, string, error) {
+		// A 1x1 transparent GIF
+		return []byte("GIF89a\x01\x00\x01\x00\x80\x00\x00\x00\x00\x00\xff\xff\xff!\xf9\x04\x01\x00\x00\x00\x00,\x00\x00\x00\x00\x01\x00\x01\x00\x00\x02\x02D\x01\x00;"), "image/gif", nil
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, WritePDF(context.Background(), &out, NewBoard(items), images))
+	assert.Contains(t, out.String(), "/XObject << /Im1 9 0 R >>")
+	assert.Contains(t, out.String(), "/SMask 8 0 R", "Transparent images should be masked")
+	assert.Contains(t, out.String(), "/Width 1 /Height 1")
+}
+
+func TestWritePDFOpacity(t *testing.T) {
+	items := []schemas.Item{
+		{ID: 1, PositionX: 0, PositionY: 0, Width: 10, Height: 10, Scale: 1, Color: "#FFFF00",
+			DrawingItem: &schemas.DrawingItem{Points: schemas.Points{{X: 0, Y: 0}, {X: 10, Y: 10}}, StrokeWidth: 20, Opacity: 0.4}},
+		{ID: 2, PositionX: 20, PositionY: 0, Width: 10, Height: 10, Scale: 1, Color: "#0000FF66",
+			ShapeItem: &schemas.ShapeItem{Name: "rectangle"}},
+		{ID: 3, PositionX: 40, PositionY: 0, Width: 10, Height: 10, Scale: 1, Color: "#FF0000",
+			ShapeItem: &schemas.ShapeItem{Name: "rectangle"}},
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, WritePDF(context.Background(), &out, NewBoard(items), nil))
+	pdf := out.String()
+
+	assert.Contains(t, pdf, "<< /Type /ExtGState /CA 0.4 >>", "Highlighter strokes should stay translucent")
+	assert.Contains(t, pdf, "<< /Type /ExtGState /ca 0.4 >>", "Translucent fills should stay so")
+	assert.Regexp(t, `/ExtGState << /CA102 \d+ 0 R /ca102 \d+ 0 R >>`, pdf)
+	assert.Equal(t, 2, strings.Count(pdf, "/Type /ExtGState"), "Opaque colors should need no graphics state")
+}
@@ -0,0 +1,300 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+	_ "golang.org/x/image/webp"
+)
+
+// Largest raster export, and largest image decoded for one, in pixels
+const maxPixels = 1 << 24
+
+// Returned when the image would exceed maxPixels
+var ErrTooLarge = errors.New("export is too large")
+
+// Write the board as a PNG image, scale pixels per board unit
+func WritePNG(ctx context.Context, w io.Writer, board Board, images ImageLoader, scale float64) error {
+	width := int(math.Ceil(board.Width() * scale))
+	height := int(math.Ceil(board.Height() * scale))
+	if width < 1 || height < 1 {
+		return errors.New("export is empty")
+	}
+	if width > maxPixels/height {
+		return ErrTooLarge
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	c := &pngCanvas{
+		dst:        dst,
+		transforms: []transform{{-board.MinX * scale, -board.MinY * scale, scale}},
+		faces:      map[float64]font.Face{},
+	}
+	drawBoard(ctx, c, board, images)
+	return png.Encode(w, dst)
+}
+
+// Maps item coordinates p to o + p*s on the image
+type transform struct {
+	ox, oy, s float64
+}
+
+// Every shape is filled as polygons in image coordinates; strokes are
+// filled as the area they cover.
+type pngCanvas struct {
+	dst        *image.RGBA
+	transforms []transform
+	faces      map[float64]font.Face
+}
+
+func (c *pngCanvas) top() transform {
+	return c.transforms[len(c.transforms)-1]
+}
+
+func (c *pngCanvas) begin(x, y, scale float64) {
+	t := c.top()
+	c.transforms = append(c.transforms, transform{t.ox + x*t.s, t.oy + y*t.s, t.s * scale})
+}
+
+func (c *pngCanvas) end() {
+	c.transforms = c.transforms[:len(c.transforms)-1]
+}
+
+func (c *pngCanvas) device(x, y float64) point {
+	t := c.top()
+	return point{t.ox + x*t.s, t.oy + y*t.s}
+}
+
+func (c *pngCanvas) rect(x, y, width, height float64, s style) {
+	box := func(grow float64) []point {
+		return []point{
+			c.device(x-grow, y-grow), c.device(x+width+grow, y-grow),
+			c.device(x+width+grow, y+height+grow), c.device(x-grow, y+height+grow),
+		}
+	}
+	if s.fill != "" {
+		c.fill(ParseColor(s.fill), box(0))
+	}
+	if s.stroke == "" {
+		return
+	}
+	if s.dashed {
+		corners := append(box(0), c.device(x, y))
+		c.fill(ParseColor(s.stroke), c.dashes(corners, s.strokeWidth)...)
+		return
+	}
+	if width <= s.strokeWidth || height <= s.strokeWidth {
+		c.fill(ParseColor(s.stroke), box(s.strokeWidth/2))
+		return
+	}
+	c.fill(ParseColor(s.stroke), ring(box(s.strokeWidth/2), box(-s.strokeWidth/2))...)
+}
+
+func (c *pngCanvas) ellipse(cx, cy, rx, ry float64, s style) {
+	oval := func(grow float64) []point {
+		rx, ry := rx+grow, ry+grow
+		if rx <= 0 || ry <= 0 {
+			return nil
+		}
+		n := segments(max(rx, ry) * c.top().s)
+		points := make([]point, n)
+		for i := range points {
+			angle := 2 * math.Pi * float64(i) / float64(n)
+			points[i] = c.device(cx+rx*math.Cos(angle), cy+ry*math.Sin(angle))
+		}
+		return points
+	}
+	if s.fill != "" {
+		c.fill(ParseColor(s.fill), oval(0))
+	}
+	if s.stroke != "" {
+		c.fill(ParseColor(s.stroke), ring(oval(s.strokeWidth/2), oval(-s.strokeWidth/2))...)
+	}
+}
+
+func (c *pngCanvas) polyline(points []point, stroke string, width float64) {
+	device := make([]point, len(points))
+	for i, p := range points {
+		device[i] = c.device(p.x, p.y)
+	}
+	c.fill(ParseColor(stroke), strokeOutline(device, width*c.top().s/2, true)...)
+}
+
+func (c *pngCanvas) text(x, y float64, text string, struck bool) {
+	size := fontSize * c.top().s
+	if size < 1 || text == "" {
+		return
+	}
+	face, ok := c.faces[size]
+	if !ok {
+		face = textFace(size)
+		c.faces[size] = face
+	}
+
+	origin := c.device(x, y+fontSize)
+	drawer := font.Drawer{
+		Dst:  c.dst,
+		Src:  image.NewUniform(ParseColor(inkColor)),
+		Face: face,
+		Dot:  fixed.Point26_6{X: fixed.Int26_6(origin.x * 64), Y: fixed.Int26_6(origin.y * 64)},
+	}
+	drawer.DrawString(text)
+
+	if struck {
+		width := float64(font.MeasureString(face, text)) / 64
+		thickness := max(1, size/16)
+		top := origin.y - size*0.3 - thickness/2
+		c.fill(ParseColor(inkColor), []point{
+			{origin.x, top}, {origin.x + width, top},
+			{origin.x + width, top + thickness}, {origin.x, top + thickness},
+		})
+	}
+}
+
+func (c *pngCanvas) image(x, y, width, height float64, data []byte, contentType string) error {
+	src, err := decodeImage(data)
+	if err != nil {
+		return err
+	}
+	from, to := c.device(x, y), c.device(x+width, y+height)
+	r := image.Rect(int(math.Round(from.x)), int(math.Round(from.y)), int(math.Round(to.x)), int(math.Round(to.y)))
+	xdraw.ApproxBiLinear.Scale(c.dst, r, src, src.Bounds(), draw.Over, nil)
+	return nil
+}
+
+// Fill polygons in image coordinates, overlapping parts once
+func (c *pngCanvas) fill(col color.NRGBA, polygons ...[]point) {
+	bounds := image.Rectangle{}
+	for _, polygon := range polygons {
+		for _, p := range polygon {
+			pixel := image.Rect(int(math.Floor(p.x)), int(math.Floor(p.y)), int(math.Ceil(p.x))+1, int(math.Ceil(p.y))+1)
+			bounds = bounds.Union(pixel)
+		}
+	}
+	clip := bounds.Intersect(c.dst.Bounds())
+	if clip.Empty() || col.A == 0 {
+		return
+	}
+
+	r := vector.NewRasterizer(clip.Dx(), clip.Dy())
+	for _, polygon := range polygons {
+		if len(polygon) < 3 {
+			continue
+		}
+		at := func(p point) (float32, float32) {
+			return float32(p.x - float64(clip.Min.X)), float32(p.y - float64(clip.Min.Y))
+		}
+		r.MoveTo(at(polygon[0]))
+		for _, p := range polygon[1:] {
+			r.LineTo(at(p))
+		}
+		r.ClosePath()
+	}
+	r.Draw(c.dst, clip, image.NewUniform(col), image.Point{})
+}
+
+// The area between two closed outlines, inner ones turning the other way
+func ring(outer, inner []point) [][]point {
+	reversed := make([]point, len(inner))
+	for i, p := range inner {
+		reversed[len(inner)-1-i] = p
+	}
+	return [][]point{outer, reversed}
+}
+
+// Butt-ended dashes along a path in image coordinates, on and off for 4
+// units each like the SVG dash array
+func (c *pngCanvas) dashes(path []point, width float64) [][]point {
+	s := c.top().s
+	dash := 4 * s
+	if dash <= 0 {
+		return nil
+	}
+	var polygons [][]point
+	on, left := true, dash
+	for i := 1; i < len(path); i++ {
+		from, to := path[i-1], path[i]
+		length := math.Hypot(to.x-from.x, to.y-from.y)
+		for done := 0.0; done < length; {
+			step := min(left, length-done)
+			if on {
+				a, b := done/length, (done+step)/length
+				segment := []point{
+					{from.x + (to.x-from.x)*a, from.y + (to.y-from.y)*a},
+					{from.x + (to.x-from.x)*b, from.y + (to.y-from.y)*b},
+				}
+				polygons = append(polygons, strokeOutline(segment, width*s/2, false)...)
+			}
+			done += step
+			left -= step
+			if left <= 0 {
+				on, left = !on, dash
+			}
+		}
+	}
+	return polygons
+}
+
+// The outline of an open path stroked halfWidth to each side: a quad per
+// segment and, for round caps and joins, a disc at each vertex. All turn the
+// same way so that overlaps are filled once.
+func strokeOutline(path []point, halfWidth float64, round bool) [][]point {
+	var polygons [][]point
+	for i := 1; i < len(path); i++ {
+		p0, p1 := path[i-1], path[i]
+		length := math.Hypot(p1.x-p0.x, p1.y-p0.y)
+		if length == 0 {
+			continue
+		}
+		nx, ny := -(p1.y-p0.y)/length*halfWidth, (p1.x-p0.x)/length*halfWidth
+		polygons = append(polygons, []point{
+			{p0.x + nx, p0.y + ny}, {p1.x + nx, p1.y + ny},
+			{p1.x - nx, p1.y - ny}, {p0.x - nx, p0.y - ny},
+		})
+	}
+	if !round {
+		return polygons
+	}
+	n := segments(halfWidth)
+	for _, p := range path {
+		disc := make([]point, n)
+		for i := range disc {
+			angle := -2 * math.Pi * float64(i) / float64(n)
+			disc[i] = point{p.x + halfWidth*math.Cos(angle), p.y + halfWidth*math.Sin(angle)}
+		}
+		polygons = append(polygons, disc)
+	}
+	return polygons
+}
+
+// Number of sides of a polygon that passes for a circle of the radius, in pixels
+func segments(radius float64) int {
+	return int(min(max(math.Ceil(radius*math.Pi), 12), 360))
+}
+
+// Decode an image for rasterizing, refusing ones too large to hold
+func decodeImage(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width < 1 || config.Height < 1 || config.Width > maxPixels/config.Height {
+		return nil, ErrTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	return src, err
+}
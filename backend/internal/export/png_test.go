@@ -0,0 +1,73 @@
+package export
+
+import (
+	"backend/internal/database/schemas"
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWritePNG(t *testing.T) {
+	var icon bytes.Buffer
+	pixel := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	for i := 0; i < len(pixel.Pix); i += 4 {
+		copy(pixel.Pix[i:], []byte{0x00, 0x00, 0xFF, 0xFF})
+	}
+	assert.NoError(t, png.Encode(&icon, pixel))
+
+	items := []schemas.Item{
+		{ID: 1, PositionX: 0, PositionY: 0, Width: 40, Height: 40, Scale: 1, Color: "#FF0000",
+			ShapeItem: &schemas.ShapeItem{Name: "rectangle"}},
+		{ID: 2, ZIndex: 1, PositionX: 20, PositionY: 20, Width: 20, Height: 20, Scale: 2, Color: "#00FF00",
+			ShapeItem: &schemas.ShapeItem{Name: "circle"}},
+		{ID: 3, PositionX: 100, PositionY: 0, Width: 40, Height: 40, Scale: 1,
+			ImageItem: &schemas.ImageItem{AssetID: "stored", Asset: &schemas.Asset{ID: "stored"}}},
+		{ID: 4, PositionX: 0, PositionY: 100, Width: 100, Height: 0, Scale: 1, Color: "#000000",
+			DrawingItem: &schemas.DrawingItem{Points: []schemas.Point{{X: 0, Y: 0}, {X: 100, Y: 0}}}},
+		{ID: 5, PositionX: 100, PositionY: 100, Width: 100, Height: 40, Scale: 1, Color: "#FFFFFF",
+			TextItem: &schemas.TextItem{Content: "Hello"}},
+	}
+	images := func(ctx context.Context, asset *schemas.Asset) ([]byte, string, error) {
+		return icon.Bytes(), "image/png", nil
+	}
+
+	var out bytes.Buffer
+	board := NewBoard(items)
+	assert.NoError(t, WritePNG(context.Background(), &out, board, images, 2))
+	img, err := png.Decode(&out)
+	assert.NoError(t, err)
+	// The board spans -16..216 by -16..156 with its margin
+	assert.Equal(t, image.Rect(0, 0, 464, 344), img.Bounds())
+
+	at := func(x, y float64) color.RGBA {
+		return color.RGBAModel.Convert(img.At(int((x-board.MinX)*2), int((y-board.MinY)*2))).(color.RGBA)
+	}
+	white := color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	assert.Equal(t, white, at(-8, -8), "Margin should be white")
+	assert.Equal(t, color.RGBA{0xFF, 0x00, 0x00, 0xFF}, at(10, 10), "Rectangle should be filled")
+	assert.Equal(t, color.RGBA{0x00, 0xFF, 0x00, 0xFF}, at(40, 40), "Scaled circle should cover its center")
+	assert.Equal(t, white, at(58, 58), "Circle should leave its corners")
+	assert.Equal(t, color.RGBA{0x00, 0x00, 0xFF, 0xFF}, at(120, 20), "Image should be stretched over the item")
+	assert.Equal(t, color.RGBA{0x00, 0x00, 0x00, 0xFF}, at(50, 100), "Drawing should be stroked")
+	assert.Equal(t, white, at(50, 104))
+
+	inked := false
+	for x := 108.0; x < 150 && !inked; x += 0.5 {
+		for y := 108.0; y < 124 && !inked; y += 0.5 {
+			inked = at(x, y) != white
+		}
+	}
+	assert.True(t, inked, "Text should be drawn")
+}
+
+func TestWritePNGTooLarge(t *testing.T) {
+	board := NewBoard(nil).Crop(0, 0, 10000, 10000)
+	err := WritePNG(context.Background(), &bytes.Buffer{}, board, nil, 1)
+	assert.True(t, errors.Is(err, ErrTooLarge))
+}
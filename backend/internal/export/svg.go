@@ -0,0 +1,109 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const svgFontFamily = "sans-serif"
+
+// Write the board as a standalone SVG document, images embedded
+func WriteSVG(ctx context.Context, w io.Writer, board Board, images ImageLoader) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="%s %s %s %s">`+"\n",
+		num(board.Width()), num(board.Height()),
+		num(board.MinX), num(board.MinY), num(board.Width()), num(board.Height()))
+	drawBoard(ctx, svgCanvas{out}, board, images)
+	out.WriteString("</svg>\n")
+	return out.Flush()
+}
+
+// Items are groups moved and scaled into place, so that the document keeps
+// their own coordinates. Errors are left to the writer's Flush.
+type svgCanvas struct {
+	out *bufio.Writer
+}
+
+func (c svgCanvas) begin(x, y, scale float64) {
+	fmt.Fprintf(c.out, `<g transform="translate(%s %s) scale(%s)">`+"\n", num(x), num(y), num(scale))
+}
+
+func (c svgCanvas) end() {
+	c.out.WriteString("</g>\n")
+}
+
+func (c svgCanvas) rect(x, y, width, height float64, s style) {
+	fmt.Fprintf(c.out, `<rect x="%s" y="%s" width="%s" height="%s"%s/>`+"\n",
+		num(x), num(y), num(width), num(height), svgStyle(s))
+}
+
+func (c svgCanvas) ellipse(cx, cy, rx, ry float64, s style) {
+	fmt.Fprintf(c.out, `<ellipse cx="%s" cy="%s" rx="%s" ry="%s"%s/>`+"\n",
+		num(cx), num(cy), num(rx), num(ry), svgStyle(s))
+}
+
+func (c svgCanvas) polyline(points []point, stroke string, width float64) {
+	coords := make([]string, 0, len(points))
+	for _, p := range points {
+		coords = append(coords, num(p.x)+","+num(p.y))
+	}
+	fmt.Fprintf(c.out, `<polyline points="%s"%s stroke-linecap="round" stroke-linejoin="round"/>`+"\n",
+		strings.Join(coords, " "), svgStyle(style{stroke: stroke, strokeWidth: width}))
+}
+
+func (c svgCanvas) text(x, y float64, text string, struck bool) {
+	decoration := ""
+	if struck {
+		decoration = ` text-decoration="line-through"`
+	}
+	fmt.Fprintf(c.out, `<text x="%s" y="%s" font-family="%s" font-size="%d" fill="%s"%s xml:space="preserve">%s</text>`+"\n",
+		num(x), num(y+fontSize), svgFontFamily, fontSize, inkColor, decoration, attr(text))
+}
+
+func (c svgCanvas) image(x, y, width, height float64, data []byte, contentType string) error {
+	fmt.Fprintf(c.out, `<image x="%s" y="%s" width="%s" height="%s" preserveAspectRatio="none" href="data:%s;base64,%s"/>`+"\n",
+		num(x), num(y), num(width), num(height), attr(contentType), base64.StdEncoding.EncodeToString(data))
+	return nil
+}
+
+func svgStyle(s style) string {
+	fill := ` fill="none"`
+	if s.fill != "" {
+		fill = svgPaint("fill", s.fill)
+	}
+	if s.stroke == "" {
+		return fill
+	}
+	dash := ""
+	if s.dashed {
+		dash = ` stroke-dasharray="4"`
+	}
+	return fmt.Sprintf(`%s%s stroke-width="%s"%s`, fill, svgPaint("stroke", s.stroke), num(s.strokeWidth), dash)
+}
+
+// A fill or stroke attribute, with its opacity if the color has one
+func svgPaint(attribute, color string) string {
+	hex, opacity := hexColor(ParseColor(color))
+	if opacity == 1 {
+		return fmt.Sprintf(` %s="%s"`, attribute, hex)
+	}
+	return fmt.Sprintf(` %s="%s" %s-opacity="%s"`, attribute, hex, attribute, num(math.Round(opacity*1000)/1000))
+}
+
+func num(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Escape text for use in an attribute or element
+func attr(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,128 @@
+package export
+
+import (
+	"backend/internal/database/schemas"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Names of the elements of an SVG document, in document order
+func svgElements(t *testing.T, doc []byte) []string {
+	var names []string
+	decoder := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return names
+		}
+		if !assert.NoError(t, err, "SVG should be well-formed") {
+			return names
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			names = append(names, start.Name.Local)
+		}
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	items := []schemas.Item{
+		{ID: 1, ZIndex: 3, PositionX: 10, PositionY: 20, Width: 100, Height: 50, Scale: 1, Color: "#FFEE00",
+			TextItem: &schemas.TextItem{Content: "Fish & <chips>\nsecond line"}},
+		{ID: 2, ZIndex: 1, PositionX: 0, PositionY: 0, Width: 40, Height: 40, Scale: 2, Color: "#FF0000",
+			ShapeItem: &schemas.ShapeItem{Name: "circle"}},
+		{ID: 3, ZIndex: 1, PositionX: 300, PositionY: 0, Width: -40, Height: 20, Scale: 1, Color: "#00FF00",
+			ShapeItem: &schemas.ShapeItem{Name: "rectangle"}},
+		{ID: 4, ZIndex: 2, PositionX: 0, PositionY: 100, Width: 120, Height: 60, Scale: 1, Color: "#FFFFFF",
+			ListItem: &schemas.TodoListItem{TodoListFields: []schemas.TodoListField{
+				{ID: 1, Content: "Done", Done: true},
+				{ID: 2, Content: "To do"},
+			}}},
+		{ID: 5, ZIndex: 4, PositionX: 0, PositionY: 200, Width: 10, Height: 10, Scale: 1, Color: "#0000FF",
+			DrawingItem: &schemas.DrawingItem{Points: []schemas.Point{{X: 0, Y: 0}, {X: 5, Y: 5}, {X: 10, Y: 0}},
+				StrokeWidth: 6, Opacity: 0.5}},
+		{ID: 6, ZIndex: 5, PositionX: 200, PositionY: 200, Width: 64, Height: 32, Scale: 1,
+			ImageItem: &schemas.ImageItem{AssetID: "stored", Asset: &schemas.Asset{ID: "stored"}}},
+		{ID: 7, ZIndex: 5, PositionX: 300, PositionY: 200, Width: 64, Height: 32, Scale: 1,
+			ImageItem: &schemas.ImageItem{AssetID: "lost", Asset: &schemas.Asset{ID: "lost"}}},
+	}
+	images := func(ctx context.Context, asset *schemas.Asset) ([]byte, string, error) {
+		if asset.ID != "stored" {
+			return nil, "", errors.New("not found")
+		}
+		return []byte("png"), "image/png", nil
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, WriteSVG(context.Background(), &out, NewBoard(items), images))
+	svg := out.String()
+
+	assert.Equal(t, []string{
+		"svg",
+		"g", "ellipse", // circle, drawn first with the lowest z-index
+		"g", "rect", // rectangle
+		"g", "rect", "rect", "polyline", "text", "rect", "text", // todo list: box, ticked and empty checkboxes
+		"g", "rect", "text", "text", // text note
+		"g", "polyline", // drawing
+		"g", "image",
+		"g", "rect", // placeholder for the missing image
+	}, svgElements(t, out.Bytes()))
+
+	assert.Contains(t, svg, `viewBox="-16 -16 396 264"`, "Board should be framed with a margin")
+	assert.Contains(t, svg, `translate(0 0) scale(2)`)
+	assert.Contains(t, svg, `<ellipse cx="20" cy="20" rx="20" ry="20" fill="#FF0000"`)
+	assert.Contains(t, svg, `<rect x="-40" y="0" width="40" height="20" fill="#00FF00"`, "Negative sizes extend leftwards")
+	assert.Contains(t, svg, `Fish &amp; &lt;chips&gt;</text>`)
+	assert.Contains(t, svg, `text-decoration="line-through" xml:space="preserve">Done</text>`)
+	assert.Contains(t, svg, `points="0,0 5,5 10,0" fill="none" stroke="#0000FF" stroke-opacity="0.502" stroke-width="6"`,
+		"Drawings should be stroked in their style")
+	assert.Contains(t, svg, `href="data:image/png;base64,cG5n"`)
+}
+
+func TestWriteSVGDrawingOutsideItsBox(t *testing.T) {
+	items := []schemas.Item{
+		{ID: 1, PositionX: 0, PositionY: 0, Width: 10, Height: 10, Scale: 1, Color: "#000000",
+			DrawingItem: &schemas.DrawingItem{Points: []schemas.Point{{X: -20, Y: 5}, {X: 50, Y: 60}}, StrokeWidth: 2}},
+		{ID: 2, PositionX: 100, PositionY: 0, Scale: 2, Color: "#000000",
+			DrawingItem: &schemas.DrawingItem{Points: []schemas.Point{{X: 0, Y: 0}, {X: 10, Y: 20}}, StrokeWidth: 2}},
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, WriteSVG(context.Background(), &out, NewBoard(items), nil))
+	assert.Contains(t, out.String(), `viewBox="-36 -16 172 92"`, "Board should frame the points of drawings")
+}
+
+func TestWriteSVGEmptyBoard(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, WriteSVG(context.Background(), &out, NewBoard(nil), nil))
+	assert.True(t, strings.HasPrefix(out.String(), `<svg xmlns="http://www.w3.org/2000/svg" width="32" height="32" viewBox="0 0 32 32">`))
+	assert.Equal(t, []string{"svg"}, svgElements(t, out.Bytes()))
+}
+
+func TestWriteSVGColors(t *testing.T) {
+	items := []schemas.Item{
+		// As stored by the app: ARGB in decimal
+		{ID: 1, Width: 10, Height: 10, Scale: 1, Color: "4294901760", ShapeItem: &schemas.ShapeItem{Name: "rectangle"}},
+		{ID: 2, Width: 10, Height: 10, Scale: 1, Color: "2147483903", ShapeItem: &schemas.ShapeItem{Name: "rectangle"}},
+		{ID: 3, Width: 10, Height: 10, Scale: 1, Color: "#0F0", ShapeItem: &schemas.ShapeItem{Name: "rectangle"}},
+		{ID: 4, Width: 10, Height: 10, Scale: 1, Color: "#00FF0080", ShapeItem: &schemas.ShapeItem{Name: "rectangle"}},
+		{ID: 5, Width: 10, Height: 10, Scale: 1, Color: "url(#evil)", ShapeItem: &schemas.ShapeItem{Name: "rectangle"}},
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, WriteSVG(context.Background(), &out, NewBoard(items), nil))
+	svg := out.String()
+
+	assert.Contains(t, svg, `fill="#FF0000" stroke=`)
+	assert.Contains(t, svg, `fill="#0000FF" fill-opacity="0.502"`)
+	assert.Contains(t, svg, `fill="#1F1F1F" stroke=`, "Unknown colors should be painted in ink")
+	assert.NotContains(t, svg, "evil")
+	assert.NotContains(t, svg, `"#0F0"`)
+	assert.Equal(t, 2, strings.Count(svg, `fill="#00FF00"`))
+}
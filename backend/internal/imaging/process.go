@@ -0,0 +1,215 @@
+// Package imaging validates and normalizes uploaded images and renders
+// their thumbnails.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupported = errors.New("not a supported image type")
+	ErrTooLarge    = errors.New("image dimensions are too large")
+)
+
+const (
+	// Longest side of a stored image; larger uploads are scaled down
+	MaxDimension = 4096
+	// Uploads with more pixels than this are rejected before decoding
+	MaxPixels   = 50_000_000
+	jpegQuality = 85
+)
+
+type Thumbnail struct {
+	Name    string
+	MaxSize int // longest side
+}
+
+// Thumbnail variants, smallest first. A variant is only made when the image
+// is larger than it.
+var Thumbnails = []Thumbnail{
+	{Name: "small", MaxSize: 256},
+	{Name: "medium", MaxSize: 1024},
+}
+
+type Image struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+type Variant struct {
+	Name string
+	Image
+}
+
+type Result struct {
+	Original   Image
+	Thumbnails []Variant
+}
+
+// Validate an upload by its content, then re-encode it: this drops EXIF and
+// other metadata after applying the EXIF orientation, and scales the image
+// down to MaxDimension. GIFs within bounds are re-encoded frame by frame so
+// that animations survive, without their comment and application extensions
+// other than the loop count. WebP is re-encoded as PNG.
+func Process(data []byte) (*Result, error) {
+	contentType := http.DetectContentType(data)
+	decode, ok := decoders[contentType]
+	if !ok {
+		return nil, ErrUnsupported
+	}
+
+	config, err := decodeConfig(contentType, data)
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	rgba := toRGBA(img)
+	if contentType == "image/jpeg" {
+		rgba = orient(rgba, jpegOrientation(data))
+	}
+	rgba = fit(rgba, MaxDimension)
+
+	// Thumbnails of photos stay JPEG; everything else may have transparency
+	outputType := "image/png"
+	if contentType == "image/jpeg" {
+		outputType = "image/jpeg"
+	}
+
+	result := &Result{}
+	b := rgba.Bounds()
+	if contentType == "image/gif" && b.Dx() == config.Width && b.Dy() == config.Height {
+		animation, err := reencodeGIF(data)
+		if err != nil {
+			return nil, err
+		}
+		result.Original = Image{Data: animation, ContentType: contentType, Width: b.Dx(), Height: b.Dy()}
+	} else {
+		result.Original, err = encode(rgba, outputType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, t := range Thumbnails {
+		if max(b.Dx(), b.Dy()) <= t.MaxSize {
+			continue
+		}
+		thumb, err := encode(fit(rgba, t.MaxSize), outputType)
+		if err != nil {
+			return nil, err
+		}
+		result.Thumbnails = append(result.Thumbnails, Variant{Name: t.Name, Image: thumb})
+	}
+
+	return result, nil
+}
+
+var decoders = map[string]func(r *bytes.Reader) (image.Image, error){
+	"image/jpeg": func(r *bytes.Reader) (image.Image, error) { return jpeg.Decode(r) },
+	"image/png":  func(r *bytes.Reader) (image.Image, error) { return png.Decode(r) },
+	"image/gif":  func(r *bytes.Reader) (image.Image, error) { return gif.Decode(r) },
+	"image/webp": func(r *bytes.Reader) (image.Image, error) { return webp.Decode(r) },
+}
+
+func decodeConfig(contentType string, data []byte) (image.Config, error) {
+	r := bytes.NewReader(data)
+	switch contentType {
+	case "image/jpeg":
+		return jpeg.DecodeConfig(r)
+	case "image/png":
+		return png.DecodeConfig(r)
+	case "image/gif":
+		return gif.DecodeConfig(r)
+	case "image/webp":
+		return webp.DecodeConfig(r)
+	}
+	return image.Config{}, ErrUnsupported
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	xdraw.Draw(rgba, rgba.Bounds(), img, b.Min, xdraw.Src)
+	return rgba
+}
+
+// Scale img down so that its longest side is at most size
+func fit(img *image.RGBA, size int) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+
+	if w >= h {
+		w, h = size, max(1, h*size/w)
+	} else {
+		w, h = max(1, w*size/h), size
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(dst, dst.Rect, img, img.Rect, xdraw.Src, nil)
+	return dst
+}
+
+func encode(img *image.RGBA, contentType string) (Image, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return Image{}, err
+	}
+
+	return Image{
+		Data:        buf.Bytes(),
+		ContentType: contentType,
+		Width:       img.Rect.Dx(),
+		Height:      img.Rect.Dy(),
+	}, nil
+}
+
+// Decode every frame of a GIF and encode them again, which keeps their delays,
+// disposal and the loop count but none of the extensions that carry metadata
+func reencodeGIF(data []byte) ([]byte, error) {
+	animation, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	pixels := 0
+	for _, frame := range animation.Image {
+		pixels += frame.Rect.Dx() * frame.Rect.Dy()
+	}
+	if pixels > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, animation); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
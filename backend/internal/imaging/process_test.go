@@ -0,0 +1,182 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	return img
+}
+
+// A JPEG carrying an EXIF block with the given orientation and a GPS-like
+// marker string that must not survive processing
+func jpegWithOrientation(t *testing.T, img image.Image, orientation uint16) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, img, nil))
+	encoded := buf.Bytes()
+
+	tiff := []byte("II*\x00\x08\x00\x00\x00")             // little endian, IFD0 at 8
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)      // one entry
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112) // orientation
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)      // SHORT
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)      // count
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)                  // padding, next IFD
+	tiff = append(tiff, []byte("GPS 48.8584N 2.2945E")...) // stand-in for location data
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	out := append([]byte{}, encoded[:2]...)
+	out = append(out, app1...)
+	return append(out, encoded[2:]...)
+}
+
+func TestProcessRejectsNonImages(t *testing.T) {
+	_, err := Process([]byte("<html><body>not an image</body></html>"))
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	_, err = Process([]byte("%PDF-1.7"))
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	// Claims to be a PNG but is truncated
+	_, err = Process([]byte("\x89PNG\r\n\x1a\n\x00\x00"))
+	assert.Error(t, err)
+}
+
+func TestProcessCapsDimensionsAndMakesThumbnails(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, testImage(MaxDimension+904, 1000)))
+
+	result, err := Process(buf.Bytes())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "image/png", result.Original.ContentType)
+	assert.Equal(t, MaxDimension, result.Original.Width)
+	assert.Equal(t, 819, result.Original.Height)
+
+	if assert.Equal(t, 2, len(result.Thumbnails)) {
+		assert.Equal(t, "small", result.Thumbnails[0].Name)
+		assert.Equal(t, 256, result.Thumbnails[0].Width)
+		assert.Equal(t, "medium", result.Thumbnails[1].Name)
+		assert.Equal(t, 1024, result.Thumbnails[1].Width)
+
+		config, err := png.DecodeConfig(bytes.NewReader(result.Thumbnails[1].Data))
+		assert.NoError(t, err)
+		assert.Equal(t, 1024, config.Width)
+	}
+}
+
+func TestProcessSmallImageHasNoThumbnails(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, testImage(100, 50)))
+
+	result, err := Process(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, 100, result.Original.Width)
+	assert.Empty(t, result.Thumbnails)
+}
+
+func TestProcessStripsExifAndAppliesOrientation(t *testing.T) {
+	// Stored sideways: rotate 90 degrees clockwise to display
+	data := jpegWithOrientation(t, testImage(300, 200), 6)
+	assert.Equal(t, 6, jpegOrientation(data))
+
+	result, err := Process(data)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "image/jpeg", result.Original.ContentType)
+	assert.Equal(t, 200, result.Original.Width)
+	assert.Equal(t, 300, result.Original.Height)
+	assert.NotContains(t, string(result.Original.Data), "Exif")
+	assert.NotContains(t, string(result.Original.Data), "GPS")
+	assert.Equal(t, 1, jpegOrientation(result.Original.Data))
+
+	if assert.Equal(t, 1, len(result.Thumbnails)) {
+		assert.Equal(t, "image/jpeg", result.Thumbnails[0].ContentType)
+		assert.Equal(t, 170, result.Thumbnails[0].Width)
+		assert.Equal(t, 256, result.Thumbnails[0].Height)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// 2x1: red then blue
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	img.Set(0, 0, red)
+	img.Set(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		w, h        int
+		first       color.RGBA // pixel at 0,0
+	}{
+		{1, 2, 1, red},
+		{2, 2, 1, blue},
+		{3, 2, 1, blue},
+		{4, 2, 1, red},
+		{5, 1, 2, red},
+		{6, 1, 2, red},
+		{7, 1, 2, blue},
+		{8, 1, 2, blue},
+	}
+	for _, tt := range tests {
+		out := orient(img, tt.orientation)
+		assert.Equal(t, tt.w, out.Rect.Dx(), "orientation %d", tt.orientation)
+		assert.Equal(t, tt.h, out.Rect.Dy(), "orientation %d", tt.orientation)
+		assert.Equal(t, tt.first, out.RGBAAt(0, 0), "orientation %d", tt.orientation)
+	}
+}
+
+func TestProcessKeepsGIFs(t *testing.T) {
+	var buf bytes.Buffer
+	palette := color.Palette{color.Black, color.White}
+	frames := []*image.Paletted{
+		image.NewPaletted(image.Rect(0, 0, 10, 10), palette),
+		image.NewPaletted(image.Rect(0, 0, 10, 10), palette),
+	}
+	assert.NoError(t, gif.EncodeAll(&buf, &gif.GIF{Image: frames, Delay: []int{10, 20}, LoopCount: 3}))
+
+	// Slip a comment extension in after the header and screen descriptor
+	data := buf.Bytes()
+	header := 13
+	if data[10]&0x80 != 0 {
+		header += 3 << (data[10]&7 + 1)
+	}
+	comment := append([]byte{0x21, 0xFE, 6}, "secret"...)
+	comment = append(comment, 0)
+	data = append(append(append([]byte(nil), data[:header]...), comment...), data[header:]...)
+
+	result, err := Process(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/gif", result.Original.ContentType)
+	assert.NotContains(t, string(result.Original.Data), "secret", "Comments should be dropped")
+
+	animation, err := gif.DecodeAll(bytes.NewReader(result.Original.Data))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(animation.Image), "Animations should survive")
+	assert.Equal(t, []int{10, 20}, animation.Delay)
+	assert.Equal(t, 3, animation.LoopCount)
+}
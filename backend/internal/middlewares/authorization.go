@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"backend/config"
+	"backend/internal/database"
 	"strings"
 	"time"
 
@@ -17,8 +18,31 @@ type userLoginKeyT struct{}
 
 var LoginKey userLoginKeyT
 
+type userRoleKeyT struct{}
+
+var RoleKey userRoleKeyT
+
+type tokenIDKeyT struct{}
+
+var TokenIDKey tokenIDKeyT
+
+type tokenExpiresKeyT struct{}
+
+// When the access token expires, as a time.Time; unset for tokens without one
+var TokenExpiresKey tokenExpiresKeyT
+
+// Browsers cannot set headers on websocket handshakes, but they can offer
+// subprotocols: "bearer" followed by the access token stands for the header.
+// Unlike a query parameter, it does not end up in access logs.
+const BearerSubprotocol = "bearer"
+
 func JWTMiddleware(c *fiber.Ctx) error {
     authHeader := c.Get("Authorization")
+    if authHeader == "" && strings.EqualFold(c.Get("Upgrade"), "websocket") {
+        if token := subprotocolToken(c.Get("Sec-WebSocket-Protocol")); token != "" {
+            authHeader = "Bearer " + token
+        }
+    }
     // If no auth header, continue without setting locals
     if authHeader == "" {
         return c.Next()
@@ -43,10 +67,23 @@ func JWTMiddleware(c *fiber.Ctx) error {
 
     if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
         // Check expiration if claim exists
-        if exp, ok := claims["exp"].(float64); ok {
-            if time.Now().Unix() > int64(exp) {
-                return c.Next() // Token expired, continue anyway
-            }
+        exp, hasExp := claims["exp"].(float64)
+        if hasExp && time.Now().Unix() > int64(exp) {
+            return c.Next() // Token expired, continue anyway
+        }
+
+        // Tokens without a jti cannot be revoked, so they are not accepted
+        jti, ok := claims["jti"].(string)
+        if !ok || jti == "" {
+            return c.Next()
+        }
+        revoked, err := database.IsTokenRevoked(jti)
+        if err != nil || revoked {
+            return c.Next() // Fail closed on lookup errors
+        }
+        c.Locals(TokenIDKey, jti)
+        if hasExp {
+            c.Locals(TokenExpiresKey, time.Unix(int64(exp), 0))
         }
 
         // Set user info if token is valid
@@ -56,7 +93,20 @@ func JWTMiddleware(c *fiber.Ctx) error {
         if login, ok := claims["login"].(string); ok {
             c.Locals(LoginKey, login)
         }
+        if role, ok := claims["role"].(string); ok {
+            c.Locals(RoleKey, role)
+        }
     }
 
     return c.Next()
 }
+
+func subprotocolToken(header string) string {
+    protocols := strings.Split(header, ",")
+    for i, protocol := range protocols[:len(protocols)-1] {
+        if strings.TrimSpace(protocol) == BearerSubprotocol {
+            return strings.TrimSpace(protocols[i+1])
+        }
+    }
+    return ""
+}
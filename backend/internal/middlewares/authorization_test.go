@@ -25,6 +25,7 @@ func TestJWTMiddleware(t *testing.T) {
 
 	// Override config
 	config.C.JwtSecret = "test-secret-123"
+	database.DB = setupTestDB(t)
 
 	app := fiber.New()
 	app.Use(JWTMiddleware)
@@ -109,6 +110,91 @@ func TestJWTMiddleware(t *testing.T) {
 			expectedLogin:  "",
 			expectedStatus: fiber.StatusOK,
 		},
+		{
+			name: "Token as subprotocol on websocket upgrade",
+			setupRequest: func() *http.Request {
+				token, err := database.CreateTokenForUser(testUser)
+				assert.NoError(t, err)
+
+				req := httptest.NewRequest("GET", "/test", nil)
+				req.Header.Set("Upgrade", "websocket")
+				req.Header.Set("Sec-WebSocket-Protocol", "bearer, "+token)
+				return req
+			},
+			expectedID:     testUser.ID,
+			expectedLogin:  testUser.Login,
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name: "Token as subprotocol without upgrade",
+			setupRequest: func() *http.Request {
+				token, err := database.CreateTokenForUser(testUser)
+				assert.NoError(t, err)
+
+				req := httptest.NewRequest("GET", "/test", nil)
+				req.Header.Set("Sec-WebSocket-Protocol", "bearer, "+token)
+				return req
+			},
+			expectedID:     0,
+			expectedLogin:  "",
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name: "Token in query is not accepted",
+			setupRequest: func() *http.Request {
+				token, err := database.CreateTokenForUser(testUser)
+				assert.NoError(t, err)
+
+				req := httptest.NewRequest("GET", "/test?token="+token, nil)
+				req.Header.Set("Upgrade", "websocket")
+				return req
+			},
+			expectedID:     0,
+			expectedLogin:  "",
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name: "Token without jti",
+			setupRequest: func() *http.Request {
+				claims := jwt.MapClaims{
+					"id":    testUser.ID,
+					"login": testUser.Login,
+					"exp":   time.Now().Add(time.Hour).Unix(),
+				}
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+				tokenString, err := token.SignedString([]byte(config.C.JwtSecret))
+				assert.NoError(t, err)
+
+				req := httptest.NewRequest("GET", "/test", nil)
+				req.Header.Set("Authorization", "Bearer "+tokenString)
+				return req
+			},
+			expectedID:     0,
+			expectedLogin:  "",
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name: "Revoked JWT token",
+			setupRequest: func() *http.Request {
+				claims := jwt.MapClaims{
+					"jti":   "revoked-jti",
+					"id":    testUser.ID,
+					"login": testUser.Login,
+					"exp":   time.Now().Add(time.Hour).Unix(),
+				}
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+				tokenString, err := token.SignedString([]byte(config.C.JwtSecret))
+				assert.NoError(t, err)
+				assert.NoError(t, database.RevokeAccessToken("revoked-jti", time.Now().Add(time.Hour)))
+
+				req := httptest.NewRequest("GET", "/test", nil)
+				req.Header.Set("Authorization", "Bearer "+tokenString)
+				return req
+			},
+			expectedID:     0,
+			expectedLogin:  "",
+			expectedStatus: fiber.StatusOK,
+		},
 		{
 			name: "Wrong signing method",
 			setupRequest: func() *http.Request {
@@ -154,6 +240,7 @@ func TestJWTMiddleware(t *testing.T) {
 func TestJWTWithCreateTokenForUser(t *testing.T) {
     // Override config
 	config.C.JwtSecret = "test-secret-123"
+	database.DB = setupTestDB(t)
 
     // Setup test user
     testUser := schemas.User{
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"backend/internal/models"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	DefaultBodyLimit = 16 << 20 // leaves room for image uploads
+	ImportBodyLimit  = 64 << 20 // a workspace import carries its images inline
+)
+
+type bodyLimitedKeyT struct{}
+
+var bodyLimitedKey bodyLimitedKeyT
+
+// Reads the request body, rejecting it past limit bytes. The app streams
+// request bodies so that routes can have their own limit: the first BodyLimit
+// a request goes through sets it, so larger ones are set up before the default.
+func BodyLimit(limit int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(bodyLimitedKey) != nil {
+			return c.Next()
+		}
+		c.Locals(bodyLimitedKey, true)
+
+		req := c.Request()
+		stream := req.BodyStream()
+		if stream == nil {
+			if len(req.Body()) > limit {
+				return bodyTooLarge(c)
+			}
+			return c.Next()
+		}
+		if req.Header.ContentLength() > limit {
+			return bodyTooLarge(c)
+		}
+		// Chunked bodies have no length, so the read is bounded too
+		body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error: "failed to read request body",
+			})
+		}
+		if len(body) > limit {
+			return bodyTooLarge(c)
+		}
+		req.SetBody(body)
+		return c.Next()
+	}
+}
+
+// What is left of the body is not read, so the connection can't be reused
+func bodyTooLarge(c *fiber.Ctx) error {
+	c.Context().SetConnectionClose()
+	return c.Status(fiber.StatusRequestEntityTooLarge).JSON(models.ErrorResponse{
+		Error: "request body too large",
+	})
+}
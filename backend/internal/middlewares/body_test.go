@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyLimit(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		BodyLimit:                    4,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
+	app.Use("/import", BodyLimit(16))
+	app.Use(BodyLimit(8))
+
+	echo := func(c *fiber.Ctx) error {
+		return c.Send(c.Body())
+	}
+	app.Post("/items", echo)
+	app.Post("/import", echo)
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		chunked        bool
+		expectedStatus int
+	}{
+		{"Within the default limit", "/items", "1234567", false, fiber.StatusOK},
+		{"Past the default limit", "/items", "123456789", false, fiber.StatusRequestEntityTooLarge},
+		{"Chunked past the default limit", "/items", "123456789", true, fiber.StatusRequestEntityTooLarge},
+		{"Chunked within the default limit", "/items", "12345", true, fiber.StatusOK},
+		{"Within the route's limit", "/import", "123456789", false, fiber.StatusOK},
+		{"Past the route's limit", "/import", strings.Repeat("1", 17), false, fiber.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, bytes.NewReader([]byte(tt.body)))
+			if tt.chunked {
+				req.ContentLength = 0
+				req.TransferEncoding = []string{"chunked"}
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus == fiber.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				assert.Equal(t, tt.body, string(body))
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/models"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Reject requests that did not carry a valid token
+func RequireAuth(c *fiber.Ctx) error {
+	if _, ok := c.Locals(IDKey).(uint); !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+	return c.Next()
+}
+
+func IsAdmin(c *fiber.Ctx) bool {
+	role, _ := c.Locals(RoleKey).(string)
+	return role == schemas.RoleAdmin
+}
+
+// Allow only admins
+func RequireAdmin(c *fiber.Ctx) error {
+	if _, ok := c.Locals(IDKey).(uint); !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+	if !IsAdmin(c) {
+		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+			Error: "forbidden",
+		})
+	}
+	return c.Next()
+}
+
+// Allow only the user named by the route parameter, or an admin
+func RequireSelfOrAdmin(param string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals(IDKey).(uint)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+				Error: "unauthorized",
+			})
+		}
+
+		targetID, err := c.ParamsInt(param)
+		if err != nil || targetID < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error: "invalid user id",
+			})
+		}
+
+		if uint(targetID) != userID && !IsAdmin(c) {
+			return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+				Error: "forbidden",
+			})
+		}
+		return c.Next()
+	}
+}
+
+type workspaceRoleKeyT struct{}
+
+// Role of the caller in the workspace named by the route, set by
+// RequireWorkspaceRole
+var WorkspaceRoleKey workspaceRoleKeyT
+
+// Allow only the owner, a member or an admin to access the workspace
+// named by the route parameter
+func RequireWorkspaceAccess(param string) fiber.Handler {
+	return RequireWorkspaceRole(param, schemas.WorkspaceRoleViewer)
+}
+
+// Allow only the owner or an admin to manage the workspace named by the route
+// parameter
+func RequireWorkspaceOwner(param string) fiber.Handler {
+	return RequireWorkspaceRole(param, schemas.WorkspaceRoleOwner)
+}
+
+// Allow only users holding at least the required role in the workspace named
+// by the route parameter; admins act as owners of every workspace
+func RequireWorkspaceRole(param string, required string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals(IDKey).(uint)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+				Error: "unauthorized",
+			})
+		}
+
+		workspaceID, err := c.ParamsInt(param)
+		if err != nil || workspaceID < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error: "invalid workspace id",
+			})
+		}
+
+		role := schemas.WorkspaceRoleOwner
+		if !IsAdmin(c) {
+			role, err = WorkspaceRole(database.DB, userID, uint(workspaceID))
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+					Error: "failed to check workspace access",
+				})
+			}
+		}
+
+		if !schemas.WorkspaceRoleAtLeast(role, required) {
+			return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+				Error: "forbidden",
+			})
+		}
+
+		c.Locals(WorkspaceRoleKey, role)
+		return c.Next()
+	}
+}
+
+// Resolve the role of a user in a workspace: owner for the workspace owner,
+// the membership role for members, and "" when the user has no access or the
+// workspace does not exist
+func WorkspaceRole(db *gorm.DB, userID uint, workspaceID uint) (string, error) {
+	var workspace schemas.Workspace
+	if err := db.Select("owner_id").First(&workspace, workspaceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	if workspace.OwnerID == userID {
+		return schemas.WorkspaceRoleOwner, nil
+	}
+
+	var member schemas.WorkspaceMember
+	err := db.Select("role").
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		Take(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return member.Role, err
+}
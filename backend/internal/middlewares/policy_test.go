@@ -0,0 +1,275 @@
+package middleware
+
+import (
+	"backend/config"
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(
+		sqlite.Open("file::memory:"), &gorm.Config{
+			TranslateError: true,
+			Logger:         logger.Default.LogMode(logger.Silent),
+		},
+	)
+	if err != nil {
+		t.Fatal("failed to connect test database")
+	}
+
+	if err := db.AutoMigrate(
+		&schemas.User{},
+		&schemas.Workspace{},
+		&schemas.WorkspaceMember{},
+		&schemas.RefreshToken{},
+		&schemas.RevokedToken{},
+	); err != nil {
+		t.Fatal("failed to migrate test database")
+	}
+	return db
+}
+
+func bearer(t *testing.T, user schemas.User) string {
+	token, err := database.CreateTokenForUser(user)
+	assert.NoError(t, err)
+	return "Bearer " + token
+}
+
+func TestRequireAuth(t *testing.T) {
+	config.C.JwtSecret = "test-secret-123"
+	database.DB = setupTestDB(t)
+
+	app := fiber.New()
+	app.Use(JWTMiddleware)
+	app.Get("/", RequireAuth, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	t.Run("No token", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer invalid.token.here")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Valid token", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", bearer(t, schemas.User{ID: 1, Login: "user"}))
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestRequireSelfOrAdmin(t *testing.T) {
+	config.C.JwtSecret = "test-secret-123"
+	database.DB = setupTestDB(t)
+
+	app := fiber.New()
+	app.Use(JWTMiddleware)
+	app.Delete("/users/:id", RequireSelfOrAdmin("id"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	self := schemas.User{ID: 1, Login: "self"}
+	admin := schemas.User{ID: 2, Login: "admin", Role: schemas.RoleAdmin}
+
+	tests := []struct {
+		name           string
+		auth           string
+		path           string
+		expectedStatus int
+	}{
+		{"No token", "", "/users/1", fiber.StatusUnauthorized},
+		{"Self", bearer(t, self), "/users/1", fiber.StatusOK},
+		{"Other user", bearer(t, self), "/users/3", fiber.StatusForbidden},
+		{"Admin on other user", bearer(t, admin), "/users/3", fiber.StatusOK},
+		{"Malformed id", bearer(t, self), "/users/abc", fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("DELETE", tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	config.C.JwtSecret = "test-secret-123"
+	database.DB = setupTestDB(t)
+
+	app := fiber.New()
+	app.Use(JWTMiddleware)
+	app.Get("/admin", RequireAdmin, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		auth           string
+		expectedStatus int
+	}{
+		{"No token", "", fiber.StatusUnauthorized},
+		{"User", bearer(t, schemas.User{ID: 1, Login: "user"}), fiber.StatusForbidden},
+		{"Admin", bearer(t, schemas.User{ID: 2, Login: "admin", Role: schemas.RoleAdmin}), fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestRequireWorkspaceAccess(t *testing.T) {
+	config.C.JwtSecret = "test-secret-123"
+	database.DB = setupTestDB(t)
+
+	owner := schemas.User{Login: "owner", PasswordHash: "hash"}
+	member := schemas.User{Login: "member", PasswordHash: "hash"}
+	stranger := schemas.User{Login: "stranger", PasswordHash: "hash"}
+	for _, u := range []*schemas.User{&owner, &member, &stranger} {
+		assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, u))
+	}
+	admin := schemas.User{ID: 99, Login: "admin", Role: schemas.RoleAdmin}
+
+	err := database.DB.Create(&schemas.WorkspaceMember{
+		WorkspaceID: owner.WorkspaceID,
+		UserID:      member.ID,
+	}).Error
+	assert.NoError(t, err)
+
+	app := fiber.New()
+	app.Use(JWTMiddleware)
+	app.Get("/workspaces/:workspace_id", RequireWorkspaceAccess("workspace_id"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	ownerPath := "/workspaces/" + strconv.FormatUint(uint64(owner.WorkspaceID), 10)
+
+	tests := []struct {
+		name           string
+		auth           string
+		path           string
+		expectedStatus int
+	}{
+		{"No token", "", ownerPath, fiber.StatusUnauthorized},
+		{"Owner", bearer(t, owner), ownerPath, fiber.StatusOK},
+		{"Member", bearer(t, member), ownerPath, fiber.StatusOK},
+		{"Stranger", bearer(t, stranger), ownerPath, fiber.StatusForbidden},
+		{"Admin", bearer(t, admin), ownerPath, fiber.StatusOK},
+		{"Non-existent workspace", bearer(t, owner), "/workspaces/999", fiber.StatusForbidden},
+		{"Malformed id", bearer(t, owner), "/workspaces/abc", fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestRequireWorkspaceRole(t *testing.T) {
+	config.C.JwtSecret = "test-secret-123"
+	database.DB = setupTestDB(t)
+
+	owner := schemas.User{Login: "owner", PasswordHash: "hash"}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, &owner))
+
+	members := map[string]schemas.User{}
+	for _, role := range []string{
+		schemas.WorkspaceRoleViewer,
+		schemas.WorkspaceRoleCommenter,
+		schemas.WorkspaceRoleEditor,
+		schemas.WorkspaceRoleOwner,
+	} {
+		user := schemas.User{Login: role + "-member", PasswordHash: "hash"}
+		assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, &user))
+		assert.NoError(t, database.DB.Create(&schemas.WorkspaceMember{
+			WorkspaceID: owner.WorkspaceID,
+			UserID:      user.ID,
+			Role:        role,
+		}).Error)
+		members[role] = user
+	}
+
+	app := fiber.New()
+	app.Use(JWTMiddleware)
+	handler := func(c *fiber.Ctx) error {
+		return c.SendString(c.Locals(WorkspaceRoleKey).(string))
+	}
+	app.Get("/workspaces/:workspace_id", RequireWorkspaceAccess("workspace_id"), handler)
+	app.Post("/workspaces/:workspace_id", RequireWorkspaceRole("workspace_id", schemas.WorkspaceRoleEditor), handler)
+	app.Delete("/workspaces/:workspace_id", RequireWorkspaceOwner("workspace_id"), handler)
+
+	path := "/workspaces/" + strconv.FormatUint(uint64(owner.WorkspaceID), 10)
+
+	tests := []struct {
+		name           string
+		user           schemas.User
+		method         string
+		expectedStatus int
+	}{
+		{"Viewer reads", members[schemas.WorkspaceRoleViewer], "GET", fiber.StatusOK},
+		{"Viewer edits", members[schemas.WorkspaceRoleViewer], "POST", fiber.StatusForbidden},
+		{"Commenter edits", members[schemas.WorkspaceRoleCommenter], "POST", fiber.StatusForbidden},
+		{"Editor edits", members[schemas.WorkspaceRoleEditor], "POST", fiber.StatusOK},
+		{"Editor manages", members[schemas.WorkspaceRoleEditor], "DELETE", fiber.StatusForbidden},
+		{"Owner member manages", members[schemas.WorkspaceRoleOwner], "DELETE", fiber.StatusOK},
+		{"Owner manages", owner, "DELETE", fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, path, nil)
+			req.Header.Set("Authorization", bearer(t, tt.user))
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+		})
+	}
+
+	t.Run("Role is exposed to handlers", func(t *testing.T) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", bearer(t, members[schemas.WorkspaceRoleCommenter]))
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, schemas.WorkspaceRoleCommenter, string(body))
+	})
+}
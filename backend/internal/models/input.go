@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type UserCreate struct {
 	Login    string `json:"login"    example:"john123"`
 	Password string `json:"password" example:"123"`
@@ -11,12 +13,36 @@ type UserUpdate struct {
 	NewPassword *string `json:"new_password" example:"234"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type WorkspaceCreate struct {
+	Name        string `json:"name"        example:"Project board"`
+	Description string `json:"description" example:"Planning for the next release"`
+}
+
+type WorkspaceUpdate struct {
+	Name        *string `json:"name,omitempty"        example:"Project board"`
+	Description *string `json:"description,omitempty" example:"Planning for the next release"`
+}
+
+type MemberInvite struct {
+	Login string `json:"login" example:"john123"`
+	Role  string `json:"role"  example:"editor" enums:"viewer,commenter,editor,owner"`
+}
+
+type MemberUpdate struct {
+	Role string `json:"role" example:"viewer" enums:"viewer,commenter,editor,owner"`
+}
+
 type TextItemCreate struct {
 	Content string `json:"content" example:"Hello, world!"`
 }
 
 type ImageItemCreate struct {
-	Bytes string `json:"bytes"`
+	AssetID string `json:"asset_id,omitempty" example:"0b9c2f4e-8a7d-4c1e-9f3a-2d6b5e8c1a70"` // from POST /workspaces/my/images
+	Bytes   string `json:"bytes,omitempty"`                                                   // or the image in base64, stored as an upload would be
 }
 
 type TodoItemFieldCreate struct {
@@ -29,12 +55,20 @@ type ShapeItemCreate struct {
 }
 
 type DrawingPointCreate struct {
-	X float64 `json:"x" example:"1.0"`
-	Y float64 `json:"y" example:"1.0"`
+	X        float64 `json:"x"                  example:"1.0"`
+	Y        float64 `json:"y"                  example:"1.0"`
+	Pressure float64 `json:"pressure,omitempty" example:"0.5"` // 0 to 1, for every point or none; kept to the thousandth
 }
 
+// A stroke and its style; omitted style fields take their default when
+// creating, and are left unchanged when updating
 type DrawingItemCreate struct {
-	Points []DrawingPointCreate `json:"points"`
+	Points      []DrawingPointCreate `json:"points"`                               // left unchanged by an update when omitted
+	StrokeWidth float64              `json:"stroke_width,omitempty" example:"3.0"` // in units of the item, up to 1000
+	Opacity     float64              `json:"opacity,omitempty"      example:"0.4"` // up to 1; 0 counts as left out
+	LineCap     string               `json:"line_cap,omitempty"     example:"round" enums:"round,butt,square"`
+	LineJoin    string               `json:"line_join,omitempty"    example:"round" enums:"round,miter,bevel"`
+	Smoothing   string               `json:"smoothing,omitempty"    example:"none"  enums:"none,quadratic,catmull-rom"`
 }
 
 type ItemCreate struct {
@@ -42,7 +76,7 @@ type ItemCreate struct {
 	PositionY   float64                `json:"position_y"          example:"1.0"`
 	ZIndex      uint                   `json:"z_index"             example:"1"`
 	Color       string                 `json:"color"               example:"#FFFFFF"`
-	Scale       float64                `json:"scale"               example:"1.0"`
+	Scale       float64                `json:"scale"               example:"1.0"` // 1 if left out
 	Width       float64                `json:"width" example:"20.0"`
 	Height      float64                `json:"height" example:"20.0"`
 	TextItem    *TextItemCreate        `json:"text,omitempty"`
@@ -51,3 +85,60 @@ type ItemCreate struct {
 	ShapeItem   *ShapeItemCreate       `json:"shape,omitempty"`
 	DrawingItem *DrawingItemCreate     `json:"drawing,omitempty"`
 }
+
+type SnapshotCreate struct {
+	Name string `json:"name" example:"Before the redesign"`
+}
+
+// Partial item update; omitted fields are left unchanged
+type ItemUpdate struct {
+	PositionX   *float64               `json:"position_x,omitempty" example:"1.0"`
+	PositionY   *float64               `json:"position_y,omitempty" example:"1.0"`
+	ZIndex      *uint                  `json:"z_index,omitempty"    example:"1"`
+	Color       *string                `json:"color,omitempty"      example:"#FFFFFF"`
+	Scale       *float64               `json:"scale,omitempty"      example:"1.0"`
+	Width       *float64               `json:"width,omitempty"      example:"20.0"`
+	Height      *float64               `json:"height,omitempty"     example:"20.0"`
+	TextItem    *TextItemCreate        `json:"text,omitempty"`
+	ImageItem   *ImageItemCreate       `json:"image,omitempty"`
+	TodoList    *[]TodoItemFieldCreate `json:"todo_list,omitempty"`
+	ShapeItem   *ShapeItemCreate       `json:"shape,omitempty"`
+	DrawingItem *DrawingItemCreate     `json:"drawing,omitempty"`
+	Version     *uint64                `json:"version,omitempty"    example:"3"` // of the edited copy; If-Match takes precedence
+}
+
+// One step of a batch; Op selects which of the other fields are used
+type BatchOperation struct {
+	Op      string      `json:"op"                example:"update"` // create, update or delete
+	ItemID  uint        `json:"item_id,omitempty" example:"12"`     // for update and delete
+	Create  *ItemCreate `json:"create,omitempty"`
+	Update  *ItemUpdate `json:"update,omitempty"`
+	Version *uint64     `json:"version,omitempty" example:"3"` // for delete; updates carry their own
+}
+
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// A whole workspace in the portable export format, images included. Image
+// items refer to an image of the document by its id.
+type WorkspaceDocument struct {
+	Format      string          `json:"format"      example:"prodspace-workspace"`
+	Version     int             `json:"version"     example:"1"`
+	ExportedAt  time.Time       `json:"exported_at"`
+	Name        string          `json:"name"        example:"Project board"`
+	Description string          `json:"description" example:"Planning for the next release"`
+	Items       []DocumentItem  `json:"items"`
+	Images      []DocumentImage `json:"images"`
+}
+
+type DocumentItem struct {
+	ID uint `json:"id" example:"12"` // in the exported workspace; new ids are given on import
+	ItemCreate
+}
+
+type DocumentImage struct {
+	ID          string `json:"id"           example:"0b9c2f4e-8a7d-4c1e-9f3a-2d6b5e8c1a70"`
+	ContentType string `json:"content_type" example:"image/png"`
+	Data        []byte `json:"data"         swaggertype:"string" format:"base64"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type UserRead struct {
 	ID          uint   `json:"id" example:"12345"`
 	Login       string `json:"username"`
@@ -16,7 +18,19 @@ type TodoListItemFieldRead struct {
 }
 
 type ImageItemRead struct {
-	Bytes string `json:"bytes"`
+	AssetID    string             `json:"asset_id"`
+	URL        string             `json:"url" example:"/assets/0b9c2f4e-8a7d-4c1e-9f3a-2d6b5e8c1a70"`
+	Width      int                `json:"width,omitempty"  example:"1920"` // of the stored original
+	Height     int                `json:"height,omitempty" example:"1080"`
+	Thumbnails []ImageVariantRead `json:"thumbnails"` // smallest first; empty when the image is small
+	Bytes      string             `json:"bytes,omitempty"` // stored original in base64, in whole workspace reads only
+}
+
+type ImageVariantRead struct {
+	Name   string `json:"name"   example:"small"`
+	URL    string `json:"url"    example:"/assets/6f1d0c3a-2b4e-4f8a-9c7d-1e5b3a9d2c40"`
+	Width  int    `json:"width"  example:"256"`
+	Height int    `json:"height" example:"144"`
 }
 
 type ShapeItemRead struct {
@@ -24,12 +38,19 @@ type ShapeItemRead struct {
 }
 
 type DrawingPointRead struct {
-	X float64 `json:"x"`
-	Y float64 `json:"y"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Pressure float64 `json:"pressure,omitempty"`
 }
 
 type DrawingItemRead struct {
-	Points []DrawingPointRead `json:"points"`
+	Points      []DrawingPointRead `json:"points"`           // null when packed
+	Packed      string             `json:"packed,omitempty"` // base64 of the stored encoding, sent instead of points with points=packed
+	StrokeWidth float64            `json:"stroke_width"      example:"3.0"`
+	Opacity     float64            `json:"opacity"           example:"1.0"`
+	LineCap     string             `json:"line_cap"          example:"round"`
+	LineJoin    string             `json:"line_join"         example:"round"`
+	Smoothing   string             `json:"smoothing"         example:"none"`
 }
 
 type ItemRead struct {
@@ -47,16 +68,142 @@ type ItemRead struct {
 	TodoListItem []TodoListItemFieldRead  `json:"todo_list,omitempty"`
 	ShapeItem    *ShapeItemRead           `json:"shape,omitempty"`
 	DrawingItem  *DrawingItemRead         `json:"drawing,omitempty"`
+	Revision     uint64                   `json:"revision" example:"42"` // of the item's last change
+	Version      uint64                   `json:"version" example:"3"`   // send back as If-Match when editing
 }
 
 type WorkspaceRead struct {
-	Items []ItemRead `json:"items"`
+	ID       uint       `json:"id"`
+	Name     string     `json:"name"`
+	Role     string     `json:"role,omitempty" example:"editor"` // role of the caller
+	Revision uint64     `json:"revision" example:"42"`           // pass as since to fetch later changes
+	Items    []ItemRead `json:"items"`
+}
+
+type TombstoneRead struct {
+	ID        uint      `json:"id"` // of the deleted item
+	Revision  uint64    `json:"revision" example:"41"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type ChangesRead struct {
+	Revision uint64          `json:"revision" example:"42"` // pass as since next time
+	Reset    bool            `json:"reset"`                 // too far behind; reload the whole workspace
+	Items    []ItemRead      `json:"items"`                 // created or updated, oldest change first
+	Deleted  []TombstoneRead `json:"deleted"`
+}
+
+type WorkspaceInfoRead struct {
+	ID          uint      `json:"id"          example:"1"`
+	OwnerID     uint      `json:"owner_id"    example:"1"`
+	Name        string    `json:"name"        example:"Project board"`
+	Description string    `json:"description" example:"Planning for the next release"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type SharedWorkspaceRead struct {
+	WorkspaceInfoRead
+	Role string `json:"role" example:"editor"`
+}
+
+type MemberRead struct {
+	UserID    uint      `json:"user_id" example:"12345"`
+	Login     string    `json:"username"`
+	Role      string    `json:"role"    example:"editor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AssetRead struct {
+	ID          string             `json:"id"           example:"0b9c2f4e-8a7d-4c1e-9f3a-2d6b5e8c1a70"`
+	ContentType string             `json:"content_type" example:"image/png"`
+	Size        int64              `json:"size"         example:"48213"`
+	URL         string             `json:"url"          example:"/assets/0b9c2f4e-8a7d-4c1e-9f3a-2d6b5e8c1a70"`
+	Width       int                `json:"width"        example:"1920"`
+	Height      int                `json:"height"       example:"1080"`
+	Thumbnails  []ImageVariantRead `json:"thumbnails"`
+}
+
+type StorageStatsRead struct {
+	Assets       int64 `json:"assets"        example:"120"` // including thumbnails
+	Blobs        int64 `json:"blobs"         example:"85"`
+	LogicalBytes int64 `json:"logical_bytes" example:"52428800"` // stored once per asset
+	StoredBytes  int64 `json:"stored_bytes"  example:"31457280"` // stored once per content
+	SavedBytes   int64 `json:"saved_bytes"   example:"20971520"`
 }
 
 type MessageResponse struct {
 	Message string `json:"message" example:"Descriptive message"`
 }
 
+// The edit was based on a stale copy of the item; Item is the current one
+type ConflictResponse struct {
+	Error string   `json:"error" example:"item has been modified"`
+	Item  ItemRead `json:"item"`
+}
+
+type BatchResultRead struct {
+	Op   string    `json:"op" example:"create"`
+	ID   uint      `json:"id" example:"12"` // allocated for creates
+	Item *ItemRead `json:"item,omitempty"` // created or updated item
+}
+
+type BatchRead struct {
+	Results []BatchResultRead `json:"results"` // in the order of the operations
+}
+
+// An operation of a batch failed, so none of them were applied
+type BatchErrorResponse struct {
+	Error     string    `json:"error" example:"item not found in workspace"`
+	Operation int       `json:"operation" example:"3"` // index of the failed operation
+	Item      *ItemRead `json:"item,omitempty"`        // current copy if the operation was stale
+}
+
+type HistoryRead struct {
+	Items   []ItemRead `json:"items"`   // restored or changed back, in the order they were replayed
+	Deleted []uint     `json:"deleted"` // ids of the items removed
+}
+
+type TrashItemRead struct {
+	ItemRead
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"` // when it will be purged, unless the trash is kept for good
+}
+
+type SnapshotInfoRead struct {
+	ID        uint      `json:"id" example:"1"`
+	Name      string    `json:"name" example:"Before the redesign"` // empty for automatic snapshots
+	Automatic bool      `json:"automatic" example:"false"`
+	Revision  uint64    `json:"revision" example:"42"` // workspace revision the snapshot was taken at
+	ItemCount int       `json:"item_count" example:"12"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SnapshotRead struct {
+	SnapshotInfoRead
+	Items []ItemRead `json:"items"`
+}
+
+type ImportRead struct {
+	Items   []ItemRead    `json:"items"`   // created, in the order of the document
+	IDs     map[uint]uint `json:"ids"`     // new item id by id in the document
+	Deleted []uint        `json:"deleted"` // ids of the items replaced
+}
+
+type ExcalidrawImportRead struct {
+	Items       []ItemRead               `json:"items"`       // created, in the order of the scene
+	IDs         map[string]uint          `json:"ids"`         // new item id by element id
+	Deleted     []uint                   `json:"deleted"`     // ids of the items replaced
+	Unsupported []UnsupportedElementRead `json:"unsupported"` // elements left out or simplified
+}
+
+type UnsupportedElementRead struct {
+	ElementID string `json:"element_id" example:"a1B2c3"`
+	Type      string `json:"type" example:"arrow"`
+	Reason    string `json:"reason" example:"arrow elements are not supported"`
+	Skipped   bool   `json:"skipped"` // false when imported without some features
+}
+
 type CreatedResponse struct {
 	Message string `json:"message" example:"Resource created successfully"`
 	ID      uint   `json:"id" example:"12345"`
@@ -71,7 +218,9 @@ type CountResponse struct {
 }
 
 type AuthResponse struct {
-	Message string `json:"message"`
-	Token   string `json:"token"`
-	UserID  uint   `json:"user_id"`
+	Message      string `json:"message"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in" example:"900"` // seconds until the token expires
+	UserID       uint   `json:"user_id"`
 }
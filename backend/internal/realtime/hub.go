@@ -0,0 +1,118 @@
+package realtime
+
+import (
+	"backend/internal/models"
+	"sync"
+)
+
+type EventType string
+
+const (
+	// Sent once when a connection starts receiving events; clients should
+	// load the workspace after it so no change falls in between
+	Subscribed  EventType = "subscribed"
+	ItemCreated EventType = "item.created"
+	ItemUpdated EventType = "item.updated"
+	ItemDeleted EventType = "item.deleted"
+)
+
+// A change to a workspace pushed to its connected clients
+type Event struct {
+	Type        EventType        `json:"type"`
+	WorkspaceID uint             `json:"workspace_id"`
+	ItemID      uint             `json:"item_id,omitempty"`
+	Item        *models.ItemRead `json:"item,omitempty"` // absent for deletes
+}
+
+// Fans workspace events out to subscribers. The in-process LocalHub only
+// reaches clients of this instance; a hub backed by a message broker can
+// replace it to fan out across instances.
+type Hub interface {
+	Publish(event Event)
+	Subscribe(workspaceID uint) Subscription
+}
+
+type Subscription interface {
+	// Closed when the subscription is closed, by the subscriber or by the
+	// hub when the subscriber falls too far behind
+	Events() <-chan Event
+	Close()
+}
+
+var DefaultHub Hub = NewLocalHub()
+
+// Events a subscriber may have pending before it is dropped
+const subscriptionBuffer = 64
+
+type LocalHub struct {
+	mu   sync.RWMutex
+	subs map[uint]map[*localSubscription]struct{}
+}
+
+func NewLocalHub() *LocalHub {
+	return &LocalHub{
+		subs: make(map[uint]map[*localSubscription]struct{}),
+	}
+}
+
+func (h *LocalHub) Subscribe(workspaceID uint) Subscription {
+	sub := &localSubscription{
+		hub:         h,
+		workspaceID: workspaceID,
+		events:      make(chan Event, subscriptionBuffer),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[workspaceID] == nil {
+		h.subs[workspaceID] = make(map[*localSubscription]struct{})
+	}
+	h.subs[workspaceID][sub] = struct{}{}
+	return sub
+}
+
+// Never blocks; subscribers whose buffer is full are dropped
+func (h *LocalHub) Publish(event Event) {
+	var lagging []*localSubscription
+
+	h.mu.RLock()
+	for sub := range h.subs[event.WorkspaceID] {
+		select {
+		case sub.events <- event:
+		default:
+			lagging = append(lagging, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range lagging {
+		sub.Close()
+	}
+}
+
+func (h *LocalHub) remove(sub *localSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs[sub.workspaceID], sub)
+	if len(h.subs[sub.workspaceID]) == 0 {
+		delete(h.subs, sub.workspaceID)
+	}
+}
+
+type localSubscription struct {
+	hub         *LocalHub
+	workspaceID uint
+	events      chan Event
+	once        sync.Once
+}
+
+func (s *localSubscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *localSubscription) Close() {
+	s.once.Do(func() {
+		s.hub.remove(s)
+		close(s.events)
+	})
+}
@@ -0,0 +1,76 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func receive(t *testing.T, sub Subscription) (Event, bool) {
+	select {
+	case event, ok := <-sub.Events():
+		return event, ok
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+		return Event{}, false
+	}
+}
+
+func TestLocalHub(t *testing.T) {
+	hub := NewLocalHub()
+
+	first := hub.Subscribe(1)
+	second := hub.Subscribe(1)
+	other := hub.Subscribe(2)
+	defer first.Close()
+	defer second.Close()
+	defer other.Close()
+
+	hub.Publish(Event{Type: ItemCreated, WorkspaceID: 1, ItemID: 7})
+
+	for _, sub := range []Subscription{first, second} {
+		event, ok := receive(t, sub)
+		assert.True(t, ok)
+		assert.Equal(t, ItemCreated, event.Type)
+		assert.Equal(t, uint(7), event.ItemID)
+	}
+
+	select {
+	case event := <-other.Events():
+		t.Fatalf("other workspace received %+v", event)
+	default:
+	}
+}
+
+func TestLocalHubClose(t *testing.T) {
+	hub := NewLocalHub()
+
+	sub := hub.Subscribe(1)
+	sub.Close()
+	sub.Close() // closing twice is harmless
+
+	_, ok := <-sub.Events()
+	assert.False(t, ok, "Events channel should be closed")
+
+	// Publishing to a workspace without subscribers must not block or panic
+	hub.Publish(Event{Type: ItemDeleted, WorkspaceID: 1, ItemID: 1})
+	assert.Empty(t, hub.subs)
+}
+
+func TestLocalHubDropsLaggingSubscriber(t *testing.T) {
+	hub := NewLocalHub()
+
+	slow := hub.Subscribe(1)
+	for i := 0; i < subscriptionBuffer+1; i++ {
+		hub.Publish(Event{Type: ItemUpdated, WorkspaceID: 1, ItemID: uint(i)})
+	}
+
+	// The buffered events drain, then the channel is closed
+	for i := 0; i < subscriptionBuffer; i++ {
+		_, ok := receive(t, slow)
+		assert.True(t, ok)
+	}
+	_, ok := receive(t, slow)
+	assert.False(t, ok, "Lagging subscriber should be dropped")
+}
@@ -0,0 +1,103 @@
+package realtime
+
+import "sync"
+
+// Tracks open connections by the user, workspace and access token they were
+// opened for, so that they can be closed when the token is revoked or the
+// user loses access to the workspace. Like LocalHub, it only
+// knows this instance's connections; those of other instances notice on
+// their own periodic check.
+type Sessions struct {
+	mu       sync.Mutex
+	sessions map[*Session]struct{}
+}
+
+func NewSessions() *Sessions {
+	return &Sessions{
+		sessions: make(map[*Session]struct{}),
+	}
+}
+
+var DefaultSessions = NewSessions()
+
+type Session struct {
+	owner       *Sessions
+	userID      uint
+	workspaceID uint
+	tokenID     string
+	revoked     chan struct{}
+	reason      string
+	once        sync.Once
+}
+
+func (s *Sessions) Open(userID, workspaceID uint, tokenID string) *Session {
+	session := &Session{
+		owner:       s,
+		userID:      userID,
+		workspaceID: workspaceID,
+		tokenID:     tokenID,
+		revoked:     make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[session] = struct{}{}
+	return session
+}
+
+const (
+	ReasonTokenRevoked  = "token revoked"
+	ReasonAccessRevoked = "access revoked"
+)
+
+// Revoke the sessions opened with an access token
+func (s *Sessions) RevokeToken(tokenID string) {
+	s.revoke(ReasonTokenRevoked, func(session *Session) bool { return session.tokenID == tokenID })
+}
+
+// Revoke every session of a user
+func (s *Sessions) RevokeUser(userID uint) {
+	s.revoke(ReasonTokenRevoked, func(session *Session) bool { return session.userID == userID })
+}
+
+// Revoke the sessions of a user in a workspace they no longer belong to
+func (s *Sessions) RevokeMember(workspaceID, userID uint) {
+	s.revoke(ReasonAccessRevoked, func(session *Session) bool {
+		return session.workspaceID == workspaceID && session.userID == userID
+	})
+}
+
+// Revoke every session in a workspace that is gone
+func (s *Sessions) RevokeWorkspace(workspaceID uint) {
+	s.revoke(ReasonAccessRevoked, func(session *Session) bool { return session.workspaceID == workspaceID })
+}
+
+func (s *Sessions) revoke(reason string, match func(*Session) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for session := range s.sessions {
+		if match(session) {
+			delete(s.sessions, session)
+			session.once.Do(func() {
+				session.reason = reason
+				close(session.revoked)
+			})
+		}
+	}
+}
+
+// Closed once the session's token or its access to the workspace is revoked
+func (s *Session) Revoked() <-chan struct{} {
+	return s.revoked
+}
+
+// Why the session was revoked; only set once Revoked is closed
+func (s *Session) Reason() string {
+	return s.reason
+}
+
+func (s *Session) Close() {
+	s.owner.mu.Lock()
+	defer s.owner.mu.Unlock()
+	delete(s.owner.sessions, s)
+}
@@ -0,0 +1,61 @@
+package realtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func revoked(session *Session) bool {
+	select {
+	case <-session.Revoked():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestSessions(t *testing.T) {
+	sessions := NewSessions()
+
+	first := sessions.Open(1, 1, "first")
+	second := sessions.Open(1, 1, "second")
+	other := sessions.Open(2, 1, "other")
+	defer other.Close()
+
+	sessions.RevokeToken("first")
+	assert.True(t, revoked(first))
+	assert.Equal(t, ReasonTokenRevoked, first.Reason())
+	assert.False(t, revoked(second))
+
+	// Revoking again is a no-op
+	sessions.RevokeToken("first")
+
+	sessions.RevokeUser(1)
+	assert.True(t, revoked(second))
+	assert.False(t, revoked(other))
+
+	// Closed sessions are forgotten
+	other.Close()
+	sessions.RevokeUser(2)
+	assert.False(t, revoked(other))
+}
+
+func TestSessionsRevokeAccess(t *testing.T) {
+	sessions := NewSessions()
+
+	member := sessions.Open(1, 10, "member")
+	elsewhere := sessions.Open(1, 20, "elsewhere")
+	owner := sessions.Open(2, 10, "owner")
+	defer elsewhere.Close()
+
+	sessions.RevokeMember(10, 1)
+	assert.True(t, revoked(member))
+	assert.Equal(t, ReasonAccessRevoked, member.Reason())
+	assert.False(t, revoked(elsewhere), "Other workspaces of the user should stay open")
+	assert.False(t, revoked(owner))
+
+	sessions.RevokeWorkspace(10)
+	assert.True(t, revoked(owner))
+	assert.False(t, revoked(elsewhere))
+}
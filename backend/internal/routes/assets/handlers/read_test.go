@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"backend/internal/assets"
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/storage"
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(
+		sqlite.Open("file::memory:"), &gorm.Config{
+			TranslateError: true,
+			Logger:         logger.Default.LogMode(logger.Silent),
+		},
+	)
+	if err != nil {
+		t.Fatal("failed to connect test database")
+	}
+
+	if err := db.AutoMigrate(&schemas.Asset{}, &schemas.Blob{}, &schemas.ImageItem{}); err != nil {
+		t.Fatal("failed to migrate test database")
+	}
+	return db
+}
+
+func TestGetAsset(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	store, err := storage.NewFileStore(t.TempDir())
+	assert.NoError(t, err)
+	storage.Default = store
+
+	gif := []byte("GIF89a\x01\x00\x01\x00")
+	asset := schemas.Asset{
+		ID:          "stored",
+		OwnerID:     1,
+		WorkspaceID: 1,
+		ContentType: "image/gif",
+	}
+	assert.NoError(t, database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := assets.Store(context.Background(), tx, &asset, gif); err != nil {
+			return err
+		}
+		return tx.Create(&asset).Error
+	}))
+
+	// Stored before uploads were checked to be images
+	page := []byte("<html><script>alert(1)</script></html>")
+	legacy := schemas.Asset{ID: "legacy", OwnerID: 1, WorkspaceID: 1, ContentType: "text/html"}
+	assert.NoError(t, database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := assets.Store(context.Background(), tx, &legacy, page); err != nil {
+			return err
+		}
+		return tx.Create(&legacy).Error
+	}))
+
+	// A row whose object went missing
+	assert.NoError(t, database.DB.Create(&schemas.Asset{ID: "lost", BlobHash: "missing", ContentType: "image/png"}).Error)
+
+	app := fiber.New()
+	app.Get("/assets/:id", GetAsset)
+
+	t.Run("Existing asset", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/assets/stored", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "image/gif", resp.Header.Get("Content-Type"))
+		assert.Contains(t, resp.Header.Get("Cache-Control"), "immutable")
+		assert.Equal(t, `"`+asset.BlobHash+`"`, resp.Header.Get("ETag"))
+		assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+		assert.Equal(t, "inline", resp.Header.Get("Content-Disposition"))
+
+		data, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, gif, data)
+	})
+
+	t.Run("Assets that are not images are downloads", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/assets/legacy", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/octet-stream", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "attachment", resp.Header.Get("Content-Disposition"))
+		assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+	})
+
+	for _, id := range []string{"unknown", "lost"} {
+		t.Run("Missing asset "+id, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", "/assets/"+id, nil))
+			assert.NoError(t, err)
+			assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"backend/internal/assets"
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary Report how much storage deduplication saves
+// @Description Identical files share one stored blob. Compares the bytes stored
+// @Description with the bytes that storing every asset separately would take.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.StorageStatsRead
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/storage [get]
+func GetStorageStats(c *fiber.Ctx) error {
+	stats, err := assets.CollectStats(database.DB)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to collect storage stats",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.StorageStatsRead{
+		Assets:       stats.Assets,
+		Blobs:        stats.Blobs,
+		LogicalBytes: stats.LogicalBytes,
+		StoredBytes:  stats.StoredBytes,
+		SavedBytes:   stats.LogicalBytes - stats.StoredBytes,
+	})
+}
@@ -0,0 +1,16 @@
+package assets
+
+import (
+	middleware "backend/internal/middlewares"
+	"backend/internal/routes/assets/handlers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupAssetRoutes(app *fiber.App) {
+	// Public so that assets can be used directly as <img> sources; asset ids
+	// are random and only handed out to users who can see the workspace
+	app.Get("/assets/:id", handlers.GetAsset)
+
+	app.Get("/admin/storage", middleware.RequireAdmin, handlers.GetStorageStats)
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	middleware "backend/internal/middlewares"
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func newAuthResponse(message string, pair *database.TokenPair) models.AuthResponse {
+	return models.AuthResponse{
+		Message:      message,
+		Token:        pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    int64(pair.ExpiresIn.Seconds()),
+		UserID:       pair.UserID,
+	}
+}
+
+// @Summary Exchange a refresh token for a new token pair
+// @Description The refresh token is rotated; reusing an old one revokes the whole session
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.AuthResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/refresh [post]
+func RefreshToken(c *fiber.Ctx) error {
+	refreshReq := new(models.RefreshRequest)
+	if err := c.BodyParser(refreshReq); err != nil || refreshReq.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "refresh token is required",
+		})
+	}
+
+	pair, err := database.RotateRefreshToken(refreshReq.RefreshToken)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidRefreshToken) || errors.Is(err, database.ErrRefreshTokenReused) {
+			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+				Error: "invalid refresh token",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to refresh token",
+		})
+	}
+
+	return c.JSON(newAuthResponse("token refreshed", pair))
+}
+
+// @Summary Log out of the current session
+// @Description Revokes the presented access token and, if given, the session's refresh tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param token body models.RefreshRequest false "Refresh token of the session"
+// @Success 200 {object} models.MessageResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/logout [post]
+func Logout(c *fiber.Ctx) error {
+	jti, ok := c.Locals(middleware.TokenIDKey).(string)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	// The body is optional; without it only the access token is revoked
+	refreshReq := new(models.RefreshRequest)
+	_ = c.BodyParser(refreshReq)
+
+	if refreshReq.RefreshToken != "" {
+		err := database.RevokeRefreshToken(refreshReq.RefreshToken)
+		if err != nil && !errors.Is(err, database.ErrInvalidRefreshToken) {
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+				Error: "failed to log out",
+			})
+		}
+	}
+
+	if err := database.RevokeAccessToken(jti, time.Now().Add(database.AccessTokenTTL)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to log out",
+		})
+	}
+	realtime.DefaultSessions.RevokeToken(jti)
+
+	return c.JSON(models.MessageResponse{
+		Message: "logged out",
+	})
+}
+
+// @Summary Log out of every session
+// @Description Revokes all refresh tokens of the user and the access tokens issued with them
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.MessageResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/logout-all [post]
+func LogoutAll(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	if err := database.RevokeAllUserTokens(database.DB, userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to log out",
+		})
+	}
+
+	// The presented token may predate the stored refresh tokens
+	if jti, ok := c.Locals(middleware.TokenIDKey).(string); ok {
+		if err := database.RevokeAccessToken(jti, time.Now().Add(database.AccessTokenTTL)); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+				Error: "failed to log out",
+			})
+		}
+	}
+
+	realtime.DefaultSessions.RevokeUser(userID)
+
+	return c.JSON(models.MessageResponse{
+		Message: "logged out of all sessions",
+	})
+}
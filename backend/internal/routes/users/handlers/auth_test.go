@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"backend/config"
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	middleware "backend/internal/middlewares"
+	"backend/internal/models"
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshAndLogout(t *testing.T) {
+	database.DB = setupTestDB()
+	config.C.JwtSecret = "test-secret"
+
+	app := fiber.New()
+	app.Use(middleware.JWTMiddleware)
+	app.Post("/auth/refresh", RefreshToken)
+	app.Post("/auth/logout", middleware.RequireAuth, Logout)
+	app.Post("/auth/logout-all", middleware.RequireAuth, LogoutAll)
+	app.Get("/me", middleware.RequireAuth, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	user := schemas.User{Login: "sessionuser", PasswordHash: "hash"}
+	assert.NoError(t, database.DB.Create(&user).Error)
+
+	post := func(path, token string, payload any) (int, models.AuthResponse) {
+		payloadBytes, _ := json.Marshal(payload)
+		req := httptest.NewRequest("POST", path, bytes.NewReader(payloadBytes))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+
+		var result models.AuthResponse
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	me := func(token string) int {
+		req := httptest.NewRequest("GET", "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	t.Run("Refresh rotates tokens", func(t *testing.T) {
+		pair, err := database.IssueTokenPair(user)
+		assert.NoError(t, err)
+
+		status, result := post("/auth/refresh", "", models.RefreshRequest{RefreshToken: pair.RefreshToken})
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, user.ID, result.UserID)
+		assert.NotEmpty(t, result.Token)
+		assert.NotEqual(t, pair.RefreshToken, result.RefreshToken)
+		assert.Equal(t, fiber.StatusOK, me(result.Token))
+
+		// Reusing the old refresh token revokes the new tokens as well
+		status, _ = post("/auth/refresh", "", models.RefreshRequest{RefreshToken: pair.RefreshToken})
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+		assert.Equal(t, fiber.StatusUnauthorized, me(result.Token))
+	})
+
+	t.Run("Refresh without token", func(t *testing.T) {
+		status, _ := post("/auth/refresh", "", models.RefreshRequest{})
+		assert.Equal(t, fiber.StatusBadRequest, status)
+	})
+
+	t.Run("Logout revokes the session", func(t *testing.T) {
+		pair, err := database.IssueTokenPair(user)
+		assert.NoError(t, err)
+
+		status, _ := post("/auth/logout", pair.AccessToken, models.RefreshRequest{RefreshToken: pair.RefreshToken})
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, fiber.StatusUnauthorized, me(pair.AccessToken))
+
+		status, _ = post("/auth/refresh", "", models.RefreshRequest{RefreshToken: pair.RefreshToken})
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+
+	t.Run("Logout all revokes every session", func(t *testing.T) {
+		laptop, err := database.IssueTokenPair(user)
+		assert.NoError(t, err)
+		phone, err := database.IssueTokenPair(user)
+		assert.NoError(t, err)
+
+		status, _ := post("/auth/logout-all", laptop.AccessToken, nil)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, fiber.StatusUnauthorized, me(laptop.AccessToken))
+		assert.Equal(t, fiber.StatusUnauthorized, me(phone.AccessToken))
+
+		status, _ = post("/auth/refresh", "", models.RefreshRequest{RefreshToken: phone.RefreshToken})
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+
+	t.Run("Logout without token", func(t *testing.T) {
+		status, _ := post("/auth/logout", "", nil)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+}
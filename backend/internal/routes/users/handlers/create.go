@@ -1,14 +1,11 @@
 package handlers
 
 import (
-	"backend/config"
 	"backend/internal/database"
 	"backend/internal/database/schemas"
 	"backend/internal/models"
 	"errors"
-	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
@@ -38,10 +35,18 @@ func RegisterUser(c *fiber.Ctx) error {
         })
     }
 
+    passwordHash, err := database.Hash(userCreate.Password)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+            Error: "failed to create user account",
+        })
+    }
+
     // Create user
     user := &schemas.User{
         Login:        userCreate.Login,
-        PasswordHash: database.Hash(userCreate.Login, userCreate.Password),
+        PasswordHash: passwordHash,
+        Role:         schemas.RoleUser,
     }
 
     // Create user with workspace
@@ -56,34 +61,14 @@ func RegisterUser(c *fiber.Ctx) error {
         })
     }
 
-    // Generate JWT token
-    token, err := generateJWTToken(user)
+    // Generate JWT tokens
+    pair, err := database.IssueTokenPair(*user)
     if err != nil {
         return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
             Error: "failed to generate authentication token",
         })
     }
 
-    // Return response with token
-    return c.Status(fiber.StatusCreated).JSON(models.AuthResponse{
-        Message: "registration successful",
-        Token:   token,
-        UserID:  user.ID,
-    })
+    // Return response with tokens
+    return c.Status(fiber.StatusCreated).JSON(newAuthResponse("registration successful", pair))
 }
-
-// Helper function to generate JWT token
-func generateJWTToken(user *schemas.User) (string, error) {
-    // Set token claims
-    claims := jwt.MapClaims{
-        "id":    user.ID,
-        "login": user.Login,
-        "exp":   time.Now().Add(time.Hour * 24).Unix(), // 24 hour expiration
-    }
-
-    // Create token
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-    // Generate encoded token
-    return token.SignedString([]byte(config.C.JwtSecret))
-}
\ No newline at end of file
@@ -27,7 +27,7 @@ func setupTestDB() *gorm.DB {
 		},
 	)
 	// Migrate the schema
-	db.AutoMigrate(&schemas.User{}, &schemas.Workspace{})
+	db.AutoMigrate(&schemas.User{}, &schemas.Workspace{}, &schemas.RefreshToken{}, &schemas.RevokedToken{})
 	return db
 } 
 
@@ -132,8 +132,8 @@ func TestRegisterUser(t *testing.T) {
 
 				// Verify workspace was created
 				var workspace schemas.Workspace
-				database.DB.Where("user_id = ?", user.ID).First(&workspace)
-				assert.Equal(t, user.ID, workspace.UserID)
+				database.DB.First(&workspace, user.WorkspaceID)
+				assert.Equal(t, user.ID, workspace.OwnerID)
 			}
 		})
 	}
@@ -1,20 +1,28 @@
 package handlers
 
 import (
+	"backend/internal/assets"
 	"backend/internal/database"
 	"backend/internal/database/schemas"
 	"backend/internal/models"
+	"backend/internal/realtime"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
 )
 
 // @Summary Delete a user by ID
+// @Description Deletes the workspaces the user owns and logs out every session
 // @Tags users
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "User ID"
 // @Success 200 {object} models.MessageResponse "User deleted successfully"
 // @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
 // @Failure 404 {object} models.ErrorResponse "User Not Found"
 // @Failure 500 {object} models.ErrorResponse "Internal Server Error"
 // @Router /users/{id} [delete]
@@ -27,20 +35,57 @@ func DeleteUser(c *fiber.Ctx) error {
 		})
 	}
 
-	result := database.DB.Delete(&schemas.User{}, id)
+	var deleted int64
+	var owned []uint
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&schemas.User{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		if deleted == 0 {
+			return nil
+		}
 
-	if err := result.Error; err != nil {
+		// Drop the user from workspaces shared with them
+		if err := tx.Where("user_id = ?", id).Delete(&schemas.WorkspaceMember{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&schemas.Workspace{}).Where("owner_id = ?", id).Pluck("id", &owned).Error; err != nil {
+			return err
+		}
+		for _, workspaceID := range owned {
+			if err := assets.DeleteWorkspace(c.Context(), tx, workspaceID); err != nil {
+				return err
+			}
+		}
+
+		return database.RevokeAllUserTokens(tx, uint(id))
+	})
+
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 			Error: "failed to delete user",
 		})
 	}
 
-	if result.RowsAffected == 0 {
+	if deleted == 0 {
 		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
 			Error: "user not found",
 		})
 	}
 
+	realtime.DefaultSessions.RevokeUser(uint(id))
+	for _, workspaceID := range owned {
+		realtime.DefaultSessions.RevokeWorkspace(workspaceID)
+	}
+
+	// Whatever fails here is left to the purger's next sweep
+	if _, err := assets.CollectGarbage(c.Context(), database.DB); err != nil {
+		log.Error().Err(err).Msg("failed to collect unused blobs")
+	}
+
 	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
 		Message: "user deleted successfully",
 	})
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"backend/config"
+	"backend/internal/assets"
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/storage"
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestDeleteUser(t *testing.T) {
+	app := fiber.New()
+
+	database.DB = setupTestDB()
+	assert.NoError(t, database.DB.AutoMigrate(
+		&schemas.WorkspaceMember{},
+		&schemas.Item{},
+		&schemas.ItemTombstone{},
+		&schemas.HistoryEntry{},
+		&schemas.HistoryAsset{},
+		&schemas.WorkspaceSnapshot{},
+		&schemas.SnapshotAsset{},
+		&schemas.WorkspaceCounter{},
+		&schemas.TextItem{},
+		&schemas.ImageItem{},
+		&schemas.TodoListItem{},
+		&schemas.TodoListField{},
+		&schemas.ShapeItem{},
+		&schemas.DrawingItem{},
+		&schemas.Asset{},
+		&schemas.Blob{},
+	))
+	config.C.JwtSecret = "test-secret"
+
+	store, err := storage.NewFileStore(t.TempDir())
+	assert.NoError(t, err)
+	storage.Default = store
+	defer func() { storage.Default = nil }()
+
+	app.Delete("/users/:id", DeleteUser)
+
+	owner := schemas.User{Login: "leaving"}
+	other := schemas.User{Login: "staying"}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, &owner))
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, &other))
+
+	owned := schemas.Workspace{Name: "Owned", OwnerID: owner.ID}
+	assert.NoError(t, database.DB.Create(&owned).Error)
+	shared := other.WorkspaceID
+	assert.NoError(t, database.DB.Create(&schemas.WorkspaceMember{
+		WorkspaceID: shared, UserID: owner.ID, Role: schemas.WorkspaceRoleEditor,
+	}).Error)
+
+	asset := schemas.Asset{ID: "photo", ContentType: "image/png", OwnerID: owner.ID}
+	assert.NoError(t, database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := assets.Store(context.Background(), tx, &asset, []byte("png")); err != nil {
+			return err
+		}
+		return tx.Create(&asset).Error
+	}))
+	assert.NoError(t, database.DB.Create(&schemas.Item{
+		WorkspaceID: owned.ID, Width: 10, Height: 10, Scale: 1,
+		ImageItem: &schemas.ImageItem{AssetID: asset.ID},
+	}).Error)
+
+	tokens, err := database.IssueTokenPair(owner)
+	assert.NoError(t, err)
+
+	deleteUser := func(id uint) int {
+		req := httptest.NewRequest("DELETE", "/users/"+strconv.FormatUint(uint64(id), 10), nil)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusOK, deleteUser(owner.ID))
+
+	var count int64
+	database.DB.Model(&schemas.Workspace{}).Where("owner_id = ?", owner.ID).Count(&count)
+	assert.Zero(t, count, "Workspaces the user owns, the default one included, should be deleted")
+	database.DB.Model(&schemas.Item{}).Unscoped().Where("workspace_id = ?", owned.ID).Count(&count)
+	assert.Zero(t, count, "Items of deleted workspaces should go with them")
+	database.DB.Model(&schemas.Asset{}).Count(&count)
+	assert.Zero(t, count, "Images only the deleted workspaces showed should be released")
+	database.DB.Model(&schemas.Blob{}).Count(&count)
+	assert.Zero(t, count, "Blobs left unused should be collected")
+
+	database.DB.Model(&schemas.Workspace{}).Where("id = ?", shared).Count(&count)
+	assert.Equal(t, int64(1), count, "Workspaces the user was a member of should stay")
+	database.DB.Model(&schemas.WorkspaceMember{}).Where("user_id = ?", owner.ID).Count(&count)
+	assert.Zero(t, count)
+
+	_, err = database.RotateRefreshToken(tokens.RefreshToken)
+	assert.Error(t, err, "The user's sessions should be revoked")
+
+	assert.Equal(t, fiber.StatusNotFound, deleteUser(owner.ID))
+}
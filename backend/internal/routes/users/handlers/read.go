@@ -8,17 +8,21 @@ import (
 	"errors"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 )
 
 // @Summary Get a user by ID
-// @Description Retrieve a user by their unique ID
+// @Description Retrieve a user by their unique ID; only the user and admins may see it
 // @Tags users
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "User ID"
 // @Success 200 {object} models.UserRead
 // @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
 // @Failure 404 {object} map[string]string "User Not Found"
 // @Failure 500 {object} map[string]string "Internal Server Error"
 // @Router /users/{id} [get]
@@ -62,14 +66,17 @@ type userQueryParams struct {
 
 // GetUserPaginate
 // @Summary Get paginated list of users
-// @Description Retrieve a paginated list of users with optional page and limit query parameters
+// @Description Retrieve a paginated list of users with optional page and limit query parameters. Admins only.
 // @Tags users
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Number of users per page" default(10)
 // @Success 200 {object} []models.UserRead
 // @Failure 400 {object} models.ErrorResponse "Malformed query parameters"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
 // @Failure 500 {object} models.ErrorResponse "Internal Server Error"
 // @Router /users [get]
 func GetUsersPaginate(c *fiber.Ctx) error {
@@ -112,10 +119,14 @@ func GetUsersPaginate(c *fiber.Ctx) error {
 
 // GetUserCount
 // @Summary Get the total number of users
+// @Description Admins only
 // @Tags users
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Success 200 {object} models.CountResponse "User count object"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
 // @Failure 500 {object} models.ErrorResponse "Internal Server Error"
 // @Router /users/count [get]
 func GetUserCount(c *fiber.Ctx) error {
@@ -178,8 +189,15 @@ func LoginUser(c *fiber.Ctx) error {
         })
     }
 
-    // Generate JWT token
-    token, err := generateJWTToken(&user)
+    // Upgrade legacy or outdated hashes while the plain password is at hand
+    if database.NeedsRehash(user.PasswordHash) {
+        if err := rehashPassword(&user, loginReq.Password); err != nil {
+            log.Warn().Err(err).Uint("user_id", user.ID).Msg("failed to upgrade password hash")
+        }
+    }
+
+    // Generate JWT tokens
+    pair, err := database.IssueTokenPair(user)
     if err != nil {
         return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
             Error: "failed to generate authentication token",
@@ -187,9 +205,15 @@ func LoginUser(c *fiber.Ctx) error {
     }
 
     // Return successful response
-    return c.JSON(models.AuthResponse{
-        Message: "login successful",
-        Token:   token,
-        UserID:  user.ID,
-    })
-}
\ No newline at end of file
+    return c.JSON(newAuthResponse("login successful", pair))
+}
+
+func rehashPassword(user *schemas.User, password string) error {
+    passwordHash, err := database.Hash(password)
+    if err != nil {
+        return err
+    }
+
+    user.PasswordHash = passwordHash
+    return database.DB.Model(user).Update("password_hash", passwordHash).Error
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"backend/config"
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/models"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginUser(t *testing.T) {
+	app := fiber.New()
+
+	database.DB = setupTestDB()
+	config.C.JwtSecret = "test-secret"
+
+	app.Post("/login", LoginUser)
+
+	passwordHash, err := database.Hash("loginpass")
+	assert.NoError(t, err)
+	assert.NoError(t, database.DB.Create(&schemas.User{
+		Login:        "loginuser",
+		PasswordHash: passwordHash,
+	}).Error)
+
+	// Hash in the pre-argon2id format: sha256(login:password:secret)
+	legacy := sha256.Sum256([]byte("legacyuser:legacypass:" + config.C.Secret))
+	assert.NoError(t, database.DB.Create(&schemas.User{
+		Login:        "legacyuser",
+		PasswordHash: hex.EncodeToString(legacy[:]),
+	}).Error)
+
+	login := func(payload models.UserCreate) int {
+		payloadBytes, _ := json.Marshal(payload)
+		req := httptest.NewRequest("POST", "/login", bytes.NewReader(payloadBytes))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	t.Run("Successful login", func(t *testing.T) {
+		status := login(models.UserCreate{Login: "loginuser", Password: "loginpass"})
+		assert.Equal(t, fiber.StatusOK, status)
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		status := login(models.UserCreate{Login: "loginuser", Password: "wrong"})
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		status := login(models.UserCreate{Login: "nobody", Password: "loginpass"})
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+
+	t.Run("Legacy hash is upgraded", func(t *testing.T) {
+		status := login(models.UserCreate{Login: "legacyuser", Password: "legacypass"})
+		assert.Equal(t, fiber.StatusOK, status)
+
+		var user schemas.User
+		database.DB.Where("login = ?", "legacyuser").First(&user)
+		assert.False(t, database.NeedsRehash(user.PasswordHash), "Legacy hash should have been replaced")
+
+		status = login(models.UserCreate{Login: "legacyuser", Password: "legacypass"})
+		assert.Equal(t, fiber.StatusOK, status, "Login should still work with the upgraded hash")
+	})
+}
@@ -14,10 +14,13 @@ import (
 // @Tags users
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "User ID"
 // @Param user body models.UserUpdate true "User update payload"
 // @Success 200 {object} models.MessageResponse "Updated Successfully"
 // @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
 // @Failure 404 {object} models.ErrorResponse "User Not Found"
 // @Failure 500 {object} models.ErrorResponse "Internal Server Error"
 // @Router /users/{id} [patch]
@@ -60,18 +63,29 @@ func UpdateUser(c *fiber.Ctx) error {
 		})
 	}
 	
-	inputHash := database.Hash(userUpdate.Login, userUpdate.OldPassword)
-	if user.PasswordHash != inputHash {
+	// Legacy hashes are bound to the current login, not the requested one
+	if !database.VerifyPassword(user.PasswordHash, user.Login, userUpdate.OldPassword) {
 		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
 			Error: "invalid login or old password",
 		})
 	}
 	
-	user.Login = userUpdate.Login
-	
+	// Rehash legacy hashes too, since they would break with the new login
+	newPassword := userUpdate.OldPassword
 	if userUpdate.NewPassword != nil {
-		user.PasswordHash = database.Hash(user.Login, *userUpdate.NewPassword)
+		newPassword = *userUpdate.NewPassword
 	}
+	if userUpdate.NewPassword != nil || database.NeedsRehash(user.PasswordHash) {
+		passwordHash, err := database.Hash(newPassword)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+				Error: "failed to update user",
+			})
+		}
+		user.PasswordHash = passwordHash
+	}
+
+	user.Login = userUpdate.Login
 
 	if err := database.DB.Save(&user).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
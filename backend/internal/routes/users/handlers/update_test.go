@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/models"
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateUser(t *testing.T) {
+	app := fiber.New()
+
+	database.DB = setupTestDB()
+
+	app.Patch("/users/:id", UpdateUser)
+
+	passwordHash, err := database.Hash("oldpass")
+	assert.NoError(t, err)
+	user := &schemas.User{
+		Login:        "renameme",
+		PasswordHash: passwordHash,
+	}
+	assert.NoError(t, database.DB.Create(user).Error)
+
+	update := func(payload models.UserUpdate) int {
+		payloadBytes, _ := json.Marshal(payload)
+		req := httptest.NewRequest("PATCH", "/users/"+strconv.FormatUint(uint64(user.ID), 10), bytes.NewReader(payloadBytes))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	t.Run("Wrong old password", func(t *testing.T) {
+		status := update(models.UserUpdate{Login: "renamed", OldPassword: "wrong"})
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+
+	t.Run("Change login keeps password", func(t *testing.T) {
+		status := update(models.UserUpdate{Login: "renamed", OldPassword: "oldpass"})
+		assert.Equal(t, fiber.StatusOK, status)
+
+		var updated schemas.User
+		database.DB.First(&updated, user.ID)
+		assert.Equal(t, "renamed", updated.Login)
+		assert.Equal(t, passwordHash, updated.PasswordHash, "Renaming should not rehash")
+		assert.True(t, database.VerifyPassword(updated.PasswordHash, updated.Login, "oldpass"))
+	})
+
+	t.Run("Change password", func(t *testing.T) {
+		newPassword := "newpass"
+		status := update(models.UserUpdate{Login: "renamed", OldPassword: "oldpass", NewPassword: &newPassword})
+		assert.Equal(t, fiber.StatusOK, status)
+
+		var updated schemas.User
+		database.DB.First(&updated, user.ID)
+		assert.True(t, database.VerifyPassword(updated.PasswordHash, updated.Login, "newpass"))
+		assert.False(t, database.VerifyPassword(updated.PasswordHash, updated.Login, "oldpass"))
+	})
+}
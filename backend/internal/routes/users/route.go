@@ -1,6 +1,7 @@
 package users
 
 import (
+	middleware "backend/internal/middlewares"
 	"backend/internal/routes/users/handlers"
 
 	"github.com/gofiber/fiber/v2"
@@ -9,9 +10,12 @@ import (
 func SetupUserRoutes(app *fiber.App) {
 	app.Post("/register/", handlers.RegisterUser)
 	app.Post("/login/", handlers.LoginUser)
-	app.Get("/users/", handlers.GetUsersPaginate)
-	app.Get("/users/count", handlers.GetUserCount)
-	app.Get("/users/:id", handlers.GetUser)
-	app.Patch("/users/:id", handlers.UpdateUser)
-	app.Delete("/users/:id", handlers.DeleteUser)
+	app.Post("/auth/refresh", handlers.RefreshToken)
+	app.Post("/auth/logout", middleware.RequireAuth, handlers.Logout)
+	app.Post("/auth/logout-all", middleware.RequireAuth, handlers.LogoutAll)
+	app.Get("/users/", middleware.RequireAdmin, handlers.GetUsersPaginate)
+	app.Get("/users/count", middleware.RequireAdmin, handlers.GetUserCount)
+	app.Get("/users/:id", middleware.RequireSelfOrAdmin("id"), handlers.GetUser)
+	app.Patch("/users/:id", middleware.RequireSelfOrAdmin("id"), handlers.UpdateUser)
+	app.Delete("/users/:id", middleware.RequireSelfOrAdmin("id"), handlers.DeleteUser)
 }
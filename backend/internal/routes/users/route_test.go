@@ -0,0 +1,81 @@
+package users
+
+import (
+	"backend/config"
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	middleware "backend/internal/middlewares"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestUserRoutePolicies(t *testing.T) {
+	config.C.JwtSecret = "test-secret-123"
+	db, err := gorm.Open(
+		sqlite.Open("file::memory:"), &gorm.Config{
+			TranslateError: true,
+			Logger:         logger.Default.LogMode(logger.Silent),
+		},
+	)
+	if err != nil {
+		t.Fatal("failed to connect test database")
+	}
+	if err := db.AutoMigrate(&schemas.User{}, &schemas.RevokedToken{}); err != nil {
+		t.Fatal("failed to migrate test database")
+	}
+	database.DB = db
+
+	app := fiber.New()
+	app.Use(middleware.JWTMiddleware)
+	SetupUserRoutes(app)
+
+	user := schemas.User{Login: "user", PasswordHash: "-"}
+	other := schemas.User{Login: "other", PasswordHash: "-"}
+	admin := schemas.User{Login: "admin", PasswordHash: "-", Role: schemas.RoleAdmin}
+	for _, u := range []*schemas.User{&user, &other, &admin} {
+		assert.NoError(t, db.Create(u).Error)
+	}
+	bearer := func(u schemas.User) string {
+		token, err := database.CreateTokenForUser(u)
+		assert.NoError(t, err)
+		return "Bearer " + token
+	}
+	userPath := func(u schemas.User) string {
+		return "/users/" + strconv.FormatUint(uint64(u.ID), 10)
+	}
+
+	tests := []struct {
+		name           string
+		auth           string
+		path           string
+		expectedStatus int
+	}{
+		{"List without token", "", "/users/", fiber.StatusUnauthorized},
+		{"List as user", bearer(user), "/users/", fiber.StatusForbidden},
+		{"List as admin", bearer(admin), "/users/", fiber.StatusOK},
+		{"Count as user", bearer(user), "/users/count", fiber.StatusForbidden},
+		{"Count as admin", bearer(admin), "/users/count", fiber.StatusOK},
+		{"Get self", bearer(user), userPath(user), fiber.StatusOK},
+		{"Get other user", bearer(user), userPath(other), fiber.StatusForbidden},
+		{"Get other user as admin", bearer(admin), userPath(other), fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	middleware "backend/internal/middlewares"
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const maxBatchOperations = 500
+
+// An operation of a batch failed; the whole batch is rolled back
+type batchOperationError struct {
+	index int
+	err   error
+}
+
+func (e *batchOperationError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.index, e.err)
+}
+
+func (e *batchOperationError) Unwrap() error {
+	return e.err
+}
+
+// @Summary Apply a batch of item operations to a workspace
+// @Description Create, update and delete items in one transaction, in the given
+// @Description order. If any operation fails nothing is applied, and the error
+// @Description names the failed operation. Requires at least the editor role
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param batch body models.BatchRequest true "Operations to apply"
+// @Success 200 {object} models.BatchRead
+// @Failure 400 {object} models.BatchErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.BatchErrorResponse
+// @Failure 409 {object} models.BatchErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/items:batch [post]
+func BatchWorkspaceItems(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return batchWorkspaceItems(c, uint(workspaceID))
+}
+
+// @Summary Apply a batch of item operations to the user's workspace
+// @Description Create, update and delete items in one transaction, in the given
+// @Description order. If any operation fails nothing is applied, and the error
+// @Description names the failed operation
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param batch body models.BatchRequest true "Operations to apply"
+// @Success 200 {object} models.BatchRead
+// @Failure 400 {object} models.BatchErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.BatchErrorResponse
+// @Failure 409 {object} models.BatchErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/items:batch [post]
+func BatchMyWorkspaceItems(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return batchWorkspaceItems(c, workspaceID)
+}
+
+func batchWorkspaceItems(c *fiber.Ctx, workspaceID uint) error {
+	var batch models.BatchRequest
+	if err := c.BodyParser(&batch); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid request body",
+		})
+	}
+
+	if len(batch.Operations) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "batch has no operations",
+		})
+	}
+	if len(batch.Operations) > maxBatchOperations {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: fmt.Sprintf("batch has more than %d operations", maxBatchOperations),
+		})
+	}
+
+	userID, _ := c.Locals(middleware.IDKey).(uint)
+	results := make([]models.BatchResultRead, 0, len(batch.Operations))
+	items := make([]*schemas.Item, 0, len(batch.Operations))
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := autoSnapshot(c.Context(), tx, workspaceID); err != nil {
+			return err
+		}
+		changes := make([]itemChange, 0, len(batch.Operations))
+		for i := range batch.Operations {
+			op := &batch.Operations[i]
+			item, change, err := applyBatchOperation(c, tx, workspaceID, userID, op)
+			if err != nil {
+				return &batchOperationError{index: i, err: err}
+			}
+			changes = append(changes, change)
+
+			result := models.BatchResultRead{Op: op.Op, ID: op.ItemID}
+			if item != nil {
+				itemRead := newItemRead(*item)
+				result.ID = item.ID
+				result.Item = &itemRead
+			}
+			results = append(results, result)
+			items = append(items, item)
+		}
+		// The whole batch is undone at once
+		return recordHistory(tx, workspaceID, userID, changes)
+	})
+
+	var opErr *batchOperationError
+	if errors.As(err, &opErr) {
+		return batchErrorResponse(c, opErr)
+	}
+	if err != nil {
+		return errorResponse(c, err, "failed to apply batch")
+	}
+
+	// Others only hear about the batch once it is committed
+	for i, result := range results {
+		switch result.Op {
+		case "create":
+			publishItemEvent(realtime.ItemCreated, workspaceID, result.ID, items[i])
+		case "update":
+			publishItemEvent(realtime.ItemUpdated, workspaceID, result.ID, items[i])
+		case "delete":
+			publishItemEvent(realtime.ItemDeleted, workspaceID, result.ID, nil)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.BatchRead{Results: results})
+}
+
+// Apply one operation of a batch and return the item it created or updated,
+// with the change to record
+func applyBatchOperation(c *fiber.Ctx, tx *gorm.DB, workspaceID, userID uint, op *models.BatchOperation) (*schemas.Item, itemChange, error) {
+	if (op.Op == "update" || op.Op == "delete") && op.ItemID == 0 {
+		return nil, itemChange{}, fiber.NewError(fiber.StatusBadRequest, "missing item id")
+	}
+
+	switch op.Op {
+	case "create":
+		if op.Create == nil {
+			return nil, itemChange{}, fiber.NewError(fiber.StatusBadRequest, "missing item to create")
+		}
+		item, imageAsset, err := newItem(tx, workspaceID, userID, op.Create)
+		if err != nil {
+			return nil, itemChange{}, err
+		}
+		if err := tx.Create(&item).Error; err != nil {
+			return nil, itemChange{}, err
+		}
+		if imageAsset != nil {
+			item.ImageItem.Asset = imageAsset
+		}
+		return &item, newItemChange(nil, &item), nil
+
+	case "update":
+		if op.Update == nil {
+			return nil, itemChange{}, fiber.NewError(fiber.StatusBadRequest, "missing fields to update")
+		}
+		if err := validateItemUpdate(tx, workspaceID, userID, op.Update); err != nil {
+			return nil, itemChange{}, err
+		}
+		item, change, err := updateItem(c.Context(), tx, workspaceID, op.ItemID, op.Update)
+		if err != nil {
+			return nil, itemChange{}, err
+		}
+		return &item, change, nil
+
+	case "delete":
+		change, err := deleteItem(tx, workspaceID, op.ItemID, op.Version)
+		return nil, change, err
+
+	default:
+		return nil, itemChange{}, fiber.NewError(fiber.StatusBadRequest, "unknown operation, expected create, update or delete")
+	}
+}
+
+// Respond with the status of the failed operation and its index
+func batchErrorResponse(c *fiber.Ctx, opErr *batchOperationError) error {
+	response := models.BatchErrorResponse{Operation: opErr.index}
+
+	var e *fiber.Error
+	var stale *staleItemError
+	switch {
+	case errors.As(opErr.err, &e):
+		response.Error = e.Message
+		return c.Status(e.Code).JSON(response)
+	case errors.As(opErr.err, &stale):
+		itemRead := newItemRead(stale.item)
+		response.Error = stale.Error()
+		response.Item = &itemRead
+		return c.Status(fiber.StatusConflict).JSON(response)
+	default:
+		log.Error().Err(opErr).Msg("failed to apply batch")
+		response.Error = "failed to apply batch"
+		return c.Status(fiber.StatusInternalServerError).JSON(response)
+	}
+}
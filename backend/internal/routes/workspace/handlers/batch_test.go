@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/models"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchMyWorkspaceItems(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+
+	existing := []schemas.Item{
+		{WorkspaceID: user.WorkspaceID, TextItem: &schemas.TextItem{Content: "Move me"}},
+		{WorkspaceID: user.WorkspaceID, TextItem: &schemas.TextItem{Content: "Delete me"}},
+	}
+	for i := range existing {
+		assert.NoError(t, database.DB.Create(&existing[i]).Error)
+	}
+	moved, deleted := existing[0].ID, existing[1].ID
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Post("/workspaces/my/items\\:batch", BatchMyWorkspaceItems)
+
+	post := func(payload string) (int, []byte) {
+		req := httptest.NewRequest("POST", "/workspaces/my/items:batch", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var body json.RawMessage
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.StatusCode, body
+	}
+	countItems := func() int64 {
+		var count int64
+		database.DB.Model(&schemas.Item{}).Where("workspace_id = ?", user.WorkspaceID).Count(&count)
+		return count
+	}
+
+	t.Run("Failed operation rolls back the batch", func(t *testing.T) {
+		status, body := post(fmt.Sprintf(`{"operations": [
+			{"op": "create", "create": {"text": {"content": "Pasted"}}},
+			{"op": "update", "item_id": %d, "update": {"position_x": 100}},
+			{"op": "delete", "item_id": 9999}
+		]}`, moved))
+		assert.Equal(t, fiber.StatusNotFound, status)
+
+		var response models.BatchErrorResponse
+		assert.NoError(t, json.Unmarshal(body, &response))
+		assert.Equal(t, 2, response.Operation)
+
+		assert.Equal(t, int64(2), countItems(), "Nothing should be created")
+		var item schemas.Item
+		assert.NoError(t, database.DB.First(&item, "id = ?", moved).Error)
+		assert.Equal(t, float64(0), item.PositionX, "Nothing should be moved")
+	})
+
+	t.Run("Invalid operations", func(t *testing.T) {
+		for _, payload := range []string{
+			`{"operations": []}`,
+			`{"operations": [{"op": "rename", "item_id": 1}]}`,
+			`{"operations": [{"op": "create", "create": {"text": {"content": ""}}}]}`,
+			`{"operations": [{"op": "update", "update": {"position_x": 1}}]}`,
+			`{"operations": [{"op": "create"}]}`,
+		} {
+			status, _ := post(payload)
+			assert.Equal(t, fiber.StatusBadRequest, status, payload)
+		}
+		assert.Equal(t, int64(2), countItems())
+	})
+
+	t.Run("Stale update", func(t *testing.T) {
+		status, body := post(fmt.Sprintf(`{"operations": [
+			{"op": "update", "item_id": %d, "update": {"version": 7, "position_x": 1}}
+		]}`, moved))
+		assert.Equal(t, fiber.StatusConflict, status)
+
+		var response models.BatchErrorResponse
+		assert.NoError(t, json.Unmarshal(body, &response))
+		if assert.NotNil(t, response.Item) {
+			assert.Equal(t, uint64(1), response.Item.Version)
+		}
+	})
+
+	t.Run("Successful batch", func(t *testing.T) {
+		status, body := post(fmt.Sprintf(`{"operations": [
+			{"op": "create", "create": {"text": {"content": "First"}}},
+			{"op": "create", "create": {"shape": {"name": "circle"}, "position_x": 5}},
+			{"op": "update", "item_id": %d, "update": {"version": 1, "position_x": 100}},
+			{"op": "delete", "item_id": %d, "version": 1}
+		]}`, moved, deleted))
+		assert.Equal(t, fiber.StatusOK, status)
+
+		var response models.BatchRead
+		assert.NoError(t, json.Unmarshal(body, &response))
+		if assert.Equal(t, 4, len(response.Results)) {
+			created := response.Results[0:2]
+			assert.NotZero(t, created[0].ID)
+			assert.Equal(t, created[0].ID+1, created[1].ID, "IDs are allocated in order")
+			assert.Equal(t, "First", created[0].Item.TextItem.Content)
+			assert.Equal(t, float64(5), created[1].Item.PositionX)
+
+			assert.Equal(t, moved, response.Results[2].ID)
+			assert.Equal(t, float64(100), response.Results[2].Item.PositionX)
+			assert.Equal(t, uint64(2), response.Results[2].Item.Version)
+
+			assert.Equal(t, "delete", response.Results[3].Op)
+			assert.Equal(t, deleted, response.Results[3].ID)
+			assert.Nil(t, response.Results[3].Item)
+		}
+		assert.Equal(t, int64(3), countItems())
+	})
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	middleware "backend/internal/middlewares"
+	"backend/internal/models"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Clients missing more changed items than this are told to reload instead
+const maxChanges = 1000
+
+// @Summary List the changes to a workspace's items since a revision
+// @Description Returns items created or updated after the revision and tombstones of
+// @Description deleted ones. When reset is true the client is too far behind and must
+// @Description reload the whole workspace. Requires at least the viewer role
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param since query int false "Revision the client has synced up to; 0 for everything"
+// @Param points query string false "How drawing points are sent" Enums(objects, packed) default(objects)
+// @Success 200 {object} models.ChangesRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/changes [get]
+func ListWorkspaceChanges(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return listWorkspaceChanges(c, uint(workspaceID))
+}
+
+// @Summary List the changes to the user's workspace since a revision
+// @Description Returns items created or updated after the revision and tombstones of
+// @Description deleted ones. When reset is true the client is too far behind and must
+// @Description reload the whole workspace
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param since query int false "Revision the client has synced up to; 0 for everything"
+// @Param points query string false "How drawing points are sent" Enums(objects, packed) default(objects)
+// @Success 200 {object} models.ChangesRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/changes [get]
+func ListMyWorkspaceChanges(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return listWorkspaceChanges(c, workspaceID)
+}
+
+func listWorkspaceChanges(c *fiber.Ctx, workspaceID uint) error {
+	since, err := strconv.ParseUint(c.Query("since", "0"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid since revision",
+		})
+	}
+	packed, err := packedPointsQuery(c)
+	if err != nil {
+		return errorResponse(c, err, "failed to list changes")
+	}
+
+	// Changes up to the counter's revision have committed; later ones are
+	// left for the next sync
+	counter, err := workspaceCounter(database.DB, workspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to list changes",
+		})
+	}
+
+	changes := models.ChangesRead{
+		Revision: counter.Revision,
+		Items:    []models.ItemRead{},
+		Deleted:  []models.TombstoneRead{},
+	}
+
+	// Deletions before the pruned revision are forgotten, and a client ahead
+	// of the server synced against state that is gone
+	if since < counter.PrunedRevision || since > counter.Revision {
+		changes.Reset = true
+		return c.Status(fiber.StatusOK).JSON(changes)
+	}
+
+	changed := database.DB.
+		Where("workspace_id = ? AND revision > ? AND revision <= ?", workspaceID, since, counter.Revision)
+
+	var count int64
+	if err := changed.Session(&gorm.Session{}).Model(&schemas.Item{}).Count(&count).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to list changes",
+		})
+	}
+	if count > maxChanges {
+		changes.Reset = true
+		return c.Status(fiber.StatusOK).JSON(changes)
+	}
+
+	var items []schemas.Item
+	if err := preloadItemRecords(changed.Session(&gorm.Session{}), "").
+		Order("revision").
+		Find(&items).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to list changes",
+		})
+	}
+
+	var tombstones []schemas.ItemTombstone
+	if err := changed.Session(&gorm.Session{}).
+		Order("revision").
+		Find(&tombstones).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to list changes",
+		})
+	}
+
+	changes.Items = newItemReads(items, packed)
+	for _, tombstone := range tombstones {
+		changes.Deleted = append(changes.Deleted, models.TombstoneRead{
+			ID:        tombstone.ItemID,
+			Revision:  tombstone.Revision,
+			DeletedAt: tombstone.DeletedAt,
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(changes)
+}
+
+// Load the counters of a workspace; zero if nothing was ever allocated
+func workspaceCounter(db *gorm.DB, workspaceID uint) (schemas.WorkspaceCounter, error) {
+	var counter schemas.WorkspaceCounter
+	err := db.Where("workspace_id = ?", workspaceID).Limit(1).Find(&counter).Error
+	return counter, err
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListMyWorkspaceChanges(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Get("/workspaces/my", GetMyWorkspace)
+	app.Get("/workspaces/my/changes", ListMyWorkspaceChanges)
+	app.Post("/workspaces/my/items", AppendMyWorkspaceItem)
+	app.Patch("/workspaces/my/items/:item_id", UpdateMyWorkspaceItem)
+	app.Delete("/workspaces/my/items/:item_id", DeleteMyWorkspaceItem)
+
+	send := func(method, path string, payload interface{}) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Less(t, resp.StatusCode, 300, "%s %s", method, path)
+	}
+	changesSince := func(since uint64) models.ChangesRead {
+		resp, err := app.Test(httptest.NewRequest("GET", fmt.Sprintf("/workspaces/my/changes?since=%d", since), nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var changes models.ChangesRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&changes))
+		return changes
+	}
+	text := func(content string) models.ItemCreate {
+		return models.ItemCreate{TextItem: &models.TextItemCreate{Content: content}}
+	}
+
+	send("POST", "/workspaces/my/items", text("first"))
+	send("POST", "/workspaces/my/items", text("second"))
+
+	// The client loads the board, then goes offline
+	resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my", nil))
+	assert.NoError(t, err)
+	var workspace models.WorkspaceRead
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&workspace))
+	assert.Equal(t, 2, len(workspace.Items))
+	synced := workspace.Revision
+	assert.NotZero(t, synced)
+
+	first, second := workspace.Items[0].ID, workspace.Items[1].ID
+	x := float64(50)
+	send("PATCH", fmt.Sprintf("/workspaces/my/items/%d", first), models.ItemUpdate{PositionX: &x})
+	send("DELETE", fmt.Sprintf("/workspaces/my/items/%d", second), nil)
+	send("POST", "/workspaces/my/items", text("third"))
+
+	t.Run("Changes since the last sync", func(t *testing.T) {
+		changes := changesSince(synced)
+		assert.False(t, changes.Reset)
+		assert.Greater(t, changes.Revision, synced)
+
+		if assert.Equal(t, 2, len(changes.Items)) {
+			assert.Equal(t, first, changes.Items[0].ID)
+			assert.Equal(t, float64(50), changes.Items[0].PositionX)
+			assert.Equal(t, "third", changes.Items[1].TextItem.Content)
+			assert.Less(t, changes.Items[0].Revision, changes.Items[1].Revision)
+		}
+		if assert.Equal(t, 1, len(changes.Deleted)) {
+			assert.Equal(t, second, changes.Deleted[0].ID)
+		}
+
+		upToDate := changesSince(changes.Revision)
+		assert.False(t, upToDate.Reset)
+		assert.Empty(t, upToDate.Items)
+		assert.Empty(t, upToDate.Deleted)
+	})
+
+	t.Run("Everything", func(t *testing.T) {
+		changes := changesSince(0)
+		assert.False(t, changes.Reset)
+		assert.Equal(t, 2, len(changes.Items))
+		assert.Equal(t, 1, len(changes.Deleted))
+	})
+
+	t.Run("Ahead of the server", func(t *testing.T) {
+		changes := changesSince(changesSince(0).Revision + 10)
+		assert.True(t, changes.Reset)
+	})
+
+	t.Run("Invalid since", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/changes?since=-1", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Behind pruned tombstones", func(t *testing.T) {
+		retention := schemas.TombstoneRetention
+		schemas.TombstoneRetention = -time.Hour // everything is old enough
+		defer func() { schemas.TombstoneRetention = retention }()
+
+		current := changesSince(0).Revision
+		send("DELETE", fmt.Sprintf("/workspaces/my/items/%d", first), nil)
+
+		assert.True(t, changesSince(synced).Reset, "Deletions the client missed are forgotten")
+
+		changes := changesSince(current + 1)
+		assert.False(t, changes.Reset)
+	})
+}
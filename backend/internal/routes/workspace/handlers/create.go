@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	middleware "backend/internal/middlewares"
+	"backend/internal/models"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary Create a workspace
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param workspace body models.WorkspaceCreate true "Workspace to create"
+// @Success 201 {object} models.CreatedResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces [post]
+func CreateWorkspace(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceCreate := new(models.WorkspaceCreate)
+	if err := c.BodyParser(workspaceCreate); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid request body",
+		})
+	}
+
+	name := strings.TrimSpace(workspaceCreate.Name)
+	if name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "workspace name is required",
+		})
+	}
+
+	workspace := schemas.Workspace{
+		OwnerID:     userID,
+		Name:        name,
+		Description: workspaceCreate.Description,
+	}
+	if err := database.DB.Create(&workspace).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to create workspace",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.CreatedResponse{
+		Message: "workspace created successfully",
+		ID:      workspace.ID,
+	})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/models"
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAndListWorkspaces(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	err := schemas.CreateUserWithWorkspace(database.DB, user)
+	assert.NoError(t, err)
+
+	other := &schemas.User{
+		Login:        "otheruser",
+		PasswordHash: "hashedpassword",
+	}
+	err = schemas.CreateUserWithWorkspace(database.DB, other)
+	assert.NoError(t, err)
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Get("/workspaces", ListWorkspaces)
+	app.Post("/workspaces", CreateWorkspace)
+
+	create := func(payload models.WorkspaceCreate) (int, models.CreatedResponse) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest("POST", "/workspaces", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+
+		var created models.CreatedResponse
+		json.NewDecoder(resp.Body).Decode(&created)
+		return resp.StatusCode, created
+	}
+
+	status, created := create(models.WorkspaceCreate{Name: "Project", Description: "Per-project board"})
+	assert.Equal(t, fiber.StatusCreated, status)
+	assert.NotEqual(t, user.WorkspaceID, created.ID)
+
+	status, _ = create(models.WorkspaceCreate{Name: "  "})
+	assert.Equal(t, fiber.StatusBadRequest, status, "Blank names should be rejected")
+
+	req := httptest.NewRequest("GET", "/workspaces", nil)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var workspaces []models.WorkspaceInfoRead
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&workspaces))
+	if assert.Equal(t, 2, len(workspaces), "Only the user's own workspaces should be listed") {
+		assert.Equal(t, schemas.DefaultWorkspaceName, workspaces[0].Name)
+		assert.Equal(t, "Project", workspaces[1].Name)
+		assert.Equal(t, "Per-project board", workspaces[1].Description)
+		assert.Equal(t, user.ID, workspaces[1].OwnerID)
+		assert.False(t, workspaces[1].CreatedAt.IsZero())
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"backend/internal/assets"
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// @Summary Delete a workspace with all its items
+// @Description The owner's default workspace cannot be deleted
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Success 200 {object} models.MessageResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id} [delete]
+func DeleteWorkspace(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var workspace schemas.Workspace
+		if err := tx.First(&workspace, workspaceID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "workspace not found")
+			}
+			return err
+		}
+
+		var defaults int64
+		if err := tx.Model(&schemas.User{}).
+			Where("workspace_id = ?", workspace.ID).
+			Count(&defaults).Error; err != nil {
+			return err
+		}
+		if defaults > 0 {
+			return fiber.NewError(fiber.StatusConflict, "cannot delete a default workspace")
+		}
+
+		return assets.DeleteWorkspace(c.Context(), tx, workspace.ID)
+	})
+
+	if err != nil {
+		return errorResponse(c, err, "failed to delete workspace")
+	}
+	realtime.DefaultSessions.RevokeWorkspace(uint(workspaceID))
+	collectBlobs(c.Context())
+
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "workspace deleted successfully",
+	})
+}
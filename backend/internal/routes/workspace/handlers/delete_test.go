@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteWorkspace(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	err := schemas.CreateUserWithWorkspace(database.DB, user)
+	assert.NoError(t, err)
+
+	project := schemas.Workspace{OwnerID: user.ID, Name: "Project"}
+	assert.NoError(t, database.DB.Create(&project).Error)
+
+	items := []schemas.Item{
+		{
+			WorkspaceID: project.ID,
+			TextItem:    &schemas.TextItem{Content: "Gone"},
+		},
+		{
+			WorkspaceID: project.ID,
+			DrawingItem: &schemas.DrawingItem{
+				Points: []schemas.Point{{X: 1, Y: 1}, {X: 2, Y: 2}},
+			},
+		},
+		{
+			WorkspaceID: user.WorkspaceID,
+			TextItem:    &schemas.TextItem{Content: "Kept"},
+		},
+	}
+	for i := range items {
+		assert.NoError(t, database.DB.Create(&items[i]).Error)
+	}
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Delete("/workspaces/:workspace_id", DeleteWorkspace)
+
+	remove := func(workspaceID uint) int {
+		req := httptest.NewRequest("DELETE", "/workspaces/"+strconv.FormatUint(uint64(workspaceID), 10), nil)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	t.Run("Default workspace is protected", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusConflict, remove(user.WorkspaceID))
+	})
+
+	t.Run("Delete workspace with items", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, remove(project.ID))
+
+		var count int64
+		database.DB.Model(&schemas.Workspace{}).Where("id = ?", project.ID).Count(&count)
+		assert.Equal(t, int64(0), count)
+		for _, child := range []interface{}{&schemas.Item{}, &schemas.TextItem{}, &schemas.DrawingItem{}} {
+			database.DB.Model(child).Where("workspace_id = ?", project.ID).Count(&count)
+			assert.Equal(t, int64(0), count, "%T rows should be deleted", child)
+		}
+
+		database.DB.Model(&schemas.Item{}).Where("workspace_id = ?", user.WorkspaceID).Count(&count)
+		assert.Equal(t, int64(1), count, "Other workspaces should be untouched")
+	})
+
+	t.Run("Non-existent workspace", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusNotFound, remove(project.ID))
+	})
+}
@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/imaging"
+	middleware "backend/internal/middlewares"
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"backend/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Identifies workspace documents, and the newest version of their format
+// this server writes and reads. Older versions stay readable.
+const (
+	documentFormat  = "prodspace-workspace"
+	documentVersion = 1
+)
+
+// Capture every item of a workspace with the original of every image shown
+func newWorkspaceDocument(ctx context.Context, workspaceID uint) (models.WorkspaceDocument, error) {
+	var workspace schemas.Workspace
+	if err := database.DB.First(&workspace, workspaceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.WorkspaceDocument{}, fiber.NewError(fiber.StatusNotFound, "workspace not found")
+		}
+		return models.WorkspaceDocument{}, err
+	}
+
+	var items []schemas.Item
+	if err := preloadItemRecords(database.DB, "").
+		Where("workspace_id = ?", workspaceID).
+		Order("id").
+		Find(&items).Error; err != nil {
+		return models.WorkspaceDocument{}, err
+	}
+
+	doc := models.WorkspaceDocument{
+		Format:      documentFormat,
+		Version:     documentVersion,
+		ExportedAt:  time.Now().UTC(),
+		Name:        workspace.Name,
+		Description: workspace.Description,
+		Items:       make([]models.DocumentItem, 0, len(items)),
+		Images:      []models.DocumentImage{},
+	}
+	seen := make(map[string]bool)
+	for _, item := range items {
+		doc.Items = append(doc.Items, models.DocumentItem{ID: item.ID, ItemCreate: *itemState(item)})
+
+		if item.ImageItem == nil || seen[item.ImageItem.AssetID] {
+			continue
+		}
+		seen[item.ImageItem.AssetID] = true
+		asset := item.ImageItem.Asset
+		if asset == nil {
+			return doc, fmt.Errorf("asset %s of item %d is missing", item.ImageItem.AssetID, item.ID)
+		}
+		data, err := readBlob(ctx, asset.BlobHash)
+		if err != nil {
+			return doc, err
+		}
+		doc.Images = append(doc.Images, models.DocumentImage{
+			ID:          asset.ID,
+			ContentType: asset.ContentType,
+			Data:        data,
+		})
+	}
+	return doc, nil
+}
+
+func readBlob(ctx context.Context, hash string) ([]byte, error) {
+	r, err := storage.Default.Get(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// Check the format and version of a document and decode the images its items
+// show, keyed by their id in the document
+func readWorkspaceDocument(doc *models.WorkspaceDocument) (map[string]*imaging.Result, error) {
+	if doc.Format != documentFormat {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "not a workspace document")
+	}
+	if doc.Version < 1 || doc.Version > documentVersion {
+		return nil, fiber.NewError(fiber.StatusBadRequest,
+			fmt.Sprintf("unsupported document version %d; expected up to %d", doc.Version, documentVersion))
+	}
+
+	shown := make(map[string]bool)
+	ids := make(map[uint]bool, len(doc.Items))
+	for _, item := range doc.Items {
+		if ids[item.ID] {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("duplicate item id %d", item.ID))
+		}
+		ids[item.ID] = true
+		if item.ImageItem != nil {
+			shown[item.ImageItem.AssetID] = true
+		}
+	}
+
+	images := make(map[string]*imaging.Result, len(shown))
+	for _, image := range doc.Images {
+		if !shown[image.ID] {
+			continue
+		}
+		if images[image.ID] != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("duplicate image id %s", image.ID))
+		}
+		// Validated and re-encoded like an upload
+		processed, err := imaging.Process(image.Data)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("image %s is not a supported image", image.ID))
+		}
+		images[image.ID] = processed
+	}
+	return images, nil
+}
+
+// @Summary Export a workspace as a JSON document
+// @Description Write every item with its typed content and the images it shows, in
+// @Description a versioned format that POST /workspaces/{workspace_id}/import reads back
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Success 200 {object} models.WorkspaceDocument
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/export.json [get]
+func ExportWorkspaceJSON(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return exportWorkspaceJSON(c, uint(workspaceID))
+}
+
+// @Summary Export the user's workspace as a JSON document
+// @Description Write every item with its typed content and the images it shows, in
+// @Description a versioned format that POST /workspaces/my/import reads back
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.WorkspaceDocument
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/export.json [get]
+func ExportMyWorkspaceJSON(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return exportWorkspaceJSON(c, workspaceID)
+}
+
+func exportWorkspaceJSON(c *fiber.Ctx, workspaceID uint) error {
+	doc, err := newWorkspaceDocument(c.Context(), workspaceID)
+	if err != nil {
+		return errorResponse(c, err, "failed to export workspace")
+	}
+
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="workspace-%d.json"`, workspaceID))
+	return c.Status(fiber.StatusOK).JSON(doc)
+}
+
+// @Summary Import a JSON document into a workspace
+// @Description Add the items of a document written by GET /workspaces/{workspace_id}/export.json,
+// @Description under new ids. In replace mode the items already on the board are moved
+// @Description to the trash first, after a snapshot of the board. Requires the editor role.
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param mode query string false "merge or replace" Enums(merge, replace) default(merge)
+// @Param document body models.WorkspaceDocument true "Exported workspace"
+// @Success 200 {object} models.ImportRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/import [post]
+func ImportWorkspace(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return importWorkspace(c, uint(workspaceID))
+}
+
+// @Summary Import a JSON document into the user's workspace
+// @Description Add the items of a document written by GET /workspaces/my/export.json,
+// @Description under new ids. In replace mode the items already on the board are moved
+// @Description to the trash first, after a snapshot of the board.
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param mode query string false "merge or replace" Enums(merge, replace) default(merge)
+// @Param document body models.WorkspaceDocument true "Exported workspace"
+// @Success 200 {object} models.ImportRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/import [post]
+func ImportMyWorkspace(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return importWorkspace(c, workspaceID)
+}
+
+func importWorkspace(c *fiber.Ctx, workspaceID uint) error {
+	replace, err := importMode(c)
+	if err != nil {
+		return errorResponse(c, err, "failed to import document")
+	}
+
+	var doc models.WorkspaceDocument
+	if err := c.BodyParser(&doc); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid request body",
+		})
+	}
+	// Decoding images is slow, so it is done before the transaction
+	images, err := readWorkspaceDocument(&doc)
+	if err != nil {
+		return errorResponse(c, err, "failed to read document")
+	}
+
+	items := make([]importedItem, 0, len(doc.Items))
+	for _, docItem := range doc.Items {
+		items = append(items, importedItem{label: fmt.Sprintf("item %d", docItem.ID), state: docItem.ItemCreate})
+	}
+	created, deleted, err := importItems(c, workspaceID, replace, items, images)
+	if err != nil {
+		return errorResponse(c, err, "failed to import document")
+	}
+
+	response := models.ImportRead{
+		Items:   make([]models.ItemRead, 0, len(created)),
+		IDs:     make(map[uint]uint, len(created)),
+		Deleted: deleted,
+	}
+	for i := range created {
+		response.Items = append(response.Items, newItemRead(created[i]))
+		response.IDs[doc.Items[i].ID] = created[i].ID
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// Read whether an import adds to the board or replaces it
+func importMode(c *fiber.Ctx) (bool, error) {
+	switch c.Query("mode", "merge") {
+	case "merge":
+		return false, nil
+	case "replace":
+		return true, nil
+	}
+	return false, fiber.NewError(fiber.StatusBadRequest, "invalid mode, expected merge or replace")
+}
+
+// An item to import, with the name its errors are reported under
+type importedItem struct {
+	label string
+	state models.ItemCreate
+}
+
+// Add items to a workspace under new ids, in order, as one change that can
+// be undone. Image items show the image of images under their asset id. When
+// replacing, the items on the board are moved to the trash first, after a
+// snapshot of the board. Returns the items created and the ids of those
+// trashed.
+func importItems(c *fiber.Ctx, workspaceID uint, replace bool, items []importedItem, images map[string]*imaging.Result) ([]schemas.Item, []uint, error) {
+	userID, _ := c.Locals(middleware.IDKey).(uint)
+	created := make([]schemas.Item, 0, len(items))
+	deleted := []uint{}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var changes []itemChange
+		if replace {
+			// The board as it is now can be restored in turn
+			if _, err := takeSnapshot(tx, workspaceID, "Before import", true); err != nil {
+				return err
+			}
+			var live []uint
+			if err := tx.Model(&schemas.Item{}).
+				Where("workspace_id = ?", workspaceID).
+				Order("id").
+				Pluck("id", &live).Error; err != nil {
+				return err
+			}
+			for _, id := range live {
+				change, err := deleteItem(tx, workspaceID, id, nil)
+				if err != nil {
+					return err
+				}
+				changes = append(changes, change)
+				deleted = append(deleted, id)
+			}
+		} else if err := autoSnapshot(c.Context(), tx, workspaceID); err != nil {
+			return err
+		}
+
+		// Stored in a stable order so that identical imports match
+		imageIDs := make([]string, 0, len(images))
+		for id := range images {
+			imageIDs = append(imageIDs, id)
+		}
+		sort.Strings(imageIDs)
+		assetIDs := make(map[string]string, len(images))
+		for _, id := range imageIDs {
+			asset, err := storeImage(c.Context(), tx, images[id], userID, workspaceID)
+			if err != nil {
+				return err
+			}
+			assetIDs[id] = asset.ID
+		}
+
+		if len(items) == 0 {
+			return recordHistory(tx, workspaceID, userID, changes)
+		}
+		firstID, err := schemas.AllocateItemIDs(tx, workspaceID, uint(len(items)))
+		if err != nil {
+			return err
+		}
+		for i := range items {
+			state := items[i].state
+			if state.ImageItem != nil {
+				assetID, ok := assetIDs[state.ImageItem.AssetID]
+				if !ok {
+					return fiber.NewError(fiber.StatusBadRequest,
+						fmt.Sprintf("%s: image %s is missing", items[i].label, state.ImageItem.AssetID))
+				}
+				state.ImageItem = &models.ImageItemCreate{AssetID: assetID}
+			}
+
+			item, imageAsset, err := newItem(tx, workspaceID, userID, &state)
+			var invalid *fiber.Error
+			if errors.As(err, &invalid) {
+				return fiber.NewError(invalid.Code, fmt.Sprintf("%s: %s", items[i].label, invalid.Message))
+			}
+			if err != nil {
+				return err
+			}
+			item.ID = firstID + uint(i)
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+			if imageAsset != nil {
+				item.ImageItem.Asset = imageAsset
+			}
+
+			changes = append(changes, newItemChange(nil, &item))
+			created = append(created, item)
+		}
+		// The import is undone as a whole
+		return recordHistory(tx, workspaceID, userID, changes)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, id := range deleted {
+		publishItemEvent(realtime.ItemDeleted, workspaceID, id, nil)
+	}
+	for i := range created {
+		publishItemEvent(realtime.ItemCreated, workspaceID, created[i].ID, &created[i])
+	}
+	return created, deleted, nil
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/imaging"
+	"backend/internal/models"
+	"backend/internal/storage"
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMyWorkspaceDocument(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	store, err := storage.NewFileStore(t.TempDir())
+	assert.NoError(t, err)
+	storage.Default = store
+
+	alice := &schemas.User{Login: "alice", PasswordHash: "hashedpassword"}
+	bob := &schemas.User{Login: "bob", PasswordHash: "hashedpassword"}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, alice))
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, bob))
+
+	var encoded bytes.Buffer
+	assert.NoError(t, png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 300, 200))))
+	processed, err := imaging.Process(encoded.Bytes())
+	assert.NoError(t, err)
+	asset, err := storeImage(context.Background(), database.DB, processed, alice.ID, alice.WorkspaceID)
+	assert.NoError(t, err)
+
+	items := []schemas.Item{
+		{WorkspaceID: alice.WorkspaceID, PositionX: 10, Width: 100, Height: 40, Scale: 1, ZIndex: 2, Color: "#FFEE00",
+			TextItem: &schemas.TextItem{Content: "Plan"}},
+		{WorkspaceID: alice.WorkspaceID, Width: 100, Height: 60, Scale: 1,
+			ListItem: &schemas.TodoListItem{TodoListFields: []schemas.TodoListField{{Content: "Ship", Done: true}, {Content: "Rest"}}}},
+		{WorkspaceID: alice.WorkspaceID, Width: 10, Height: 10, Scale: 2,
+			DrawingItem: &schemas.DrawingItem{Points: []schemas.Point{{X: 0, Y: 0}, {X: 10, Y: 10}}}},
+		{WorkspaceID: alice.WorkspaceID, Width: 30, Height: 20, Scale: 1,
+			ImageItem: &schemas.ImageItem{AssetID: asset.ID}},
+	}
+	for i := range items {
+		assert.NoError(t, database.DB.Create(&items[i]).Error)
+	}
+
+	newApp := func(userID uint) *fiber.App {
+		app := fiber.New()
+		app.Use(mockAuthMiddleware(userID))
+		app.Get("/workspaces/my/export.json", ExportMyWorkspaceJSON)
+		app.Post("/workspaces/my/import", ImportMyWorkspace)
+		app.Get("/workspaces/my/items", ListMyWorkspaceItems)
+		return app
+	}
+	importDocument := func(app *fiber.App, query string, doc any) *http.Response {
+		body, _ := json.Marshal(doc)
+		req := httptest.NewRequest("POST", "/workspaces/my/import"+query, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+	listItems := func(app *fiber.App) []models.ItemRead {
+		resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/items", nil))
+		assert.NoError(t, err)
+		var itemReads []models.ItemRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&itemReads))
+		return itemReads
+	}
+
+	var doc models.WorkspaceDocument
+	t.Run("Export", func(t *testing.T) {
+		resp, err := newApp(alice.ID).Test(httptest.NewRequest("GET", "/workspaces/my/export.json", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get(fiber.HeaderContentDisposition), "attachment")
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+
+		assert.Equal(t, "prodspace-workspace", doc.Format)
+		assert.Equal(t, 1, doc.Version)
+		assert.Equal(t, 4, len(doc.Items))
+		assert.Equal(t, items[0].ID, doc.Items[0].ID)
+		assert.Equal(t, "Plan", doc.Items[0].TextItem.Content)
+		assert.Equal(t, 2, len(*doc.Items[1].TodoList))
+		assert.Equal(t, 2, len(doc.Items[2].DrawingItem.Points))
+		if assert.Equal(t, 1, len(doc.Images)) {
+			assert.Equal(t, asset.ID, doc.Images[0].ID)
+			assert.Equal(t, processed.Original.Data, doc.Images[0].Data, "The original should be embedded")
+		}
+	})
+
+	t.Run("Merge into another account", func(t *testing.T) {
+		app := newApp(bob.ID)
+		assert.NoError(t, database.DB.Create(&schemas.Item{
+			WorkspaceID: bob.WorkspaceID, Width: 10, Height: 10, Scale: 1,
+			ShapeItem: &schemas.ShapeItem{Name: "circle"},
+		}).Error)
+
+		resp := importDocument(app, "", doc)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var result models.ImportRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.Equal(t, 4, len(result.Items))
+		assert.Empty(t, result.Deleted)
+		for i, itemRead := range result.Items {
+			assert.Equal(t, itemRead.ID, result.IDs[doc.Items[i].ID])
+			assert.Equal(t, bob.WorkspaceID, itemRead.WorkspaceID)
+		}
+		assert.Equal(t, uint(2), result.Items[0].ID, "Ids should continue after the existing item")
+		assert.Equal(t, "Plan", result.Items[0].TextItem.Content)
+		assert.Equal(t, uint(2), result.Items[0].ZIndex)
+		assert.Equal(t, "Ship", result.Items[1].TodoListItem[0].TextItemRead.Content)
+		assert.True(t, result.Items[1].TodoListItem[0].Done)
+
+		if assert.NotNil(t, result.Items[3].ImageItem) {
+			imported := result.Items[3].ImageItem.AssetID
+			assert.NotEqual(t, asset.ID, imported, "Images should be copied into the workspace")
+			var copied schemas.Asset
+			assert.NoError(t, database.DB.First(&copied, "id = ?", imported).Error)
+			assert.Equal(t, bob.WorkspaceID, copied.WorkspaceID)
+			assert.Equal(t, asset.BlobHash, copied.BlobHash, "Identical images should share their blob")
+		}
+		assert.Equal(t, 5, len(listItems(app)))
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		app := newApp(bob.ID)
+		before := listItems(app)
+
+		resp := importDocument(app, "?mode=replace", doc)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var result models.ImportRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.Equal(t, len(before), len(result.Deleted))
+
+		var imported, after []uint
+		for _, itemRead := range result.Items {
+			imported = append(imported, itemRead.ID)
+		}
+		for _, itemRead := range listItems(app) {
+			after = append(after, itemRead.ID)
+		}
+		assert.ElementsMatch(t, imported, after, "Only the imported items should be left")
+
+		var trashed int64
+		database.DB.Unscoped().Model(&schemas.Item{}).
+			Where("workspace_id = ? AND deleted_at IS NOT NULL", bob.WorkspaceID).
+			Count(&trashed)
+		assert.Equal(t, int64(len(before)), trashed, "Replaced items should go to the trash")
+
+		var snapshot schemas.WorkspaceSnapshot
+		assert.NoError(t, database.DB.Where("workspace_id = ?", bob.WorkspaceID).Last(&snapshot).Error)
+		assert.Equal(t, len(before), snapshot.ItemCount, "The replaced board should be snapshotted")
+	})
+
+	t.Run("Invalid documents", func(t *testing.T) {
+		app := newApp(bob.ID)
+		count := len(listItems(app))
+
+		newer := doc
+		newer.Version = 2
+		foreign := doc
+		foreign.Format = "excalidraw"
+		missingImage := doc
+		missingImage.Images = nil
+		emptyText := doc
+		emptyText.Items = []models.DocumentItem{{ID: 7, ItemCreate: models.ItemCreate{TextItem: &models.TextItemCreate{}}}}
+		duplicate := doc
+		duplicate.Items = append([]models.DocumentItem{doc.Items[0]}, doc.Items...)
+		brokenImage := doc
+		brokenImage.Images = []models.DocumentImage{{ID: asset.ID, Data: []byte("not an image")}}
+
+		for name, invalid := range map[string]models.WorkspaceDocument{
+			"newer version": newer,
+			"other format":  foreign,
+			"missing image": missingImage,
+			"empty text":    emptyText,
+			"duplicate id":  duplicate,
+			"broken image":  brokenImage,
+		} {
+			resp := importDocument(app, "?mode=replace", invalid)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, name)
+		}
+
+		resp := importDocument(app, "?mode=overwrite", doc)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, count, len(listItems(app)), "Failed imports should change nothing")
+	})
+}
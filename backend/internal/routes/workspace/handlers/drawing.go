@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"backend/config"
+	"backend/internal/database/schemas"
+	"backend/internal/models"
+	"cmp"
+	"encoding/base64"
+	"math"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// How far, in the units of a drawing, a stroke may be simplified away from
+// the points it was drawn with; 0 keeps every point
+var strokeTolerance = config.C.StrokeTolerance
+
+// Style of strokes that set none, as the app draws them
+const (
+	defaultStrokeWidth = 3
+	defaultOpacity     = 1
+	defaultLineCap     = "round"
+	defaultLineJoin    = "round"
+	defaultSmoothing   = "none"
+
+	maxStrokeWidth = 1000
+)
+
+var (
+	lineCaps   = map[string]bool{"round": true, "butt": true, "square": true}
+	lineJoins  = map[string]bool{"round": true, "miter": true, "bevel": true}
+	smoothings = map[string]bool{"none": true, "quadratic": true, "catmull-rom": true}
+)
+
+// Check the style and pressures of a stroke to create or update. Style
+// fields left at zero take their default or current value.
+func validateStroke(drawing *models.DrawingItemCreate) error {
+	switch {
+	case !(drawing.StrokeWidth >= 0 && drawing.StrokeWidth <= maxStrokeWidth):
+		return fiber.NewError(fiber.StatusBadRequest, "invalid stroke_width, expected up to 1000")
+	case !(drawing.Opacity >= 0 && drawing.Opacity <= 1):
+		return fiber.NewError(fiber.StatusBadRequest, "invalid opacity, expected up to 1; 0 keeps the default or current opacity")
+	case drawing.LineCap != "" && !lineCaps[drawing.LineCap]:
+		return fiber.NewError(fiber.StatusBadRequest, "invalid line_cap, expected round, butt or square")
+	case drawing.LineJoin != "" && !lineJoins[drawing.LineJoin]:
+		return fiber.NewError(fiber.StatusBadRequest, "invalid line_join, expected round, miter or bevel")
+	case drawing.Smoothing != "" && !smoothings[drawing.Smoothing]:
+		return fiber.NewError(fiber.StatusBadRequest, "invalid smoothing, expected none, quadratic or catmull-rom")
+	}
+
+	pressed := 0
+	for _, p := range drawing.Points {
+		if !(p.Pressure >= 0 && p.Pressure <= 1) {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid pressure, expected 0 to 1")
+		}
+		if p.Pressure != 0 {
+			pressed++
+		}
+	}
+	if pressed != 0 && pressed != len(drawing.Points) {
+		return fiber.NewError(fiber.StatusBadRequest, "pressure must be given for every point of a stroke or for none")
+	}
+	return nil
+}
+
+// Build a stroke as it was drawn, in the default style where it sets none
+func newDrawingItem(drawing *models.DrawingItemCreate) *schemas.DrawingItem {
+	return &schemas.DrawingItem{
+		Points:      newPoints(drawing.Points),
+		StrokeWidth: cmp.Or(drawing.StrokeWidth, defaultStrokeWidth),
+		Opacity:     cmp.Or(drawing.Opacity, defaultOpacity),
+		LineCap:     cmp.Or(drawing.LineCap, defaultLineCap),
+		LineJoin:    cmp.Or(drawing.LineJoin, defaultLineJoin),
+		Smoothing:   cmp.Or(drawing.Smoothing, defaultSmoothing),
+	}
+}
+
+// Apply an update to a stroke: its points are replaced, simplified, if the
+// update has some, and so is every style field it sets
+func updateDrawingItem(item *schemas.DrawingItem, drawing *models.DrawingItemCreate) {
+	if drawing.Points != nil {
+		item.Points = newStroke(drawing.Points)
+	}
+	item.StrokeWidth = cmp.Or(drawing.StrokeWidth, item.StrokeWidth)
+	item.Opacity = cmp.Or(drawing.Opacity, item.Opacity)
+	item.LineCap = cmp.Or(drawing.LineCap, item.LineCap)
+	item.LineJoin = cmp.Or(drawing.LineJoin, item.LineJoin)
+	item.Smoothing = cmp.Or(drawing.Smoothing, item.Smoothing)
+}
+
+// Build the points of a stroke as they were drawn. Pressures are kept to the
+// thousandth, which packs smaller, and stay above 0 so that a stroke keeps
+// them for every point.
+func newPoints(pointCreates []models.DrawingPointCreate) schemas.Points {
+	points := make(schemas.Points, 0, len(pointCreates))
+	for _, p := range pointCreates {
+		point := schemas.Point{
+			X: p.X,
+			Y: p.Y,
+		}
+		if p.Pressure != 0 {
+			point.Pressure = max(math.Round(p.Pressure*1000)/1000, 0.001)
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// Build the points of a new stroke, simplified to the stroke tolerance
+func newStroke(pointCreates []models.DrawingPointCreate) schemas.Points {
+	return newPoints(pointCreates).Simplify(strokeTolerance)
+}
+
+// Convert a stroke to the response model
+func newDrawingItemRead(item *schemas.DrawingItem) *models.DrawingItemRead {
+	points := make([]models.DrawingPointRead, 0, len(item.Points))
+	for _, p := range item.Points {
+		points = append(points, models.DrawingPointRead{
+			X:        p.X,
+			Y:        p.Y,
+			Pressure: p.Pressure,
+		})
+	}
+	return &models.DrawingItemRead{
+		Points:      points,
+		StrokeWidth: item.StrokeWidth,
+		Opacity:     item.Opacity,
+		LineCap:     item.LineCap,
+		LineJoin:    item.LineJoin,
+		Smoothing:   item.Smoothing,
+	}
+}
+
+// Read how the client wants the points of drawings: as objects, or packed
+// with points=packed
+func packedPointsQuery(c *fiber.Ctx) (bool, error) {
+	switch c.Query("points", "objects") {
+	case "objects":
+		return false, nil
+	case "packed":
+		return true, nil
+	}
+	return false, fiber.NewError(fiber.StatusBadRequest, "invalid points, expected objects or packed")
+}
+
+// Convert items with their preloaded sub-records to the response model,
+// sending the points of drawings packed as they are stored if asked to
+func newItemReads(items []schemas.Item, packed bool) []models.ItemRead {
+	itemReads := make([]models.ItemRead, 0, len(items))
+	for _, item := range items {
+		itemRead := newItemRead(item)
+		if packed && item.DrawingItem != nil {
+			itemRead.DrawingItem.Points = nil
+			itemRead.DrawingItem.Packed = base64.StdEncoding.EncodeToString(schemas.EncodePoints(item.DrawingItem.Points))
+		}
+		itemReads = append(itemReads, itemRead)
+	}
+	return itemReads
+}
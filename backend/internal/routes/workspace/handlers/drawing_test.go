@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/models"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrawingPoints(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+
+	tolerance := strokeTolerance
+	strokeTolerance = 0.5
+	defer func() { strokeTolerance = tolerance }()
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Post("/workspaces/my/items", AppendMyWorkspaceItem)
+	app.Get("/workspaces/my/items", ListMyWorkspaceItems)
+
+	// A shaky straight line, then a corner
+	stroke := []models.DrawingPointCreate{
+		{X: 0, Y: 0}, {X: 2, Y: 0.1}, {X: 4, Y: -0.2}, {X: 6, Y: 0.1}, {X: 8, Y: 0},
+		{X: 8.1, Y: 4}, {X: 7.9, Y: 8.25},
+	}
+	body, _ := json.Marshal(models.ItemCreate{DrawingItem: &models.DrawingItemCreate{Points: stroke}})
+	req := httptest.NewRequest("POST", "/workspaces/my/items", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	simplified := schemas.Points{{X: 0, Y: 0}, {X: 8, Y: 0}, {X: 7.9, Y: 8.25}}
+
+	t.Run("Points are simplified when stored", func(t *testing.T) {
+		var drawing schemas.DrawingItem
+		assert.NoError(t, database.DB.First(&drawing).Error)
+		assert.Equal(t, simplified, drawing.Points)
+	})
+
+	t.Run("Points as objects", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/items", nil))
+		assert.NoError(t, err)
+		var itemReads []models.ItemRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&itemReads))
+		if assert.Equal(t, 1, len(itemReads)) && assert.NotNil(t, itemReads[0].DrawingItem) {
+			assert.Equal(t, 3, len(itemReads[0].DrawingItem.Points))
+			assert.Equal(t, 8.25, itemReads[0].DrawingItem.Points[2].Y)
+			assert.Empty(t, itemReads[0].DrawingItem.Packed)
+		}
+	})
+
+	t.Run("Packed points", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/items?points=packed", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var itemReads []models.ItemRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&itemReads))
+		if assert.Equal(t, 1, len(itemReads)) && assert.NotNil(t, itemReads[0].DrawingItem) {
+			assert.Nil(t, itemReads[0].DrawingItem.Points)
+			data, err := base64.StdEncoding.DecodeString(itemReads[0].DrawingItem.Packed)
+			assert.NoError(t, err)
+			points, err := schemas.DecodePoints(data)
+			assert.NoError(t, err)
+			assert.Equal(t, simplified, points)
+		}
+	})
+
+	t.Run("Unknown form", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/items?points=svg", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestDrawingStyle(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Post("/workspaces/my/items", AppendMyWorkspaceItem)
+	app.Patch("/workspaces/my/items/:item_id", UpdateMyWorkspaceItem)
+	app.Get("/workspaces/my/items", ListMyWorkspaceItems)
+
+	send := func(method, url string, payload any) *http.Response {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+	readDrawings := func(query string) []models.DrawingItemRead {
+		resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/items"+query, nil))
+		assert.NoError(t, err)
+		var itemReads []models.ItemRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&itemReads))
+		drawings := make([]models.DrawingItemRead, 0, len(itemReads))
+		for _, item := range itemReads {
+			if assert.NotNil(t, item.DrawingItem) {
+				drawings = append(drawings, *item.DrawingItem)
+			}
+		}
+		return drawings
+	}
+
+	// A highlighter stroke, and a pen stroke in the default style
+	highlighter := models.DrawingItemCreate{
+		Points:      []models.DrawingPointCreate{{X: 0, Y: 0, Pressure: 0.25}, {X: 10, Y: 10, Pressure: 0.0001}},
+		StrokeWidth: 20,
+		Opacity:     0.4,
+		LineCap:     "butt",
+		LineJoin:    "bevel",
+		Smoothing:   "catmull-rom",
+	}
+	resp := send("POST", "/workspaces/my/items", models.ItemCreate{DrawingItem: &highlighter})
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp = send("POST", "/workspaces/my/items", models.ItemCreate{DrawingItem: &models.DrawingItemCreate{
+		Points: []models.DrawingPointCreate{{X: 1, Y: 1}},
+	}})
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	drawings := readDrawings("")
+	if assert.Equal(t, 2, len(drawings)) {
+		assert.Equal(t, models.DrawingItemRead{
+			Points:      []models.DrawingPointRead{{X: 0, Y: 0, Pressure: 0.25}, {X: 10, Y: 10, Pressure: 0.001}},
+			StrokeWidth: 20,
+			Opacity:     0.4,
+			LineCap:     "butt",
+			LineJoin:    "bevel",
+			Smoothing:   "catmull-rom",
+		}, drawings[0], "Pressures should be kept to the thousandth, above 0")
+		assert.Equal(t, models.DrawingItemRead{
+			Points:      []models.DrawingPointRead{{X: 1, Y: 1}},
+			StrokeWidth: 3,
+			Opacity:     1,
+			LineCap:     "round",
+			LineJoin:    "round",
+			Smoothing:   "none",
+		}, drawings[1], "Strokes without a style should get the default one")
+	}
+
+	t.Run("Packed points keep the style", func(t *testing.T) {
+		drawings := readDrawings("?points=packed")
+		if assert.Equal(t, 2, len(drawings)) {
+			assert.Equal(t, 20.0, drawings[0].StrokeWidth)
+			data, err := base64.StdEncoding.DecodeString(drawings[0].Packed)
+			assert.NoError(t, err)
+			points, err := schemas.DecodePoints(data)
+			assert.NoError(t, err)
+			assert.Equal(t, schemas.Points{{X: 0, Y: 0, Pressure: 0.25}, {X: 10, Y: 10, Pressure: 0.001}}, points)
+		}
+	})
+
+	t.Run("Invalid styles", func(t *testing.T) {
+		for name, drawing := range map[string]models.DrawingItemCreate{
+			"negative width":    {StrokeWidth: -1},
+			"too wide":          {StrokeWidth: 1001},
+			"opacity above 1":   {Opacity: 1.5},
+			"negative opacity":  {Opacity: -0.5},
+			"unknown cap":       {LineCap: "arrow"},
+			"unknown join":      {LineJoin: "rounded"},
+			"unknown smoothing": {Smoothing: "bezier"},
+			"pressure above 1":  {Points: []models.DrawingPointCreate{{X: 0, Y: 0, Pressure: 2}}},
+			"partial pressure":  {Points: []models.DrawingPointCreate{{X: 0, Y: 0, Pressure: 0.5}, {X: 1, Y: 1}}},
+		} {
+			resp := send("POST", "/workspaces/my/items", models.ItemCreate{DrawingItem: &drawing})
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, name)
+			resp = send("PATCH", "/workspaces/my/items/1", models.ItemUpdate{DrawingItem: &drawing})
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, name)
+		}
+	})
+
+	t.Run("Updates keep what they leave out", func(t *testing.T) {
+		resp := send("PATCH", "/workspaces/my/items/1", models.ItemUpdate{DrawingItem: &models.DrawingItemCreate{Opacity: 1}})
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var itemRead models.ItemRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&itemRead))
+		if assert.NotNil(t, itemRead.DrawingItem) {
+			assert.Equal(t, 2, len(itemRead.DrawingItem.Points))
+			assert.Equal(t, 1.0, itemRead.DrawingItem.Opacity)
+			assert.Equal(t, 20.0, itemRead.DrawingItem.StrokeWidth)
+			assert.Equal(t, "butt", itemRead.DrawingItem.LineCap)
+		}
+
+		resp = send("PATCH", "/workspaces/my/items/1", models.ItemUpdate{DrawingItem: &models.DrawingItemCreate{
+			Points: []models.DrawingPointCreate{{X: 5, Y: 5}},
+		}})
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var updated models.ItemRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&updated))
+		if assert.NotNil(t, updated.DrawingItem) {
+			assert.Equal(t, []models.DrawingPointRead{{X: 5, Y: 5}}, updated.DrawingItem.Points)
+			assert.Equal(t, "catmull-rom", updated.DrawingItem.Smoothing)
+		}
+	})
+}
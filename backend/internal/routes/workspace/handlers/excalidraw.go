@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"backend/internal/excalidraw"
+	"backend/internal/export"
+	"backend/internal/imaging"
+	middleware "backend/internal/middlewares"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary Export a workspace as an Excalidraw scene
+// @Description Write the board as a .excalidraw file: shapes as rectangles and ellipses,
+// @Description text items and todo lists as rectangles with their text, drawings as
+// @Description free drawings and images with their files embedded
+// @Tags workspaces
+// @Produce application/vnd.excalidraw+json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param bbox query string false "Region as minX,minY,maxX,maxY"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/export.excalidraw [get]
+func ExportWorkspaceExcalidraw(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return exportWorkspaceExcalidraw(c, uint(workspaceID))
+}
+
+// @Summary Export the user's workspace as an Excalidraw scene
+// @Description Write the board as a .excalidraw file: shapes as rectangles and ellipses,
+// @Description text items and todo lists as rectangles with their text, drawings as
+// @Description free drawings and images with their files embedded
+// @Tags workspaces
+// @Produce application/vnd.excalidraw+json
+// @Security BearerAuth
+// @Param bbox query string false "Region as minX,minY,maxX,maxY"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/export.excalidraw [get]
+func ExportMyWorkspaceExcalidraw(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return exportWorkspaceExcalidraw(c, workspaceID)
+}
+
+func exportWorkspaceExcalidraw(c *fiber.Ctx, workspaceID uint) error {
+	board, err := exportBoard(c, workspaceID)
+	if err != nil {
+		return errorResponse(c, err, "failed to load workspace")
+	}
+
+	scene := excalidraw.FromItems(c.Context(), board.Items, export.StoredImage)
+
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="workspace-%d.excalidraw"`, workspaceID))
+	if err := c.Status(fiber.StatusOK).JSON(scene); err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, "application/vnd.excalidraw+json")
+	return nil
+}
+
+// @Summary Import an Excalidraw scene into a workspace
+// @Description Add the elements of a .excalidraw file as items: rectangles and ellipses as
+// @Description shapes, text as text items, free drawings and lines as drawings and images
+// @Description with their files. Elements that cannot be imported, or only in part, are
+// @Description listed in unsupported. In replace mode the items already on the board are
+// @Description moved to the trash first, after a snapshot of the board. Requires the editor role.
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param mode query string false "merge or replace" Enums(merge, replace) default(merge)
+// @Param scene body object true "Excalidraw scene"
+// @Success 200 {object} models.ExcalidrawImportRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/import/excalidraw [post]
+func ImportWorkspaceExcalidraw(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return importWorkspaceExcalidraw(c, uint(workspaceID))
+}
+
+// @Summary Import an Excalidraw scene into the user's workspace
+// @Description Add the elements of a .excalidraw file as items: rectangles and ellipses as
+// @Description shapes, text as text items, free drawings and lines as drawings and images
+// @Description with their files. Elements that cannot be imported, or only in part, are
+// @Description listed in unsupported. In replace mode the items already on the board are
+// @Description moved to the trash first, after a snapshot of the board.
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param mode query string false "merge or replace" Enums(merge, replace) default(merge)
+// @Param scene body object true "Excalidraw scene"
+// @Success 200 {object} models.ExcalidrawImportRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/import/excalidraw [post]
+func ImportMyWorkspaceExcalidraw(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return importWorkspaceExcalidraw(c, workspaceID)
+}
+
+func importWorkspaceExcalidraw(c *fiber.Ctx, workspaceID uint) error {
+	replace, err := importMode(c)
+	if err != nil {
+		return errorResponse(c, err, "failed to import scene")
+	}
+
+	var scene excalidraw.Scene
+	if err := c.BodyParser(&scene); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid request body",
+		})
+	}
+	if err := scene.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	converted, unsupported := excalidraw.ToItems(scene)
+
+	// Decoding images is slow, so it is done before the transaction. Images
+	// that cannot be read are left out like other unsupported elements.
+	images := make(map[string]*imaging.Result)
+	unreadable := make(map[string]error)
+	items := make([]importedItem, 0, len(converted))
+	elementIDs := make([]string, 0, len(converted))
+	for _, item := range converted {
+		if item.ImageItem != nil {
+			fileID := item.ImageItem.AssetID
+			if images[fileID] == nil && unreadable[fileID] == nil {
+				if processed, err := processFile(scene.Files[fileID]); err != nil {
+					unreadable[fileID] = err
+				} else {
+					images[fileID] = processed
+				}
+			}
+			if err := unreadable[fileID]; err != nil {
+				unsupported = append(unsupported, excalidraw.Unsupported{
+					ElementID: item.ElementID, Type: "image", Reason: err.Error(), Skipped: true,
+				})
+				continue
+			}
+		}
+		items = append(items, importedItem{label: "element " + item.ElementID, state: item.ItemCreate})
+		elementIDs = append(elementIDs, item.ElementID)
+	}
+
+	created, deleted, err := importItems(c, workspaceID, replace, items, images)
+	if err != nil {
+		return errorResponse(c, err, "failed to import scene")
+	}
+
+	response := models.ExcalidrawImportRead{
+		Items:       make([]models.ItemRead, 0, len(created)),
+		IDs:         make(map[string]uint, len(created)),
+		Deleted:     deleted,
+		Unsupported: make([]models.UnsupportedElementRead, 0, len(unsupported)),
+	}
+	for i := range created {
+		response.Items = append(response.Items, newItemRead(created[i]))
+		response.IDs[elementIDs[i]] = created[i].ID
+	}
+	for _, u := range unsupported {
+		response.Unsupported = append(response.Unsupported, models.UnsupportedElementRead{
+			ElementID: u.ElementID,
+			Type:      u.Type,
+			Reason:    u.Reason,
+			Skipped:   u.Skipped,
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// Decode and validate an embedded file like an upload
+func processFile(file excalidraw.File) (*imaging.Result, error) {
+	data, err := file.Data()
+	if err != nil {
+		return nil, fmt.Errorf("image file is not readable: %w", err)
+	}
+	processed, err := imaging.Process(data)
+	if err != nil {
+		return nil, errors.New("image file is not a supported image")
+	}
+	return processed, nil
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/excalidraw"
+	"backend/internal/models"
+	"backend/internal/storage"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMyWorkspaceExcalidraw(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	store, err := storage.NewFileStore(t.TempDir())
+	assert.NoError(t, err)
+	storage.Default = store
+
+	user := &schemas.User{Login: "alice", PasswordHash: "hashedpassword"}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Get("/workspaces/my/export.excalidraw", ExportMyWorkspaceExcalidraw)
+	app.Post("/workspaces/my/import/excalidraw", ImportMyWorkspaceExcalidraw)
+	app.Get("/workspaces/my/items", ListMyWorkspaceItems)
+
+	importScene := func(query string, scene string) *http.Response {
+		req := httptest.NewRequest("POST", "/workspaces/my/import/excalidraw"+query, bytes.NewReader([]byte(scene)))
+		req.Header.Set("Content-Type", "application/vnd.excalidraw+json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	var encoded bytes.Buffer
+	assert.NoError(t, png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 30, 20))))
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(encoded.Bytes())
+
+	t.Run("Import", func(t *testing.T) {
+		resp := importScene("", `{
+			"type": "excalidraw", "version": 2,
+			"elements": [
+				{"id": "box", "type": "rectangle", "x": 0, "y": 0, "width": 100, "height": 50,
+					"backgroundColor": "#ffc9c9", "opacity": 100},
+				{"id": "hello", "type": "text", "x": 0, "y": 80, "width": 50, "height": 20, "opacity": 100,
+					"text": "Hello", "fontSize": 16},
+				{"id": "photo", "type": "image", "x": 200, "y": 0, "width": 30, "height": 20, "opacity": 100,
+					"fileId": "f1"},
+				{"id": "broken", "type": "image", "x": 200, "y": 0, "width": 30, "height": 20, "opacity": 100,
+					"fileId": "f2"},
+				{"id": "pointer", "type": "arrow", "x": 0, "y": 0, "width": 30, "height": 20, "opacity": 100}
+			],
+			"files": {
+				"f1": {"id": "f1", "mimeType": "image/png", "dataURL": "`+dataURL+`"},
+				"f2": {"id": "f2", "mimeType": "image/png", "dataURL": "data:image/png;base64,bm90IGFuIGltYWdl"}
+			}
+		}`)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result models.ExcalidrawImportRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		if !assert.Equal(t, 3, len(result.Items)) {
+			return
+		}
+		assert.Equal(t, "rectangle", result.Items[0].ShapeItem.Name)
+		assert.Equal(t, "Hello", result.Items[1].TextItem.Content)
+		assert.NotEmpty(t, result.Items[2].ImageItem.AssetID)
+		assert.Equal(t, result.Items[2].ID, result.IDs["photo"])
+
+		skipped := make(map[string]string)
+		for _, u := range result.Unsupported {
+			skipped[u.ElementID] = u.Reason
+		}
+		assert.Equal(t, map[string]string{
+			"broken":  "image file is not a supported image",
+			"pointer": "arrow elements are not supported",
+		}, skipped)
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/export.excalidraw", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/vnd.excalidraw+json", resp.Header.Get(fiber.HeaderContentType))
+		assert.Contains(t, resp.Header.Get(fiber.HeaderContentDisposition), ".excalidraw")
+
+		var scene excalidraw.Scene
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&scene))
+		assert.NoError(t, scene.Validate())
+		var types []string
+		for _, e := range scene.Elements {
+			types = append(types, e.Type)
+		}
+		assert.Equal(t, []string{"rectangle", "rectangle", "text", "image"}, types)
+		assert.Equal(t, 1, len(scene.Files))
+	})
+
+	t.Run("Invalid scenes", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/items", nil))
+		assert.NoError(t, err)
+		var before []models.ItemRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&before))
+
+		assert.Equal(t, fiber.StatusBadRequest, importScene("", `{"type": "prodspace-workspace", "elements": []}`).StatusCode)
+		assert.Equal(t, fiber.StatusBadRequest, importScene("", `{"type": "excalidraw", "elements": {}}`).StatusCode)
+		assert.Equal(t, fiber.StatusBadRequest, importScene("?mode=overwrite", `{"type": "excalidraw", "elements": []}`).StatusCode)
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/workspaces/my/items", nil))
+		assert.NoError(t, err)
+		var after []models.ItemRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&after))
+		assert.Equal(t, len(before), len(after), "Failed imports should change nothing")
+	})
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/export"
+	middleware "backend/internal/middlewares"
+	"backend/internal/models"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Largest pixel density of PNG exports
+const maxExportScale = 4
+
+// How a board is written in one of the export formats
+type exportFormat struct {
+	contentType string
+	extension   string
+	write       func(ctx context.Context, w io.Writer, board export.Board) error
+}
+
+// Load the items of a workspace laid out for export. With the bbox query
+// parameter only the items in that region are kept and the board is
+// cropped to it.
+func exportBoard(c *fiber.Ctx, workspaceID uint) (export.Board, error) {
+	query := preloadItemRecords(database.DB, "").Where("workspace_id = ?", workspaceID)
+
+	bbox := c.Query("bbox")
+	var minX, minY, maxX, maxY float64
+	if bbox != "" {
+		var err error
+		minX, minY, maxX, maxY, err = parseBBox(bbox)
+		if err == nil && (minX == maxX || minY == maxY) {
+			err = errors.New("region must not be empty")
+		}
+		if err != nil {
+			return export.Board{}, fiber.NewError(fiber.StatusBadRequest, "invalid bbox: "+err.Error())
+		}
+		query = query.Where(
+			"min_x <= ? AND max_x >= ? AND min_y <= ? AND max_y >= ?",
+			maxX, minX, maxY, minY,
+		)
+	}
+
+	var items []schemas.Item
+	if err := query.Find(&items).Error; err != nil {
+		return export.Board{}, err
+	}
+	board := export.NewBoard(items)
+	if bbox != "" {
+		board = board.Crop(minX, minY, maxX, maxY)
+	}
+	return board, nil
+}
+
+func exportWorkspace(c *fiber.Ctx, workspaceID uint, format exportFormat) error {
+	board, err := exportBoard(c, workspaceID)
+	if err != nil {
+		return errorResponse(c, err, "failed to load workspace")
+	}
+
+	var out bytes.Buffer
+	if err := format.write(c.Context(), &out, board); err != nil {
+		return errorResponse(c, err, "failed to export workspace")
+	}
+
+	c.Set(fiber.HeaderContentType, format.contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="workspace-%d.%s"`, workspaceID, format.extension))
+	return c.Status(fiber.StatusOK).Send(out.Bytes())
+}
+
+// @Summary Export a workspace as an SVG image
+// @Description Render the whole board, images included, as a standalone SVG document
+// @Tags workspaces
+// @Produce image/svg+xml
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param bbox query string false "Region as minX,minY,maxX,maxY"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/export.svg [get]
+func ExportWorkspaceSVG(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return exportWorkspaceSVG(c, uint(workspaceID))
+}
+
+// @Summary Export the user's workspace as an SVG image
+// @Description Render the whole board, images included, as a standalone SVG document
+// @Tags workspaces
+// @Produce image/svg+xml
+// @Security BearerAuth
+// @Param bbox query string false "Region as minX,minY,maxX,maxY"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/export.svg [get]
+func ExportMyWorkspaceSVG(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return exportWorkspaceSVG(c, workspaceID)
+}
+
+func exportWorkspaceSVG(c *fiber.Ctx, workspaceID uint) error {
+	return exportWorkspace(c, workspaceID, exportFormat{
+		contentType: "image/svg+xml",
+		extension:   "svg",
+		write: func(ctx context.Context, w io.Writer, board export.Board) error {
+			return export.WriteSVG(ctx, w, board, export.StoredImage)
+		},
+	})
+}
+
+// @Summary Export a workspace as a PNG image
+// @Description Rasterize the whole board, or the region in bbox, with scale pixels per unit
+// @Tags workspaces
+// @Produce image/png
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param scale query number false "Pixels per unit, up to 4" default(1)
+// @Param bbox query string false "Region as minX,minY,maxX,maxY"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/export.png [get]
+func ExportWorkspacePNG(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return exportWorkspacePNG(c, uint(workspaceID))
+}
+
+// @Summary Export the user's workspace as a PNG image
+// @Description Rasterize the whole board, or the region in bbox, with scale pixels per unit
+// @Tags workspaces
+// @Produce image/png
+// @Security BearerAuth
+// @Param scale query number false "Pixels per unit, up to 4" default(1)
+// @Param bbox query string false "Region as minX,minY,maxX,maxY"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/export.png [get]
+func ExportMyWorkspacePNG(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return exportWorkspacePNG(c, workspaceID)
+}
+
+func exportWorkspacePNG(c *fiber.Ctx, workspaceID uint) error {
+	scale, err := strconv.ParseFloat(c.Query("scale", "1"), 64)
+	if err != nil || !(scale > 0 && scale <= maxExportScale) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: fmt.Sprintf("invalid scale; expected a number above 0 and up to %d", maxExportScale),
+		})
+	}
+
+	return exportWorkspace(c, workspaceID, exportFormat{
+		contentType: "image/png",
+		extension:   "png",
+		write: func(ctx context.Context, w io.Writer, board export.Board) error {
+			err := export.WritePNG(ctx, w, board, export.StoredImage, scale)
+			if errors.Is(err, export.ErrTooLarge) {
+				return fiber.NewError(fiber.StatusBadRequest, "export is too large; lower the scale or crop it with bbox")
+			}
+			return err
+		},
+	})
+}
+
+// @Summary Export a workspace as a PDF document
+// @Description Render the whole board, or the region in bbox, on a single page
+// @Tags workspaces
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param bbox query string false "Region as minX,minY,maxX,maxY"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/export.pdf [get]
+func ExportWorkspacePDF(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return exportWorkspacePDF(c, uint(workspaceID))
+}
+
+// @Summary Export the user's workspace as a PDF document
+// @Description Render the whole board, or the region in bbox, on a single page
+// @Tags workspaces
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param bbox query string false "Region as minX,minY,maxX,maxY"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/export.pdf [get]
+func ExportMyWorkspacePDF(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return exportWorkspacePDF(c, workspaceID)
+}
+
+func exportWorkspacePDF(c *fiber.Ctx, workspaceID uint) error {
+	return exportWorkspace(c, workspaceID, exportFormat{
+		contentType: "application/pdf",
+		extension:   "pdf",
+		write: func(ctx context.Context, w io.Writer, board export.Board) error {
+			return export.WritePDF(ctx, w, board, export.StoredImage)
+		},
+	})
+}
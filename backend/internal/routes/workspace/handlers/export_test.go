@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"image"
+	"image/png"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportMyWorkspaceSVG(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+
+	items := []schemas.Item{
+		{WorkspaceID: user.WorkspaceID, Width: 100, Height: 40, Scale: 1, TextItem: &schemas.TextItem{Content: "On the board"}},
+		{WorkspaceID: user.WorkspaceID, Width: 100, Height: 40, Scale: 1, TextItem: &schemas.TextItem{Content: "In the trash"}},
+	}
+	for i := range items {
+		assert.NoError(t, database.DB.Create(&items[i]).Error)
+	}
+	_, err := schemas.TrashItem(database.DB, user.WorkspaceID, items[1].ID)
+	assert.NoError(t, err)
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Get("/workspaces/my/export.svg", ExportMyWorkspaceSVG)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/export.svg", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/svg+xml", resp.Header.Get(fiber.HeaderContentType))
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "On the board")
+	assert.NotContains(t, string(body), "In the trash")
+}
+
+func TestExportMyWorkspaceRaster(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+
+	items := []schemas.Item{
+		{WorkspaceID: user.WorkspaceID, PositionX: 0, PositionY: 0, Width: 100, Height: 40, Scale: 1,
+			TextItem: &schemas.TextItem{Content: "Inside"}},
+		{WorkspaceID: user.WorkspaceID, PositionX: 500, PositionY: 500, Width: 100, Height: 40, Scale: 1,
+			TextItem: &schemas.TextItem{Content: "Outside"}},
+	}
+	for i := range items {
+		assert.NoError(t, database.DB.Create(&items[i]).Error)
+	}
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Get("/workspaces/my/export.svg", ExportMyWorkspaceSVG)
+	app.Get("/workspaces/my/export.png", ExportMyWorkspacePNG)
+	app.Get("/workspaces/my/export.pdf", ExportMyWorkspacePDF)
+
+	t.Run("PNG at scale", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/export.png?scale=2&bbox=0,0,200,100", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "image/png", resp.Header.Get(fiber.HeaderContentType))
+
+		img, err := png.Decode(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 400, 200), img.Bounds(), "Export should be cropped to the bbox")
+	})
+
+	t.Run("PDF", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/export.pdf", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/pdf", resp.Header.Get(fiber.HeaderContentType))
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(body), "%PDF-"))
+	})
+
+	t.Run("Crop leaves out other items", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/export.svg?bbox=-10,-10,200,100", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Contains(t, string(body), `viewBox="-10 -10 210 110"`)
+		assert.Contains(t, string(body), "Inside")
+		assert.NotContains(t, string(body), "Outside")
+	})
+
+	for _, query := range []string{
+		"export.png?scale=0",
+		"export.png?scale=5",
+		"export.png?scale=big",
+		"export.png?bbox=0,0,0,10",
+		"export.pdf?bbox=1,2,3",
+		"export.png?scale=4&bbox=0,0,100000,100000",
+	} {
+		t.Run("Rejects "+query, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/"+query, nil))
+			assert.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,504 @@
+package handlers
+
+import (
+	"backend/config"
+	"backend/internal/assets"
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	middleware "backend/internal/middlewares"
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Undoable changes kept per workspace; 0 disables the history
+var historyDepth = config.C.HistoryDepth
+
+// The state of an item before and after a change, nil where it did not
+// exist, and its version in each state
+type itemChange struct {
+	ID            uint               `json:"id"`
+	Before        *models.ItemCreate `json:"before,omitempty"`
+	BeforeVersion uint64             `json:"before_version,omitempty"`
+	After         *models.ItemCreate `json:"after,omitempty"`
+	AfterVersion  uint64             `json:"after_version,omitempty"`
+}
+
+// Describe the change from before to after; either may be nil
+func newItemChange(before, after *schemas.Item) itemChange {
+	var change itemChange
+	if before != nil {
+		change.ID = before.ID
+		change.Before = itemState(*before)
+		change.BeforeVersion = before.Version
+	}
+	if after != nil {
+		change.ID = after.ID
+		change.After = itemState(*after)
+		change.AfterVersion = after.Version
+	}
+	return change
+}
+
+// Capture an item with its preloaded sub-record in the form it is created from
+func itemState(item schemas.Item) *models.ItemCreate {
+	state := &models.ItemCreate{
+		PositionX: item.PositionX,
+		PositionY: item.PositionY,
+		ZIndex:    item.ZIndex,
+		Color:     item.Color,
+		Scale:     item.Scale,
+		Width:     item.Width,
+		Height:    item.Height,
+	}
+
+	switch {
+	case item.TextItem != nil:
+		state.TextItem = &models.TextItemCreate{Content: item.TextItem.Content}
+	case item.ImageItem != nil:
+		state.ImageItem = &models.ImageItemCreate{AssetID: item.ImageItem.AssetID}
+	case item.ListItem != nil:
+		fields := make([]models.TodoItemFieldCreate, 0, len(item.ListItem.TodoListFields))
+		for _, f := range item.ListItem.TodoListFields {
+			fields = append(fields, models.TodoItemFieldCreate{
+				TextItem: models.TextItemCreate{Content: f.Content},
+				Done:     f.Done,
+			})
+		}
+		state.TodoList = &fields
+	case item.ShapeItem != nil:
+		state.ShapeItem = &models.ShapeItemCreate{Name: item.ShapeItem.Name}
+	case item.DrawingItem != nil:
+		points := make([]models.DrawingPointCreate, 0, len(item.DrawingItem.Points))
+		for _, p := range item.DrawingItem.Points {
+			points = append(points, models.DrawingPointCreate{X: p.X, Y: p.Y, Pressure: p.Pressure})
+		}
+		state.DrawingItem = &models.DrawingItemCreate{
+			Points:      points,
+			StrokeWidth: item.DrawingItem.StrokeWidth,
+			Opacity:     item.DrawingItem.Opacity,
+			LineCap:     item.DrawingItem.LineCap,
+			LineJoin:    item.DrawingItem.LineJoin,
+			Smoothing:   item.DrawingItem.Smoothing,
+		}
+	}
+	return state
+}
+
+// Record changes made together as one history entry. Undone entries can no
+// longer be redone, and entries past the history depth are dropped.
+func recordHistory(tx *gorm.DB, workspaceID, userID uint, changes []itemChange) error {
+	if historyDepth <= 0 || len(changes) == 0 {
+		return nil
+	}
+
+	if err := deleteHistoryEntries(tx, "workspace_id = ? AND undone", workspaceID); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	if err := tx.Create(&schemas.HistoryEntry{
+		WorkspaceID: workspaceID,
+		UserID:      userID,
+		Changes:     string(data),
+		Assets:      historyAssets(workspaceID, changes),
+	}).Error; err != nil {
+		return err
+	}
+
+	var expired []uint
+	if err := tx.Model(&schemas.HistoryEntry{}).
+		Where("workspace_id = ?", workspaceID).
+		Order("id DESC").
+		Offset(historyDepth).
+		Limit(1).
+		Pluck("id", &expired).
+		Error; err != nil {
+		return err
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	return deleteHistoryEntries(tx, "workspace_id = ? AND id <= ?", workspaceID, expired[0])
+}
+
+// The images shown before or after the changes, once each
+func historyAssets(workspaceID uint, changes []itemChange) []schemas.HistoryAsset {
+	var historyAssets []schemas.HistoryAsset
+	seen := make(map[string]bool)
+	for _, change := range changes {
+		for _, state := range []*models.ItemCreate{change.Before, change.After} {
+			if state == nil || state.ImageItem == nil || seen[state.ImageItem.AssetID] {
+				continue
+			}
+			seen[state.ImageItem.AssetID] = true
+			historyAssets = append(historyAssets, schemas.HistoryAsset{
+				AssetID:     state.ImageItem.AssetID,
+				WorkspaceID: workspaceID,
+			})
+		}
+	}
+	return historyAssets
+}
+
+// Delete the history entries matching the condition and release the images
+// only they kept
+func deleteHistoryEntries(tx *gorm.DB, query string, args ...interface{}) error {
+	var entryIDs []uint
+	if err := tx.Model(&schemas.HistoryEntry{}).Where(query, args...).Pluck("id", &entryIDs).Error; err != nil {
+		return err
+	}
+	if len(entryIDs) == 0 {
+		return nil
+	}
+
+	var assetIDs []string
+	if err := tx.Model(&schemas.HistoryAsset{}).
+		Where("entry_id IN ?", entryIDs).
+		Distinct().
+		Pluck("asset_id", &assetIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("entry_id IN ?", entryIDs).Delete(&schemas.HistoryAsset{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Delete(&schemas.HistoryEntry{}, entryIDs).Error; err != nil {
+		return err
+	}
+	return assets.Release(tx.Statement.Context, tx, assetIDs)
+}
+
+// @Summary Undo the latest change to a workspace's items
+// @Description Restore the items touched by the latest change that was not undone.
+// @Description Fails if any of them has changed since. Requires at least the editor role
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Success 200 {object} models.HistoryRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/undo [post]
+func UndoWorkspace(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return replayHistory(c, uint(workspaceID), true)
+}
+
+// @Summary Redo the latest undone change to a workspace's items
+// @Description Fails if any of the items it touches has changed since the undo.
+// @Description Requires at least the editor role
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Success 200 {object} models.HistoryRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/redo [post]
+func RedoWorkspace(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return replayHistory(c, uint(workspaceID), false)
+}
+
+// @Summary Undo the latest change to the user's workspace
+// @Description Restore the items touched by the latest change that was not undone.
+// @Description Fails if any of them has changed since
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.HistoryRead
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/undo [post]
+func UndoMyWorkspace(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return replayHistory(c, workspaceID, true)
+}
+
+// @Summary Redo the latest undone change to the user's workspace
+// @Description Fails if any of the items it touches has changed since the undo
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.HistoryRead
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/redo [post]
+func RedoMyWorkspace(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return replayHistory(c, workspaceID, false)
+}
+
+// Bring the items of the latest history entry back to their state before it
+// (undo) or after it (redo), and flip the entry
+func replayHistory(c *fiber.Ctx, workspaceID uint, undo bool) error {
+	userID, _ := c.Locals(middleware.IDKey).(uint)
+	response := models.HistoryRead{
+		Items:   []models.ItemRead{},
+		Deleted: []uint{},
+	}
+	var restored []schemas.Item
+	var recreated []bool
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var entry schemas.HistoryEntry
+		query := tx.Where("workspace_id = ? AND undone = ?", workspaceID, !undo)
+		if undo {
+			query = query.Order("id DESC")
+		} else {
+			query = query.Order("id")
+		}
+		if err := query.First(&entry).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) && undo {
+				return fiber.NewError(fiber.StatusConflict, "nothing to undo")
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusConflict, "nothing to redo")
+			}
+			return err
+		}
+
+		var changes []itemChange
+		if err := json.Unmarshal([]byte(entry.Changes), &changes); err != nil {
+			return err
+		}
+		if err := autoSnapshot(c.Context(), tx, workspaceID); err != nil {
+			return err
+		}
+
+		// Undo walks the changes backwards so that each item ends up where it
+		// was before the first of them
+		for i := range changes {
+			change := &changes[i]
+			if undo {
+				change = &changes[len(changes)-1-i]
+			}
+
+			from, to, toVersion := change.After, change.Before, &change.BeforeVersion
+			if !undo {
+				from, to, toVersion = change.Before, change.After, &change.AfterVersion
+			}
+
+			item, err := restoreItemState(c.Context(), tx, workspaceID, userID, change.ID, from, to, *toVersion)
+			if err != nil {
+				return err
+			}
+			if item == nil {
+				response.Deleted = append(response.Deleted, change.ID)
+				continue
+			}
+			// Later replays expect the version the item has now
+			*toVersion = item.Version
+			restored = append(restored, *item)
+			recreated = append(recreated, from == nil)
+		}
+
+		data, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&entry).Updates(map[string]interface{}{
+			"undone":  undo,
+			"changes": string(data),
+		}).Error
+	})
+	if err != nil {
+		return errorResponse(c, err, "failed to replay history")
+	}
+
+	for i := range restored {
+		response.Items = append(response.Items, newItemRead(restored[i]))
+		eventType := realtime.ItemUpdated
+		if recreated[i] {
+			eventType = realtime.ItemCreated
+		}
+		publishItemEvent(eventType, workspaceID, restored[i].ID, &restored[i])
+	}
+	for _, id := range response.Deleted {
+		publishItemEvent(realtime.ItemDeleted, workspaceID, id, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// Make sure the image a state shows is still stored. The history keeps the
+// images it refers to, but entries older than that may not.
+func checkStateImage(tx *gorm.DB, itemID uint, state *models.ItemCreate) error {
+	if state.ImageItem == nil {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&schemas.Asset{}).Where("id = ?", state.ImageItem.AssetID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("the image of item %d is no longer stored", itemID))
+	}
+	return nil
+}
+
+// Bring an item from one recorded state to another; nil states mean the item
+// does not exist. The item must still be in the from state, so that replaying
+// history never overwrites later changes. An item brought back is given a
+// version past toVersion. Returns the item as it ends up, or nil if it was
+// deleted.
+func restoreItemState(ctx context.Context, tx *gorm.DB, workspaceID, userID, itemID uint, from, to *models.ItemCreate, toVersion uint64) (*schemas.Item, error) {
+	current, err := lockItem(tx, workspaceID, itemID)
+	exists := err == nil
+	var e *fiber.Error
+	if err != nil && !(errors.As(err, &e) && e.Code == fiber.StatusNotFound) {
+		return nil, err
+	}
+
+	changed := exists != (from != nil)
+	if exists && !changed {
+		changed, err = stateChanged(itemState(current), from)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if changed {
+		return nil, fiber.NewError(fiber.StatusConflict, fmt.Sprintf("item %d has changed since", itemID))
+	}
+
+	switch {
+	case to == nil:
+		_, err := deleteItem(tx, workspaceID, itemID, nil)
+		return nil, err
+
+	case exists:
+		if err := checkStateImage(tx, itemID, to); err != nil {
+			return nil, err
+		}
+		item, _, err := updateItem(ctx, tx, workspaceID, itemID, itemStateUpdate(to))
+		return &item, err
+
+	default:
+		// A deleted item still in the trash is taken out of it
+		item, err := restoreTrashedItem(tx, workspaceID, itemID, toVersion)
+		if err == nil {
+			changed, err := stateChanged(itemState(item), to)
+			if err != nil || !changed {
+				return &item, err
+			}
+			item, _, err = updateItem(ctx, tx, workspaceID, itemID, itemStateUpdate(to))
+			return &item, err
+		}
+		if !(errors.As(err, &e) && e.Code == fiber.StatusNotFound) {
+			return nil, err
+		}
+
+		// Purged since, so it is made again
+		if err := checkStateImage(tx, itemID, to); err != nil {
+			return nil, err
+		}
+
+		item, imageAsset, err := newItem(tx, workspaceID, userID, to)
+		if err != nil {
+			return nil, err
+		}
+		// Bring the item back under its id, newer than any copy a client kept
+		item.ID = itemID
+		item.Version = toVersion + 1
+		if err := tx.Create(&item).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.
+			Where("workspace_id = ? AND item_id = ?", workspaceID, itemID).
+			Delete(&schemas.ItemTombstone{}).
+			Error; err != nil {
+			return nil, err
+		}
+		if imageAsset != nil {
+			item.ImageItem.Asset = imageAsset
+		}
+		return &item, nil
+	}
+}
+
+// Compare states as they are recorded, since versions move on with every replay
+func stateChanged(current, recorded *models.ItemCreate) (bool, error) {
+	a, err := json.Marshal(current)
+	if err != nil {
+		return false, err
+	}
+	b, err := json.Marshal(recorded)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(a, b), nil
+}
+
+// An update replacing every field of an item with a recorded state
+func itemStateUpdate(state *models.ItemCreate) *models.ItemUpdate {
+	return &models.ItemUpdate{
+		PositionX:   &state.PositionX,
+		PositionY:   &state.PositionY,
+		ZIndex:      &state.ZIndex,
+		Color:       &state.Color,
+		Scale:       &state.Scale,
+		Width:       &state.Width,
+		Height:      &state.Height,
+		TextItem:    state.TextItem,
+		ImageItem:   state.ImageItem,
+		TodoList:    state.TodoList,
+		ShapeItem:   state.ShapeItem,
+		DrawingItem: state.DrawingItem,
+	}
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/models"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUndoRedoMyWorkspace(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Post("/workspaces/my/items", AppendMyWorkspaceItem)
+	app.Post("/workspaces/my/items\\:batch", BatchMyWorkspaceItems)
+	app.Patch("/workspaces/my/items/:item_id", UpdateMyWorkspaceItem)
+	app.Delete("/workspaces/my/items/:item_id", DeleteMyWorkspaceItem)
+	app.Post("/workspaces/my/undo", UndoMyWorkspace)
+	app.Post("/workspaces/my/redo", RedoMyWorkspace)
+
+	send := func(method, path, payload string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+	replay := func(action string) (int, models.HistoryRead) {
+		resp, err := app.Test(httptest.NewRequest("POST", "/workspaces/my/"+action, nil))
+		assert.NoError(t, err)
+		var history models.HistoryRead
+		if resp.StatusCode == fiber.StatusOK {
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+		}
+		return resp.StatusCode, history
+	}
+	findItem := func(id uint) *schemas.Item {
+		var items []schemas.Item
+		assert.NoError(t, preloadItemRecords(database.DB, "").Find(&items, "id = ?", id).Error)
+		if len(items) == 0 {
+			return nil
+		}
+		return &items[0]
+	}
+
+	assert.Equal(t, fiber.StatusCreated, send("POST", "/workspaces/my/items", `{"text": {"content": "Note"}, "position_x": 1}`))
+	var id uint = 1
+	path := fmt.Sprintf("/workspaces/my/items/%d", id)
+	assert.Equal(t, fiber.StatusOK, send("PATCH", path, `{"position_x": 50, "text": {"content": "Edited"}}`))
+	assert.Equal(t, fiber.StatusOK, send("DELETE", path, ""))
+
+	t.Run("Undo a delete", func(t *testing.T) {
+		status, history := replay("undo")
+		assert.Equal(t, fiber.StatusOK, status)
+		if assert.Equal(t, 1, len(history.Items)) {
+			assert.Equal(t, id, history.Items[0].ID, "Item should come back under its id")
+			assert.Equal(t, "Edited", history.Items[0].TextItem.Content)
+		}
+
+		var tombstones int64
+		database.DB.Model(&schemas.ItemTombstone{}).Count(&tombstones)
+		assert.Zero(t, tombstones)
+	})
+
+	t.Run("Undo an update", func(t *testing.T) {
+		status, _ := replay("undo")
+		assert.Equal(t, fiber.StatusOK, status)
+		item := findItem(id)
+		if assert.NotNil(t, item) {
+			assert.Equal(t, float64(1), item.PositionX)
+			assert.Equal(t, "Note", item.TextItem.Content)
+		}
+	})
+
+	t.Run("Undo a create", func(t *testing.T) {
+		status, history := replay("undo")
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, []uint{id}, history.Deleted)
+		assert.Nil(t, findItem(id))
+
+		status, _ = replay("undo")
+		assert.Equal(t, fiber.StatusConflict, status, "Nothing is left to undo")
+	})
+
+	t.Run("Redo", func(t *testing.T) {
+		for range 2 {
+			status, _ := replay("redo")
+			assert.Equal(t, fiber.StatusOK, status)
+		}
+		item := findItem(id)
+		if assert.NotNil(t, item) {
+			assert.Equal(t, float64(50), item.PositionX)
+			assert.Equal(t, "Edited", item.TextItem.Content)
+		}
+	})
+
+	t.Run("New change drops the redo history", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, send("PATCH", path, `{"position_y": 5}`))
+		status, _ := replay("redo")
+		assert.Equal(t, fiber.StatusConflict, status)
+	})
+
+	t.Run("Items changed since cannot be undone", func(t *testing.T) {
+		// e.g. changed by a request that raced with the history
+		assert.NoError(t, database.DB.Model(&schemas.Item{}).Where("id = ?", id).Update("position_x", 99).Error)
+		status, _ := replay("undo")
+		assert.Equal(t, fiber.StatusConflict, status)
+		assert.NoError(t, database.DB.Model(&schemas.Item{}).Where("id = ?", id).Update("position_x", 50).Error)
+	})
+
+	t.Run("A batch is undone at once", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, send("POST", "/workspaces/my/items:batch", `{"operations": [
+			{"op": "create", "create": {"shape": {"name": "circle"}}},
+			{"op": "create", "create": {"shape": {"name": "square"}}},
+			{"op": "delete", "item_id": 1}
+		]}`))
+
+		status, history := replay("undo")
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, 2, len(history.Deleted))
+		assert.Equal(t, 1, len(history.Items))
+
+		var count int64
+		database.DB.Model(&schemas.Item{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Depth", func(t *testing.T) {
+		depth := historyDepth
+		historyDepth = 2
+		defer func() { historyDepth = depth }()
+
+		for i := range 3 {
+			assert.Equal(t, fiber.StatusCreated, send("POST", "/workspaces/my/items", fmt.Sprintf(`{"text": {"content": "%d"}}`, i)))
+		}
+		var entries int64
+		database.DB.Model(&schemas.HistoryEntry{}).Count(&entries)
+		assert.Equal(t, int64(2), entries)
+
+		for range 2 {
+			status, _ := replay("undo")
+			assert.Equal(t, fiber.StatusOK, status)
+		}
+		status, _ := replay("undo")
+		assert.Equal(t, fiber.StatusConflict, status)
+	})
+}
+
+func TestHistoryKeepsImages(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	// Snapshots would keep the images too
+	interval := snapshotInterval
+	snapshotInterval = 0
+	defer func() { snapshotInterval = interval }()
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+	for _, id := range []string{"first", "second"} {
+		assert.NoError(t, database.DB.Create(&schemas.Asset{
+			ID:          id,
+			OwnerID:     user.ID,
+			WorkspaceID: user.WorkspaceID,
+			ContentType: "image/png",
+		}).Error)
+	}
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Post("/workspaces/my/items", AppendMyWorkspaceItem)
+	app.Patch("/workspaces/my/items/:item_id", UpdateMyWorkspaceItem)
+	app.Delete("/workspaces/my/items/:item_id", DeleteMyWorkspaceItem)
+	app.Delete("/workspaces/my/trash/:item_id", PurgeMyWorkspaceTrashItem)
+	app.Post("/workspaces/my/undo", UndoMyWorkspace)
+
+	send := func(method, path, payload string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+	assetExists := func(id string) bool {
+		var count int64
+		database.DB.Model(&schemas.Asset{}).Where("id = ?", id).Count(&count)
+		return count > 0
+	}
+	shownAsset := func() string {
+		var image schemas.ImageItem
+		assert.NoError(t, database.DB.First(&image, "item_id = ?", 1).Error)
+		return image.AssetID
+	}
+
+	assert.Equal(t, fiber.StatusCreated, send("POST", "/workspaces/my/items", `{"image": {"asset_id": "first"}}`))
+
+	t.Run("Undo replacing an image", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, send("PATCH", "/workspaces/my/items/1", `{"image": {"asset_id": "second"}}`))
+		assert.True(t, assetExists("first"), "The replaced image should be kept for undo")
+
+		assert.Equal(t, fiber.StatusOK, send("POST", "/workspaces/my/undo", ""))
+		assert.Equal(t, "first", shownAsset())
+	})
+
+	t.Run("Undo deleting a purged image", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, send("DELETE", "/workspaces/my/items/1", ""))
+		assert.Equal(t, fiber.StatusOK, send("DELETE", "/workspaces/my/trash/1", ""))
+		assert.True(t, assetExists("first"), "The purged image should be kept for undo")
+
+		assert.Equal(t, fiber.StatusOK, send("POST", "/workspaces/my/undo", ""))
+		assert.Equal(t, "first", shownAsset())
+	})
+
+	t.Run("Expired entries release their images", func(t *testing.T) {
+		depth := historyDepth
+		historyDepth = 1
+		defer func() { historyDepth = depth }()
+
+		assert.Equal(t, fiber.StatusCreated, send("POST", "/workspaces/my/items", `{"text": {"content": "Later"}}`))
+		assert.True(t, assetExists("first"), "Images on the board stay")
+		assert.False(t, assetExists("second"), "Images only the history kept should go")
+	})
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"backend/internal/assets"
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/imaging"
+	middleware "backend/internal/middlewares"
+	"backend/internal/models"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const maxImageSize = 10 << 20 // bytes
+
+// @Summary Upload an image to the user's workspace
+// @Description Validate and normalize an image, render its thumbnails and return the
+// @Description asset id to reference from image items. EXIF metadata and GIF comments are
+// @Description stripped, animations kept, and images are scaled down to 4096 pixels on their
+// @Description longest side.
+// @Tags workspaces
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param image formData file true "Image file"
+// @Success 201 {object} models.AssetRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/images [post]
+func UploadMyWorkspaceImage(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "missing image file",
+		})
+	}
+
+	if fileHeader.Size > maxImageSize {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(models.ErrorResponse{
+			Error: "image is too large",
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "failed to read image file",
+		})
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "failed to read image file",
+		})
+	}
+
+	processed, err := imaging.Process(data)
+	if errors.Is(err, imaging.ErrUnsupported) {
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(models.ErrorResponse{
+			Error: "file is not a supported image",
+		})
+	}
+	if errors.Is(err, imaging.ErrTooLarge) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "image dimensions are too large",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "failed to decode image",
+		})
+	}
+
+	var asset schemas.Asset
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		asset, err = storeImage(c.Context(), tx, processed, userID, workspaceID)
+		return err
+	})
+
+	if err != nil {
+		log.Error().Err(err).Str("asset", asset.ID).Msg("failed to store image")
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to store image",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(newAssetRead(asset))
+}
+
+// Create the asset of a processed image with its thumbnails. Identical images
+// share their blobs, so storing one again writes nothing.
+func storeImage(ctx context.Context, tx *gorm.DB, processed *imaging.Result, userID, workspaceID uint) (schemas.Asset, error) {
+	asset := newImageAsset(processed.Original, userID, workspaceID)
+	variants := make([]schemas.Asset, 0, len(processed.Thumbnails))
+	for _, thumb := range processed.Thumbnails {
+		variant := newImageAsset(thumb.Image, userID, workspaceID)
+		variant.ParentID = &asset.ID
+		variant.Variant = thumb.Name
+		variants = append(variants, variant)
+	}
+
+	if err := assets.Store(ctx, tx, &asset, processed.Original.Data); err != nil {
+		return asset, err
+	}
+	for i := range variants {
+		if err := assets.Store(ctx, tx, &variants[i], processed.Thumbnails[i].Data); err != nil {
+			return asset, err
+		}
+	}
+	asset.Variants = variants
+	return asset, tx.Create(&asset).Error
+}
+
+// Store the picture an image item was sent with inline, base64 encoded, as an
+// upload would be, and point the item at it. Clients that predate uploads send
+// images this way.
+func storeInlineImage(db *gorm.DB, image *models.ImageItemCreate, userID, workspaceID uint) error {
+	if image.Bytes == "" || image.AssetID != "" {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(image.Bytes)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "image bytes are not base64")
+	}
+	if len(data) > maxImageSize {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, "image is too large")
+	}
+
+	processed, err := imaging.Process(data)
+	if errors.Is(err, imaging.ErrUnsupported) {
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, "file is not a supported image")
+	}
+	if errors.Is(err, imaging.ErrTooLarge) {
+		return fiber.NewError(fiber.StatusBadRequest, "image dimensions are too large")
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to decode image")
+	}
+
+	var asset schemas.Asset
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		asset, err = storeImage(tx.Statement.Context, tx, processed, userID, workspaceID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	image.AssetID, image.Bytes = asset.ID, ""
+	return nil
+}
+
+// Embed the stored original of each image in whole workspace reads, which
+// clients that predate uploads draw images from. Images that cannot be read
+// are left out.
+func embedImageBytes(ctx context.Context, items []schemas.Item, itemReads []models.ItemRead) {
+	for i, item := range items {
+		if item.ImageItem == nil || item.ImageItem.Asset == nil || itemReads[i].ImageItem == nil {
+			continue
+		}
+		data, err := readBlob(ctx, item.ImageItem.Asset.BlobHash)
+		if err != nil {
+			log.Warn().Err(err).Str("asset", item.ImageItem.AssetID).Msg("failed to read image")
+			continue
+		}
+		itemReads[i].ImageItem.Bytes = base64.StdEncoding.EncodeToString(data)
+	}
+}
+
+func newImageAsset(img imaging.Image, userID, workspaceID uint) schemas.Asset {
+	return schemas.Asset{
+		ID:          uuid.NewString(),
+		OwnerID:     userID,
+		WorkspaceID: workspaceID,
+		ContentType: img.ContentType,
+		Size:        int64(len(img.Data)),
+		Width:       img.Width,
+		Height:      img.Height,
+	}
+}
+
+func newAssetRead(asset schemas.Asset) models.AssetRead {
+	return models.AssetRead{
+		ID:          asset.ID,
+		ContentType: asset.ContentType,
+		Size:        asset.Size,
+		URL:         assetURL(asset.ID),
+		Width:       asset.Width,
+		Height:      asset.Height,
+		Thumbnails:  newImageVariantReads(asset.Variants),
+	}
+}
+
+func newImageVariantReads(variants []schemas.Asset) []models.ImageVariantRead {
+	variantReads := make([]models.ImageVariantRead, 0, len(variants))
+	for _, v := range variants {
+		variantReads = append(variantReads, models.ImageVariantRead{
+			Name:   v.Variant,
+			URL:    assetURL(v.ID),
+			Width:  v.Width,
+			Height: v.Height,
+		})
+	}
+	return variantReads
+}
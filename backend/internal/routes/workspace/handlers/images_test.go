@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"backend/internal/assets"
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/models"
+	"backend/internal/storage"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadMyWorkspaceImage(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	store, err := storage.NewFileStore(t.TempDir())
+	assert.NoError(t, err)
+	storage.Default = store
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	err = schemas.CreateUserWithWorkspace(database.DB, user)
+	assert.NoError(t, err)
+
+	app := fiber.New(fiber.Config{BodyLimit: 16 << 20})
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Post("/workspaces/my/images", UploadMyWorkspaceImage)
+
+	upload := func(field string, data []byte) *http.Response {
+		body := &bytes.Buffer{}
+		w := multipart.NewWriter(body)
+		part, _ := w.CreateFormFile(field, "image.png")
+		part.Write(data)
+		w.Close()
+
+		req := httptest.NewRequest("POST", "/workspaces/my/images", body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// Large enough to get a small thumbnail but not a medium one
+	var encoded bytes.Buffer
+	assert.NoError(t, png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 600, 300))))
+	pngData := encoded.Bytes()
+
+	t.Run("Upload image", func(t *testing.T) {
+		resp := upload("image", pngData)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		var assetRead models.AssetRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&assetRead))
+		assert.NotEmpty(t, assetRead.ID)
+		assert.Equal(t, "image/png", assetRead.ContentType)
+		assert.Equal(t, "/assets/"+assetRead.ID, assetRead.URL)
+		assert.Equal(t, 600, assetRead.Width)
+		assert.Equal(t, 300, assetRead.Height)
+
+		var asset schemas.Asset
+		assert.NoError(t, database.DB.Preload("Variants").First(&asset, "id = ?", assetRead.ID).Error)
+		assert.Equal(t, user.ID, asset.OwnerID)
+		assert.Equal(t, user.WorkspaceID, asset.WorkspaceID)
+		assert.Nil(t, asset.ParentID)
+
+		r, err := store.Get(context.Background(), asset.BlobHash)
+		if assert.NoError(t, err) {
+			data, _ := io.ReadAll(r)
+			r.Close()
+			assert.Equal(t, asset.Size, int64(len(data)))
+			config, err := png.DecodeConfig(bytes.NewReader(data))
+			assert.NoError(t, err)
+			assert.Equal(t, 600, config.Width)
+		}
+
+		if assert.Equal(t, 1, len(assetRead.Thumbnails)) && assert.Equal(t, 1, len(asset.Variants)) {
+			thumbnail := assetRead.Thumbnails[0]
+			assert.Equal(t, "small", thumbnail.Name)
+			assert.Equal(t, 256, thumbnail.Width)
+			assert.Equal(t, 128, thumbnail.Height)
+			assert.Equal(t, "/assets/"+asset.Variants[0].ID, thumbnail.URL)
+			assert.Equal(t, asset.ID, *asset.Variants[0].ParentID)
+
+			r, err := store.Get(context.Background(), asset.Variants[0].BlobHash)
+			if assert.NoError(t, err) {
+				r.Close()
+			}
+		}
+	})
+
+	t.Run("Not an image", func(t *testing.T) {
+		resp := upload("image", []byte("<svg onload=alert(1)></svg>"))
+		assert.Equal(t, fiber.StatusUnsupportedMediaType, resp.StatusCode)
+	})
+
+	t.Run("Corrupt image", func(t *testing.T) {
+		resp := upload("image", pngData[:64])
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		resp := upload("file", pngData)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Too large", func(t *testing.T) {
+		resp := upload("image", make([]byte, maxImageSize+1))
+		assert.Equal(t, fiber.StatusRequestEntityTooLarge, resp.StatusCode)
+	})
+}
+
+func TestInlineImageBytes(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	store, err := storage.NewFileStore(t.TempDir())
+	assert.NoError(t, err)
+	storage.Default = store
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+
+	app := fiber.New(fiber.Config{BodyLimit: 16 << 20})
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Post("/workspaces/my/items", AppendMyWorkspaceItem)
+	app.Patch("/workspaces/my/items/:item_id", UpdateMyWorkspaceItem)
+	app.Get("/workspaces/my", GetMyWorkspace)
+	app.Get("/workspaces/my/items", ListMyWorkspaceItems)
+
+	send := func(method, url string, payload any) *http.Response {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	var encoded bytes.Buffer
+	assert.NoError(t, png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 20, 10))))
+	inline := base64.StdEncoding.EncodeToString(encoded.Bytes())
+
+	// How the app sends images
+	resp := send("POST", "/workspaces/my/items", models.ItemCreate{ImageItem: &models.ImageItemCreate{Bytes: inline}})
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	var stored schemas.ImageItem
+	assert.NoError(t, database.DB.Preload("Asset").First(&stored).Error)
+	if assert.NotNil(t, stored.Asset, "Inline images should be stored as uploads") {
+		assert.Equal(t, user.WorkspaceID, stored.Asset.WorkspaceID)
+		assert.Equal(t, 20, stored.Asset.Width)
+	}
+
+	t.Run("Whole workspace reads embed the bytes", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my", nil))
+		assert.NoError(t, err)
+		var workspace models.WorkspaceRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&workspace))
+		if assert.Equal(t, 1, len(workspace.Items)) && assert.NotNil(t, workspace.Items[0].ImageItem) {
+			data, err := base64.StdEncoding.DecodeString(workspace.Items[0].ImageItem.Bytes)
+			assert.NoError(t, err)
+			config, err := png.DecodeConfig(bytes.NewReader(data))
+			assert.NoError(t, err)
+			assert.Equal(t, 20, config.Width)
+		}
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/workspaces/my/items", nil))
+		assert.NoError(t, err)
+		var itemReads []models.ItemRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&itemReads))
+		if assert.Equal(t, 1, len(itemReads)) {
+			assert.Empty(t, itemReads[0].ImageItem.Bytes, "Item lists should only link images")
+		}
+	})
+
+	t.Run("Updates take inline bytes", func(t *testing.T) {
+		var larger bytes.Buffer
+		assert.NoError(t, png.Encode(&larger, image.NewRGBA(image.Rect(0, 0, 30, 10))))
+		resp := send("PATCH", "/workspaces/my/items/1", models.ItemUpdate{
+			ImageItem: &models.ImageItemCreate{Bytes: base64.StdEncoding.EncodeToString(larger.Bytes())},
+		})
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var itemRead models.ItemRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&itemRead))
+		if assert.NotNil(t, itemRead.ImageItem) {
+			assert.NotEqual(t, stored.AssetID, itemRead.ImageItem.AssetID)
+			assert.Equal(t, 30, itemRead.ImageItem.Width)
+		}
+	})
+
+	t.Run("Invalid bytes", func(t *testing.T) {
+		for inline, status := range map[string]int{
+			"not base64!": fiber.StatusBadRequest,
+			base64.StdEncoding.EncodeToString([]byte("<svg onload=alert(1)></svg>")): fiber.StatusUnsupportedMediaType,
+		} {
+			resp := send("POST", "/workspaces/my/items", models.ItemCreate{ImageItem: &models.ImageItemCreate{Bytes: inline}})
+			assert.Equal(t, status, resp.StatusCode)
+		}
+	})
+}
+
+func TestImageDeduplication(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	// Snapshots and the history would keep the images
+	interval := snapshotInterval
+	snapshotInterval = 0
+	defer func() { snapshotInterval = interval }()
+	depth := historyDepth
+	historyDepth = 0
+	defer func() { historyDepth = depth }()
+
+	store, err := storage.NewFileStore(t.TempDir())
+	assert.NoError(t, err)
+	storage.Default = store
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Post("/workspaces/my/images", UploadMyWorkspaceImage)
+	app.Post("/workspaces/my/items", AppendMyWorkspaceItem)
+	app.Delete("/workspaces/my/items/:item_id", DeleteMyWorkspaceItem)
+	app.Delete("/workspaces/my/trash/:item_id", PurgeMyWorkspaceTrashItem)
+
+	var encoded bytes.Buffer
+	assert.NoError(t, png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 300, 300))))
+
+	// The same logo pasted onto the board twice
+	var itemIDs []uint
+	var assetIDs []string
+	for i := 0; i < 2; i++ {
+		body := &bytes.Buffer{}
+		w := multipart.NewWriter(body)
+		part, _ := w.CreateFormFile("image", "logo.png")
+		part.Write(encoded.Bytes())
+		w.Close()
+
+		req := httptest.NewRequest("POST", "/workspaces/my/images", body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		var assetRead models.AssetRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&assetRead))
+		assetIDs = append(assetIDs, assetRead.ID)
+
+		payload, _ := json.Marshal(models.ItemCreate{
+			ImageItem: &models.ImageItemCreate{AssetID: assetRead.ID},
+		})
+		req = httptest.NewRequest("POST", "/workspaces/my/items", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err = app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		var created models.CreatedResponse
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+		itemIDs = append(itemIDs, created.ID)
+	}
+	assert.NotEqual(t, assetIDs[0], assetIDs[1], "Each upload should get its own asset")
+
+	var blobs []schemas.Blob
+	assert.NoError(t, database.DB.Order("size").Find(&blobs).Error)
+	if assert.Equal(t, 2, len(blobs), "Original and thumbnail should be stored once each") {
+		for _, blob := range blobs {
+			assert.Equal(t, int64(2), blob.RefCount)
+		}
+	}
+
+	stats, err := assets.CollectStats(database.DB)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), stats.Assets)
+	assert.Equal(t, int64(2), stats.Blobs)
+	assert.Equal(t, 2*stats.StoredBytes, stats.LogicalBytes)
+
+	// Images are kept while their item is in the trash
+	deleteItem := func(id uint) {
+		for _, path := range []string{"/workspaces/my/items/", "/workspaces/my/trash/"} {
+			req := httptest.NewRequest("DELETE", path+strconv.FormatUint(uint64(id), 10), nil)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		}
+	}
+
+	deleteItem(itemIDs[0])
+
+	var count int64
+	database.DB.Model(&schemas.Asset{}).Count(&count)
+	assert.Equal(t, int64(2), count, "The unused asset and its thumbnail should be deleted")
+	assert.NoError(t, database.DB.Find(&blobs).Error)
+	for _, blob := range blobs {
+		assert.Equal(t, int64(1), blob.RefCount)
+		r, err := store.Get(context.Background(), blob.Hash)
+		if assert.NoError(t, err, "Blob still in use should be kept") {
+			r.Close()
+		}
+	}
+
+	deleteItem(itemIDs[1])
+
+	database.DB.Model(&schemas.Asset{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	database.DB.Model(&schemas.Blob{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	for _, blob := range blobs {
+		_, err := store.Get(context.Background(), blob.Hash)
+		assert.ErrorIs(t, err, storage.ErrNotFound, "Unreferenced blob should be collected")
+	}
+}
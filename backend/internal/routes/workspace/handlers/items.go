@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"cmp"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Resolve the default workspace of a user, addressed as /workspaces/my
+func myWorkspaceID(userID uint) (uint, error) {
+	var user schemas.User
+	if err := database.DB.Select("workspace_id").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fiber.NewError(fiber.StatusNotFound, "workspace not found")
+		}
+		return 0, err
+	}
+	return user.WorkspaceID, nil
+}
+
+// Respond with the status of a *fiber.Error, with a 409 and the current copy
+// of a stale item, or with a 500 and fallback message for any other error
+func errorResponse(c *fiber.Ctx, err error, fallback string) error {
+	if e, ok := err.(*fiber.Error); ok {
+		return c.Status(e.Code).JSON(models.ErrorResponse{Error: e.Message})
+	}
+	var stale *staleItemError
+	if errors.As(err, &stale) {
+		c.Set(fiber.HeaderETag, itemETag(stale.item.Version))
+		return c.Status(fiber.StatusConflict).JSON(models.ConflictResponse{
+			Error: stale.Error(),
+			Item:  newItemRead(stale.item),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+		Error: fallback,
+	})
+}
+
+// The client edited a copy of the item older than the one stored
+type staleItemError struct {
+	item schemas.Item
+}
+
+func (e *staleItemError) Error() string {
+	return "item has been modified"
+}
+
+func itemETag(version uint64) string {
+	return strconv.Quote(strconv.FormatUint(version, 10))
+}
+
+// Read the version of the item the client edited from If-Match, or else from
+// bodyVersion or the version query parameter. Nil means the client did not
+// say, or sent If-Match: *, and any version may be overwritten.
+func expectedVersion(c *fiber.Ctx, bodyVersion *uint64) (*uint64, error) {
+	ifMatch := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if ifMatch == "*" {
+		return nil, nil
+	}
+	if ifMatch != "" {
+		tag := strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+		version, err := strconv.ParseUint(tag, 10, 64)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "invalid If-Match header")
+		}
+		return &version, nil
+	}
+	if bodyVersion != nil {
+		return bodyVersion, nil
+	}
+	if query := c.Query("version"); query != "" {
+		version, err := strconv.ParseUint(query, 10, 64)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "invalid version")
+		}
+		return &version, nil
+	}
+	return nil, nil
+}
+
+// Load an item with its sub-records, locked until the transaction ends
+func lockItem(tx *gorm.DB, workspaceID, itemID uint) (schemas.Item, error) {
+	var item schemas.Item
+	err := preloadItemRecords(tx, "").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&item, "id = ? AND workspace_id = ?", itemID, workspaceID).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return item, fiber.NewError(fiber.StatusNotFound, "item not found in workspace")
+	}
+	return item, err
+}
+
+// Refuse to change an item unless the client edited its current version
+func checkItemVersion(item schemas.Item, version *uint64) error {
+	if version != nil && *version != item.Version {
+		return &staleItemError{item: item}
+	}
+	return nil
+}
+
+// Preload every typed sub-record of an item; prefix is the path to the items
+// relative to the queried model, e.g. "Items." when loading a workspace
+func preloadItemRecords(db *gorm.DB, prefix string) *gorm.DB {
+	return db.
+		Preload(prefix+"TextItem").
+		Preload(prefix+"ImageItem.Asset.Variants", orderByID).
+		Preload(prefix+"ListItem.TodoListFields", orderByID).
+		Preload(prefix + "ShapeItem").
+		Preload(prefix + "DrawingItem")
+}
+
+func orderByID(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
+
+// Validate an item to create and build it with its typed sub-record. The asset
+// of an image item is returned apart, to attach once the item is saved so that
+// saving leaves assets alone.
+func newItem(db *gorm.DB, workspaceID, userID uint, itemCreate *models.ItemCreate) (schemas.Item, *schemas.Asset, error) {
+	itemTypes := 0
+	if itemCreate.TextItem != nil {
+		itemTypes++
+	}
+	if itemCreate.ImageItem != nil {
+		itemTypes++
+	}
+	if itemCreate.TodoList != nil {
+		itemTypes++
+	}
+	if itemCreate.ShapeItem != nil {
+		itemTypes++
+	}
+	if itemCreate.DrawingItem != nil {
+		itemTypes++
+	}
+
+	if itemTypes != 1 {
+		return schemas.Item{}, nil, fiber.NewError(fiber.StatusBadRequest, "must provide exactly one item type (text, image, todo list, shape, or drawing)")
+	}
+	if itemCreate.Scale < 0 {
+		return schemas.Item{}, nil, fiber.NewError(fiber.StatusBadRequest, "scale must be positive")
+	}
+
+	var imageAsset *schemas.Asset
+	switch {
+	case itemCreate.TextItem != nil:
+		if itemCreate.TextItem.Content == "" {
+			return schemas.Item{}, nil, fiber.NewError(fiber.StatusBadRequest, "cannot create an empty text item")
+		}
+	case itemCreate.ImageItem != nil:
+		if err := storeInlineImage(db, itemCreate.ImageItem, userID, workspaceID); err != nil {
+			return schemas.Item{}, nil, err
+		}
+		asset, err := checkImageAsset(db, itemCreate.ImageItem.AssetID, workspaceID, userID)
+		if err != nil {
+			return schemas.Item{}, nil, err
+		}
+		imageAsset = asset
+	case itemCreate.DrawingItem != nil:
+		if err := validateStroke(itemCreate.DrawingItem); err != nil {
+			return schemas.Item{}, nil, err
+		}
+	}
+	item := itemFromState(workspaceID, itemCreate)
+	if item.DrawingItem != nil {
+		item.DrawingItem.Points = item.DrawingItem.Points.Simplify(strokeTolerance)
+	}
+	return item, imageAsset, nil
+}
+
+// Build an item with its typed sub-record, without checking it. Items
+// without a scale are at 1.
+func itemFromState(workspaceID uint, itemCreate *models.ItemCreate) schemas.Item {
+	item := schemas.Item{
+		WorkspaceID: workspaceID,
+		PositionX:   itemCreate.PositionX,
+		PositionY:   itemCreate.PositionY,
+		ZIndex:      itemCreate.ZIndex,
+		Color:       itemCreate.Color,
+		Scale:       cmp.Or(itemCreate.Scale, 1),
+		Width:       itemCreate.Width,
+		Height:      itemCreate.Height,
+	}
+
+	switch {
+	case itemCreate.TextItem != nil:
+		item.TextItem = &schemas.TextItem{Content: itemCreate.TextItem.Content}
+	case itemCreate.ImageItem != nil:
+		item.ImageItem = &schemas.ImageItem{AssetID: itemCreate.ImageItem.AssetID}
+	case itemCreate.TodoList != nil:
+		item.ListItem = &schemas.TodoListItem{
+			TodoListFields: newTodoListFields(*itemCreate.TodoList),
+		}
+	case itemCreate.ShapeItem != nil:
+		item.ShapeItem = &schemas.ShapeItem{Name: itemCreate.ShapeItem.Name}
+	case itemCreate.DrawingItem != nil:
+		item.DrawingItem = newDrawingItem(itemCreate.DrawingItem)
+	}
+	return item
+}
+
+// Build todo list fields with ids local to their list
+func newTodoListFields(fieldCreates []models.TodoItemFieldCreate) []schemas.TodoListField {
+	fields := make([]schemas.TodoListField, 0, len(fieldCreates))
+	for i, f := range fieldCreates {
+		fields = append(fields, schemas.TodoListField{
+			ID:      uint(i + 1),
+			Content: f.TextItem.Content,
+			Done:    f.Done,
+		})
+	}
+	return fields
+}
+
+// Make sure an image item may show the asset: it must exist, be an original
+// rather than a thumbnail and have been uploaded to the workspace or by the
+// user. The asset is returned with its thumbnails.
+func checkImageAsset(db *gorm.DB, assetID string, workspaceID, userID uint) (*schemas.Asset, error) {
+	var asset schemas.Asset
+	err := db.Preload("Variants", orderByID).First(&asset, "id = ?", assetID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "unknown asset")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if asset.WorkspaceID != workspaceID && asset.OwnerID != userID {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "unknown asset")
+	}
+	if asset.ParentID != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "asset is a thumbnail")
+	}
+	return &asset, nil
+}
+
+func assetURL(assetID string) string {
+	return "/assets/" + assetID
+}
+
+// Convert an item with its preloaded sub-records to the response model
+func newItemRead(item schemas.Item) models.ItemRead {
+	itemRead := models.ItemRead{
+		ID:          item.ID,
+		PositionX:   item.PositionX,
+		PositionY:   item.PositionY,
+		ZIndex:      item.ZIndex,
+		WorkspaceID: item.WorkspaceID,
+		Color:       item.Color,
+		Width:       item.Width,
+		Height:      item.Height,
+		Scale:       item.Scale,
+		Revision:    item.Revision,
+		Version:     item.Version,
+	}
+
+	// Handle text items
+	if item.TextItem != nil {
+		itemRead.TextItem = &models.TextItemRead{
+			Content: item.TextItem.Content,
+		}
+	}
+
+	// Handle image items
+	if item.ImageItem != nil {
+		itemRead.ImageItem = &models.ImageItemRead{
+			AssetID:    item.ImageItem.AssetID,
+			URL:        assetURL(item.ImageItem.AssetID),
+			Thumbnails: []models.ImageVariantRead{},
+		}
+		if asset := item.ImageItem.Asset; asset != nil {
+			itemRead.ImageItem.Width = asset.Width
+			itemRead.ImageItem.Height = asset.Height
+			itemRead.ImageItem.Thumbnails = newImageVariantReads(asset.Variants)
+		}
+	}
+
+	// Handle list items
+	if item.ListItem != nil {
+		listFields := make([]models.TodoListItemFieldRead, 0, len(item.ListItem.TodoListFields))
+		for _, field := range item.ListItem.TodoListFields {
+			listFields = append(listFields, models.TodoListItemFieldRead{
+				TextItemRead: models.TextItemRead{
+					Content: field.Content,
+				},
+				Done: field.Done,
+			})
+		}
+		itemRead.TodoListItem = listFields
+	}
+
+	// Handle shape items
+	if item.ShapeItem != nil {
+		itemRead.ShapeItem = &models.ShapeItemRead{
+			Name: item.ShapeItem.Name,
+		}
+	}
+
+	// Handle drawing items
+	if item.DrawingItem != nil {
+		itemRead.DrawingItem = newDrawingItemRead(item.DrawingItem)
+	}
+
+	return itemRead
+}
+
+// Notify clients connected to the workspace of an item change; item is nil
+// for deletes
+func publishItemEvent(eventType realtime.EventType, workspaceID, itemID uint, item *schemas.Item) {
+	event := realtime.Event{
+		Type:        eventType,
+		WorkspaceID: workspaceID,
+		ItemID:      itemID,
+	}
+	if item != nil {
+		itemRead := newItemRead(*item)
+		event.Item = &itemRead
+	}
+	realtime.DefaultHub.Publish(event)
+}
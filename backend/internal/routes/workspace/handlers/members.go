@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	middleware "backend/internal/middlewares"
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// @Summary List the members of a workspace
+// @Description The owner is not listed; see owner_id of the workspace
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Success 200 {object} []models.MemberRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/members [get]
+func ListWorkspaceMembers(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	memberReads := make([]models.MemberRead, 0)
+	if err := database.DB.
+		Model(&schemas.WorkspaceMember{}).
+		Select("workspace_members.user_id, users.login, workspace_members.role, workspace_members.created_at").
+		Joins("JOIN users ON users.id = workspace_members.user_id").
+		Where("workspace_members.workspace_id = ?", workspaceID).
+		Order("workspace_members.created_at, workspace_members.user_id").
+		Scan(&memberReads).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to list members",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(memberReads)
+}
+
+// @Summary Invite a user to a workspace by login
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param member body models.MemberInvite true "Login and role; role defaults to viewer"
+// @Success 201 {object} models.MemberRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/members [post]
+func InviteWorkspaceMember(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	invite := new(models.MemberInvite)
+	if err := c.BodyParser(invite); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid request body",
+		})
+	}
+
+	role := invite.Role
+	if role == "" {
+		role = schemas.WorkspaceRoleViewer
+	}
+	if !schemas.IsWorkspaceRole(role) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid role",
+		})
+	}
+
+	var member schemas.WorkspaceMember
+	var user schemas.User
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var workspace schemas.Workspace
+		if err := tx.Select("id", "owner_id").First(&workspace, workspaceID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "workspace not found")
+			}
+			return err
+		}
+
+		if err := tx.Where("login = ?", strings.TrimSpace(invite.Login)).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "user not found")
+			}
+			return err
+		}
+
+		if user.ID == workspace.OwnerID {
+			return fiber.NewError(fiber.StatusConflict, "user owns the workspace")
+		}
+
+		member = schemas.WorkspaceMember{
+			WorkspaceID: workspace.ID,
+			UserID:      user.ID,
+			Role:        role,
+		}
+		if err := tx.Create(&member).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return fiber.NewError(fiber.StatusConflict, "user is already a member")
+			}
+			return err
+		}
+		return nil
+	})
+
+	if err != nil {
+		return errorResponse(c, err, "failed to invite member")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(newMemberRead(member, user.Login))
+}
+
+// @Summary Change the role of a workspace member
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param user_id path int true "User ID"
+// @Param member body models.MemberUpdate true "New role"
+// @Success 200 {object} models.MemberRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/members/{user_id} [patch]
+func UpdateWorkspaceMember(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	userID, err := c.ParamsInt("user_id")
+	if err != nil || userID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid user id",
+		})
+	}
+
+	memberUpdate := new(models.MemberUpdate)
+	if err := c.BodyParser(memberUpdate); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid request body",
+		})
+	}
+
+	if !schemas.IsWorkspaceRole(memberUpdate.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid role",
+		})
+	}
+
+	var member schemas.WorkspaceMember
+	var user schemas.User
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+			Take(&member).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "member not found")
+			}
+			return err
+		}
+
+		if err := tx.Select("login").First(&user, member.UserID).Error; err != nil {
+			return err
+		}
+
+		member.Role = memberUpdate.Role
+		return tx.Model(&member).
+			Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+			Update("role", member.Role).Error
+	})
+
+	if err != nil {
+		return errorResponse(c, err, "failed to update member")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newMemberRead(member, user.Login))
+}
+
+// @Summary Remove a member from a workspace
+// @Description Owners may remove anyone; other members may only remove themselves
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} models.MessageResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/members/{user_id} [delete]
+func RemoveWorkspaceMember(c *fiber.Ctx) error {
+	callerID, ok := c.Locals(middleware.IDKey).(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	userID, err := c.ParamsInt("user_id")
+	if err != nil || userID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid user id",
+		})
+	}
+
+	role, _ := c.Locals(middleware.WorkspaceRoleKey).(string)
+	if uint(userID) != callerID && role != schemas.WorkspaceRoleOwner {
+		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+			Error: "forbidden",
+		})
+	}
+
+	result := database.DB.
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		Delete(&schemas.WorkspaceMember{})
+
+	if err := result.Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to remove member",
+		})
+	}
+
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error: "member not found",
+		})
+	}
+	realtime.DefaultSessions.RevokeMember(uint(workspaceID), uint(userID))
+
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "member removed successfully",
+	})
+}
+
+// @Summary List workspaces other users shared with the caller
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} []models.SharedWorkspaceRead
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/shared-with-me [get]
+func ListSharedWorkspaces(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	var members []schemas.WorkspaceMember
+	if err := database.DB.
+		Where("user_id = ?", userID).
+		Order("workspace_id").
+		Find(&members).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to list workspaces",
+		})
+	}
+
+	workspaceIDs := make([]uint, 0, len(members))
+	roles := make(map[uint]string, len(members))
+	for _, member := range members {
+		workspaceIDs = append(workspaceIDs, member.WorkspaceID)
+		roles[member.WorkspaceID] = member.Role
+	}
+
+	var workspaces []schemas.Workspace
+	if len(workspaceIDs) > 0 {
+		if err := database.DB.
+			Where("id IN ?", workspaceIDs).
+			Order("id").
+			Find(&workspaces).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+				Error: "failed to list workspaces",
+			})
+		}
+	}
+
+	workspaceReads := make([]models.SharedWorkspaceRead, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		workspaceReads = append(workspaceReads, models.SharedWorkspaceRead{
+			WorkspaceInfoRead: newWorkspaceInfoRead(workspace),
+			Role:              roles[workspace.ID],
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(workspaceReads)
+}
+
+func newMemberRead(member schemas.WorkspaceMember, login string) models.MemberRead {
+	return models.MemberRead{
+		UserID:    member.UserID,
+		Login:     login,
+		Role:      member.Role,
+		CreatedAt: member.CreatedAt,
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/middlewares"
+	"backend/internal/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspaceMembers(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	users := map[string]*schemas.User{}
+	for _, login := range []string{"owner", "alice", "bob"} {
+		user := &schemas.User{Login: login, PasswordHash: "hashedpassword"}
+		assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+		users[login] = user
+	}
+	owner, alice, bob := users["owner"], users["alice"], users["bob"]
+
+	membersPath := fmt.Sprintf("/workspaces/%d/members", owner.WorkspaceID)
+	memberPath := func(user *schemas.User) string {
+		return fmt.Sprintf("%s/%d", membersPath, user.ID)
+	}
+
+	// The app as seen by a given caller
+	send := func(caller *schemas.User, method, path, body string) *http.Response {
+		app := fiber.New()
+		app.Use(mockAuthMiddleware(caller.ID))
+		access := middleware.RequireWorkspaceAccess("workspace_id")
+		owner := middleware.RequireWorkspaceOwner("workspace_id")
+		app.Get("/workspaces/shared-with-me", ListSharedWorkspaces)
+		app.Get("/workspaces/:workspace_id/members", access, ListWorkspaceMembers)
+		app.Post("/workspaces/:workspace_id/members", owner, InviteWorkspaceMember)
+		app.Patch("/workspaces/:workspace_id/members/:user_id", owner, UpdateWorkspaceMember)
+		app.Delete("/workspaces/:workspace_id/members/:user_id", access, RemoveWorkspaceMember)
+
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Invite", func(t *testing.T) {
+		resp := send(owner, "POST", membersPath, `{"login": "alice", "role": "editor"}`)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		var member models.MemberRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&member))
+		assert.Equal(t, alice.ID, member.UserID)
+		assert.Equal(t, "alice", member.Login)
+		assert.Equal(t, schemas.WorkspaceRoleEditor, member.Role)
+
+		resp = send(owner, "POST", membersPath, `{"login": "bob"}`)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&member))
+		assert.Equal(t, schemas.WorkspaceRoleViewer, member.Role, "Role should default to viewer")
+	})
+
+	t.Run("Invalid invites", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			body           string
+			expectedStatus int
+		}{
+			{"Unknown role", `{"login": "alice", "role": "admin"}`, fiber.StatusBadRequest},
+			{"Unknown user", `{"login": "nobody"}`, fiber.StatusNotFound},
+			{"Already a member", `{"login": "alice"}`, fiber.StatusConflict},
+			{"Owner", `{"login": "owner"}`, fiber.StatusConflict},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := send(owner, "POST", membersPath, tt.body)
+				assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			})
+		}
+	})
+
+	t.Run("Only owners invite", func(t *testing.T) {
+		resp := send(alice, "POST", membersPath, `{"login": "bob"}`)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("List members", func(t *testing.T) {
+		resp := send(bob, "GET", membersPath, "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var members []models.MemberRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&members))
+		if assert.Equal(t, 2, len(members)) {
+			assert.Equal(t, "alice", members[0].Login)
+			assert.Equal(t, "bob", members[1].Login)
+		}
+	})
+
+	t.Run("Change role", func(t *testing.T) {
+		resp := send(owner, "PATCH", memberPath(bob), `{"role": "commenter"}`)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var member models.MemberRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&member))
+		assert.Equal(t, schemas.WorkspaceRoleCommenter, member.Role)
+
+		resp = send(owner, "PATCH", memberPath(bob), `{"role": "superuser"}`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+		resp = send(owner, "PATCH", memberPath(owner), `{"role": "viewer"}`)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "The owner is not a member")
+	})
+
+	t.Run("Shared with me", func(t *testing.T) {
+		resp := send(bob, "GET", "/workspaces/shared-with-me", "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var workspaces []models.SharedWorkspaceRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&workspaces))
+		if assert.Equal(t, 1, len(workspaces)) {
+			assert.Equal(t, owner.WorkspaceID, workspaces[0].ID)
+			assert.Equal(t, owner.ID, workspaces[0].OwnerID)
+			assert.Equal(t, schemas.WorkspaceRoleCommenter, workspaces[0].Role)
+		}
+
+		resp = send(owner, "GET", "/workspaces/shared-with-me", "")
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&workspaces))
+		assert.Empty(t, workspaces)
+	})
+
+	t.Run("Remove members", func(t *testing.T) {
+		resp := send(bob, "DELETE", memberPath(alice), "")
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode, "Members may not remove others")
+
+		resp = send(bob, "DELETE", memberPath(bob), "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode, "Members may leave")
+
+		resp = send(owner, "DELETE", memberPath(alice), "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		resp = send(owner, "DELETE", memberPath(alice), "")
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+		resp = send(alice, "GET", membersPath, "")
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode, "Removed members lose access")
+	})
+}
@@ -6,41 +6,54 @@ import (
 	middleware "backend/internal/middlewares"
 	"backend/internal/models"
 	"errors"
-	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
-// @Summary Get workspace by user id
-// @Description Retrieve a workspace by their unique user id
+// @Summary Get workspace by id
+// @Description Retrieve a workspace with all its items; requires at least the viewer role
 // @Tags workspaces
 // @Accept json
 // @Produce json
-// @Param user_id path int true "User id"
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param points query string false "How drawing points are sent" Enums(objects, packed) default(objects)
 // @Success 200 {object} models.WorkspaceRead
 // @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
 // @Failure 404 {object} models.ErrorResponse "User Not Found"
 // @Failure 500 {object} models.ErrorResponse "Internal Server Error"
-// @Router /workspaces/{user_id} [get]
+// @Router /workspaces/{workspace_id} [get]
 func GetWorkspace(c *fiber.Ctx) error {
-	id, err := c.ParamsInt("user_id")
+	id, err := c.ParamsInt("workspace_id")
 	if err != nil || id < 1 {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error: "invalid user ID",
+			Error: "invalid workspace id",
+		})
+	}
+
+	packed, err := packedPointsQuery(c)
+	if err != nil {
+		return errorResponse(c, err, "failed to get workspace")
+	}
+
+	// Read before the items, so that syncing from it cannot miss a change
+	counter, err := workspaceCounter(database.DB, uint(id))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to get workspace",
 		})
 	}
 
 	// Load workspace with all nested relationships
 	var workspace schemas.Workspace
-	err = database.DB.
-		Preload("Items.TextItem").
-		Preload("Items.ImageItem").
-		Preload("Items.ListItem.TodoListFields.TextItem").
-		Preload("Items.ShapeItem").
-		Preload("Items.DrawingItem").
-		Joins("User").
-		First(&workspace, "user_id = ?", id).Error
+	err = preloadItemRecords(database.DB, "Items.").
+		First(&workspace, id).Error
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -54,74 +67,17 @@ func GetWorkspace(c *fiber.Ctx) error {
 	}
 
 	// Convert to response model
-	itemReads := make([]models.ItemRead, 0, len(workspace.Items))
-	for _, item := range workspace.Items {
-		itemRead := models.ItemRead{
-			ID:          item.ID,
-			PositionX:   item.PositionX,
-			PositionY:   item.PositionY,
-			ZIndex:      item.ZIndex,
-			WorkspaceID: item.WorkspaceID,
-			Color:       item.Color,
-			Scale:       item.Scale,
-		}
-
-		// Handle text items
-		if item.TextItem != nil {
-			itemRead.TextItem = &models.TextItemRead{
-				Content: item.TextItem.Content,
-			}
-		}
-
-		// Handle image items
-		if item.ImageItem != nil {
-			itemRead.ImageItem = &models.ImageItemRead{
-				Bytes: item.ImageItem.Bytes,
-			}
-		}
+	itemReads := newItemReads(workspace.Items, packed)
+	embedImageBytes(c.Context(), workspace.Items, itemReads)
 
-		// Handle list items
-		if item.ListItem != nil {
-			listFields := make([]models.TodoListItemFieldRead, 0, len(item.ListItem.TodoListFields))
-			for _, field := range item.ListItem.TodoListFields {
-				if field.TextItem != nil {
-					listFields = append(listFields, models.TodoListItemFieldRead{
-						TextItemRead: models.TextItemRead{
-							Content: field.TextItem.Content,
-						},
-						Done: field.Done,
-					})
-				}
-			}
-			itemRead.TodoListItem = listFields
-		}
-
-		// Handle shape items
-		if item.ShapeItem != nil {
-			itemRead.ShapeItem = &models.ShapeItemRead{
-				Name: item.ShapeItem.Name,
-			}
-		}
-
-		// Handle drawing items
-		if item.DrawingItem != nil {
-			points := make([]models.DrawingPointRead, 0, len(item.DrawingItem.Points))
-			for _, p := range item.DrawingItem.Points {
-				points = append(points, models.DrawingPointRead{
-					X: p.X,
-					Y: p.Y,
-				})
-			}
-			itemRead.DrawingItem = &models.DrawingItemRead{
-				Points: points,
-			}
-		}
-
-		itemReads = append(itemReads, itemRead)
-	}
+	role, _ := c.Locals(middleware.WorkspaceRoleKey).(string)
 
 	return c.Status(fiber.StatusOK).JSON(models.WorkspaceRead{
-		Items: itemReads,
+		ID:       workspace.ID,
+		Name:     workspace.Name,
+		Role:     role,
+		Revision: counter.Revision,
+		Items:    itemReads,
 	})
 }
 
@@ -130,6 +86,7 @@ func GetWorkspace(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param points query string false "How drawing points are sent" Enums(objects, packed) default(objects)
 // @Success 200 {object} models.WorkspaceRead
 // @Failure 400 {object} models.ErrorResponse "Bad Request"
 // @Failure 401 {object} models.ErrorResponse "Bad Request"
@@ -151,16 +108,28 @@ func GetMyWorkspace(c *fiber.Ctx) error {
 		})
 	}
 
+	workspaceID, err := myWorkspaceID(id)
+	if err != nil {
+		return errorResponse(c, err, "failed to get workspace")
+	}
+
+	packed, err := packedPointsQuery(c)
+	if err != nil {
+		return errorResponse(c, err, "failed to get workspace")
+	}
+
+	// Read before the items, so that syncing from it cannot miss a change
+	counter, err := workspaceCounter(database.DB, workspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to get workspace",
+		})
+	}
+
 	// Load workspace with all nested relationships
 	var workspace schemas.Workspace
-	err := database.DB.
-		Preload("Items.TextItem").
-		Preload("Items.ImageItem").
-		Preload("Items.ListItem.TodoListFields.TextItem").
-		Preload("Items.ShapeItem").
-		Preload("Items.DrawingItem.Points").
-		Where("user_id = ?", id).
-		First(&workspace, "user_id = ?", id).Error
+	err = preloadItemRecords(database.DB, "Items.").
+		First(&workspace, workspaceID).Error
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -174,75 +143,173 @@ func GetMyWorkspace(c *fiber.Ctx) error {
 	}
 
 	// Convert to response model
-	itemReads := make([]models.ItemRead, 0, len(workspace.Items))
-	for _, item := range workspace.Items {
-		itemRead := models.ItemRead{
-			ID:          item.ID,
-			PositionX:   item.PositionX,
-			PositionY:   item.PositionY,
-			ZIndex:      item.ZIndex,
-			WorkspaceID: item.WorkspaceID,
-			Color:		 item.Color,
-			Width:		 item.Width,
-			Height:		 item.Height,
-			Scale:       item.Scale,
-		}
+	itemReads := newItemReads(workspace.Items, packed)
+	embedImageBytes(c.Context(), workspace.Items, itemReads)
+	return c.Status(fiber.StatusOK).JSON(models.WorkspaceRead{
+		ID:       workspace.ID,
+		Name:     workspace.Name,
+		Role:     schemas.WorkspaceRoleOwner,
+		Revision: counter.Revision,
+		Items:    itemReads,
+	})
+}
 
-		// Handle text items
-		if item.TextItem != nil {
-			itemRead.TextItem = &models.TextItemRead{
-				Content: item.TextItem.Content,
-			}
-		}
+// @Summary List the items of a workspace, optionally within a viewport
+// @Description With bbox=minX,minY,maxX,maxY only items whose rectangle intersects
+// @Description it are returned, so that a large board can be loaded as it is panned.
+// @Description Requires at least the viewer role
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param bbox query string false "Viewport as minX,minY,maxX,maxY"
+// @Param points query string false "How drawing points are sent" Enums(objects, packed) default(objects)
+// @Success 200 {object} []models.ItemRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/items [get]
+func ListWorkspaceItems(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
 
-		// Handle image items
-		if item.ImageItem != nil {
-			itemRead.ImageItem = &models.ImageItemRead{
-				Bytes: item.ImageItem.Bytes,
-			}
-		}
+	return listWorkspaceItems(c, uint(workspaceID))
+}
 
-		// Handle list items
-		if item.ListItem != nil {
-			listFields := make([]models.TodoListItemFieldRead, 0, len(item.ListItem.TodoListFields))
-			for _, field := range item.ListItem.TodoListFields {
-				if field.TextItem != nil {
-					listFields = append(listFields, models.TodoListItemFieldRead{
-						TextItemRead: models.TextItemRead{
-							Content: field.TextItem.Content,
-						},
-						Done: field.Done,
-					})
-				}
-			}
-			itemRead.TodoListItem = listFields
-		}
+// @Summary List the items of the user's workspace, optionally within a viewport
+// @Description With bbox=minX,minY,maxX,maxY only items whose rectangle intersects
+// @Description it are returned, so that a large board can be loaded as it is panned
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param bbox query string false "Viewport as minX,minY,maxX,maxY"
+// @Param points query string false "How drawing points are sent" Enums(objects, packed) default(objects)
+// @Success 200 {object} []models.ItemRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/items [get]
+func ListMyWorkspaceItems(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return listWorkspaceItems(c, workspaceID)
+}
+
+func listWorkspaceItems(c *fiber.Ctx, workspaceID uint) error {
+	packed, err := packedPointsQuery(c)
+	if err != nil {
+		return errorResponse(c, err, "failed to list items")
+	}
 
-		// Handle shape items
-		if item.ShapeItem != nil {
-			itemRead.ShapeItem = &models.ShapeItemRead{
-				Name: item.ShapeItem.Name,
-			}
+	query := preloadItemRecords(database.DB, "").Where("workspace_id = ?", workspaceID)
+
+	if bbox := c.Query("bbox"); bbox != "" {
+		minX, minY, maxX, maxY, err := parseBBox(bbox)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error: "invalid bbox: " + err.Error(),
+			})
 		}
-		
-		if item.DrawingItem != nil {
-			var pointReads []models.DrawingPointRead
-			for _, point := range item.DrawingItem.Points {
-				pointRead := models.DrawingPointRead{
-					X: point.X,
-					Y: point.Y,
-				}
-				pointReads = append(pointReads, pointRead)
-			}
-			itemRead.DrawingItem = &models.DrawingItemRead{
-				Points: pointReads,
-			}
+		// Served by idx_items_bounds; edges that touch count as intersecting
+		query = query.Where(
+			"min_x <= ? AND max_x >= ? AND min_y <= ? AND max_y >= ?",
+			maxX, minX, maxY, minY,
+		)
+	}
+
+	var items []schemas.Item
+	if err := query.Order("z_index, id").Find(&items).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to list items",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newItemReads(items, packed))
+}
+
+// Parse "minX,minY,maxX,maxY"
+func parseBBox(s string) (minX, minY, maxX, maxY float64, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return 0, 0, 0, 0, errors.New("expected minX,minY,maxX,maxY")
+	}
+
+	var values [4]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0, 0, 0, 0, errors.New("coordinates must be numbers")
 		}
-		
-		itemReads = append(itemReads, itemRead)
+		values[i] = v
+	}
+
+	minX, minY, maxX, maxY = values[0], values[1], values[2], values[3]
+	if minX > maxX || minY > maxY {
+		return 0, 0, 0, 0, errors.New("min must not exceed max")
+	}
+	return minX, minY, maxX, maxY, nil
+}
+
+// @Summary List the user's workspaces
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} []models.WorkspaceInfoRead
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces [get]
+func ListWorkspaces(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	var workspaces []schemas.Workspace
+	if err := database.DB.
+		Where("owner_id = ?", userID).
+		Order("id").
+		Find(&workspaces).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to list workspaces",
+		})
+	}
+
+	workspaceReads := make([]models.WorkspaceInfoRead, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		workspaceReads = append(workspaceReads, newWorkspaceInfoRead(workspace))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(workspaceReads)
+}
+
+func newWorkspaceInfoRead(workspace schemas.Workspace) models.WorkspaceInfoRead {
+	return models.WorkspaceInfoRead{
+		ID:          workspace.ID,
+		OwnerID:     workspace.OwnerID,
+		Name:        workspace.Name,
+		Description: workspace.Description,
+		CreatedAt:   workspace.CreatedAt,
+		UpdatedAt:   workspace.UpdatedAt,
 	}
-	fmt.Println("Item reads: ", itemReads)
-	return c.Status(fiber.StatusOK).JSON(models.WorkspaceRead{
-		Items: itemReads,
-	})
 }
@@ -5,9 +5,11 @@ import (
 	"backend/internal/database/schemas"
 	"backend/internal/middlewares"
 	"backend/internal/models"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
@@ -32,14 +34,22 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	err = db.AutoMigrate(
 		&schemas.User{},
 		&schemas.Workspace{},
+		&schemas.WorkspaceMember{},
 		&schemas.Item{},
+		&schemas.ItemTombstone{},
+		&schemas.HistoryEntry{},
+		&schemas.WorkspaceSnapshot{},
+		&schemas.SnapshotAsset{},
+		&schemas.HistoryAsset{},
+		&schemas.WorkspaceCounter{},
 		&schemas.TextItem{},
 		&schemas.ImageItem{},
+		&schemas.Asset{},
+		&schemas.Blob{},
 		&schemas.TodoListItem{},
 		&schemas.TodoListField{},
 		&schemas.ShapeItem{},
 		&schemas.DrawingItem{},
-		&schemas.Point{},
 	)
 	if err != nil {
 		t.Fatal("failed to migrate test database")
@@ -75,7 +85,7 @@ func TestGetMyWorkspace(t *testing.T) {
 	// Create test items with all types
 	items := []schemas.Item{
 		{
-			WorkspaceID: user.ID,
+			WorkspaceID: user.WorkspaceID,
 			PositionX:   10,
 			PositionY:   999,
 			ZIndex:      1,
@@ -84,32 +94,30 @@ func TestGetMyWorkspace(t *testing.T) {
 			},
 		},
 		{
-			WorkspaceID: user.ID,
+			WorkspaceID: user.WorkspaceID,
 			PositionX:   30,
 			PositionY:   40,
 			ZIndex:      2,
 			ImageItem: &schemas.ImageItem{
-				Bytes: "test-image-bytes",
+				AssetID: "test-asset",
 			},
 		},
 		{
-			WorkspaceID: user.ID,
+			WorkspaceID: user.WorkspaceID,
 			PositionX:   50,
 			PositionY:   60,
 			ZIndex:      3,
 			ListItem: &schemas.TodoListItem{
 				TodoListFields: []schemas.TodoListField{
 					{
-						TextItem: &schemas.TextItem{
-							Content: "Task 1",
-						},
-						Done: false,
+						Content: "Task 1",
+						Done:    false,
 					},
 				},
 			},
 		},
 		{
-			WorkspaceID: user.ID,
+			WorkspaceID: user.WorkspaceID,
 			PositionX:   70,
 			PositionY:   80,
 			ZIndex:      4,
@@ -118,7 +126,7 @@ func TestGetMyWorkspace(t *testing.T) {
 			},
 		},
 		{
-			WorkspaceID: user.ID,
+			WorkspaceID: user.WorkspaceID,
 			PositionX:   90,
 			PositionY:   100,
 			ZIndex:      5,
@@ -133,13 +141,13 @@ func TestGetMyWorkspace(t *testing.T) {
 	
 	
 	w := &schemas.Workspace{}
-	err = database.DB.First(w, "user_id = ?", user.ID).Error
+	err = database.DB.First(w, user.WorkspaceID).Error
 	if err != nil {
 	    t.Fatal("failed to load workspace")
 	}
 
 	for i := range items {
-	    items[i].WorkspaceID = w.UserID
+	    items[i].WorkspaceID = w.ID
 	    err = database.DB.Session(&gorm.Session{FullSaveAssociations: true}).Create(&items[i]).Error
 	    if err != nil {
 	        t.Fatalf("failed to save item %d: %v", i, err)
@@ -205,7 +213,8 @@ func TestGetMyWorkspace(t *testing.T) {
 					case item.TextItem != nil:
 						assert.Equal(t, "Test content", item.TextItem.Content)
 					case item.ImageItem != nil:
-						assert.Equal(t, "test-image-bytes", item.ImageItem.Bytes)
+						assert.Equal(t, "test-asset", item.ImageItem.AssetID)
+						assert.Equal(t, "/assets/test-asset", item.ImageItem.URL)
 					case item.TodoListItem != nil:
 						assert.Equal(t, 1, len(item.TodoListItem))
 						assert.Equal(t, "Task 1", item.TodoListItem[0].TextItemRead.Content)
@@ -219,4 +228,137 @@ func TestGetMyWorkspace(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestListMyWorkspaceItems(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+
+	// Rectangles on the board: (0,0)-(100,50), (500,500)-(700,700) scaled
+	// from 100x100, and (-200,10)-(-50,20) drawn leftwards
+	items := []schemas.Item{
+		{WorkspaceID: user.WorkspaceID, PositionX: 0, PositionY: 0, Width: 100, Height: 50, Scale: 1,
+			TextItem: &schemas.TextItem{Content: "near"}},
+		{WorkspaceID: user.WorkspaceID, PositionX: 500, PositionY: 500, Width: 100, Height: 100, Scale: 2,
+			TextItem: &schemas.TextItem{Content: "far"}},
+		{WorkspaceID: user.WorkspaceID, PositionX: -50, PositionY: 10, Width: -150, Height: 10, Scale: 1,
+			DrawingItem: &schemas.DrawingItem{Points: []schemas.Point{{X: 1, Y: 2}}}},
+	}
+	for i := range items {
+		assert.NoError(t, database.DB.Create(&items[i]).Error)
+	}
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Get("/workspaces/my/items", ListMyWorkspaceItems)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedIDs    []uint
+	}{
+		{"Whole board", "", fiber.StatusOK, []uint{items[0].ID, items[1].ID, items[2].ID}},
+		{"Viewport around the origin", "?bbox=-10,-10,200,200", fiber.StatusOK, []uint{items[0].ID}},
+		{"Scaled item", "?bbox=650,650,800,800", fiber.StatusOK, []uint{items[1].ID}},
+		{"Negative size", "?bbox=-100,0,-60,15", fiber.StatusOK, []uint{items[2].ID}},
+		{"Touching edge", "?bbox=100,50,120,60", fiber.StatusOK, []uint{items[0].ID}},
+		{"Empty area", "?bbox=1000,1000,2000,2000", fiber.StatusOK, []uint{}},
+		{"Too few coordinates", "?bbox=0,0,10", fiber.StatusBadRequest, nil},
+		{"Not a number", "?bbox=0,0,ten,10", fiber.StatusBadRequest, nil},
+		{"Inverted", "?bbox=10,0,0,10", fiber.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/items"+tt.query, nil))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedStatus == fiber.StatusOK {
+				var itemReads []models.ItemRead
+				assert.NoError(t, json.NewDecoder(resp.Body).Decode(&itemReads))
+				ids := make([]uint, 0, len(itemReads))
+				for _, item := range itemReads {
+					ids = append(ids, item.ID)
+				}
+				assert.Equal(t, tt.expectedIDs, ids)
+			}
+		})
+	}
+
+	t.Run("Bounds follow updates", func(t *testing.T) {
+		x := float64(1500)
+		_, _, err := updateItem(context.Background(), database.DB, user.WorkspaceID, items[0].ID, &models.ItemUpdate{PositionX: &x})
+		assert.NoError(t, err)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/items?bbox=1000,0,2000,10", nil))
+		assert.NoError(t, err)
+		var itemReads []models.ItemRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&itemReads))
+		if assert.Equal(t, 1, len(itemReads)) {
+			assert.Equal(t, items[0].ID, itemReads[0].ID)
+		}
+	})
+}
+
+func TestListItemsCreatedLikeTheApp(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+
+	tolerance := strokeTolerance
+	strokeTolerance = 0
+	defer func() { strokeTolerance = tolerance }()
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Post("/workspaces/my/items", AppendMyWorkspaceItem)
+	app.Patch("/workspaces/my/items/:item_id", UpdateMyWorkspaceItem)
+	app.Get("/workspaces/my/items", ListMyWorkspaceItems)
+
+	send := func(method, url, body string) int {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+	listIDs := func(bbox string) []uint {
+		resp, err := app.Test(httptest.NewRequest("GET", "/workspaces/my/items?bbox="+bbox, nil))
+		assert.NoError(t, err)
+		var itemReads []models.ItemRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&itemReads))
+		ids := make([]uint, 0, len(itemReads))
+		for _, item := range itemReads {
+			ids = append(ids, item.ID)
+		}
+		return ids
+	}
+
+	// The app sends no scale, and no size for drawings
+	assert.Equal(t, fiber.StatusCreated, send("POST", "/workspaces/my/items",
+		`{"position_x": 300, "position_y": 300, "width": 100, "height": 50, "text": {"content": "note"}}`))
+	assert.Equal(t, fiber.StatusCreated, send("POST", "/workspaces/my/items",
+		`{"position_x": 1, "position_y": 1, "drawing": {"points": [{"x": 400, "y": 400}, {"x": 450, "y": 420}]}}`))
+
+	assert.Equal(t, []uint{1}, listIDs("350,310,380,330"), "Items without a scale should be at scale 1")
+	assert.Equal(t, []uint{2}, listIDs("420,410,430,415"), "Drawings without a size should be found by their points")
+	assert.Equal(t, []uint{}, listIDs("-10,-10,10,10"))
+
+	assert.Equal(t, fiber.StatusOK, send("PATCH", "/workspaces/my/items/2",
+		`{"drawing": {"points": [{"x": 1000, "y": 1000}, {"x": 1010, "y": 1010}]}}`))
+	assert.Equal(t, []uint{2}, listIDs("1005,1005,1020,1020"), "Bounds should follow new points")
+	assert.Equal(t, []uint{}, listIDs("420,410,430,415"))
+
+	assert.Equal(t, fiber.StatusBadRequest, send("PATCH", "/workspaces/my/items/1", `{"scale": 0}`))
+	assert.Equal(t, fiber.StatusBadRequest, send("POST", "/workspaces/my/items", `{"scale": -1, "text": {"content": "note"}}`))
+}
@@ -0,0 +1,693 @@
+package handlers
+
+import (
+	"backend/config"
+	"backend/internal/assets"
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	middleware "backend/internal/middlewares"
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var (
+	snapshotInterval  = config.C.SnapshotInterval
+	snapshotKeep      = config.C.SnapshotKeep
+	snapshotRetention = config.C.SnapshotRetention
+)
+
+// An item as stored in a snapshot
+type snapshotItem struct {
+	ID      uint   `json:"id"`
+	Version uint64 `json:"version"`
+	models.ItemCreate
+}
+
+// Copy every item of a workspace into a new snapshot
+func takeSnapshot(tx *gorm.DB, workspaceID uint, name string, automatic bool) (schemas.WorkspaceSnapshot, error) {
+	var items []schemas.Item
+	if err := preloadItemRecords(tx, "").
+		Where("workspace_id = ?", workspaceID).
+		Order("id").
+		Find(&items).Error; err != nil {
+		return schemas.WorkspaceSnapshot{}, err
+	}
+
+	counter, err := workspaceCounter(tx, workspaceID)
+	if err != nil {
+		return schemas.WorkspaceSnapshot{}, err
+	}
+
+	stored := make([]snapshotItem, 0, len(items))
+	seen := make(map[string]bool)
+	var snapshotAssets []schemas.SnapshotAsset
+	for _, item := range items {
+		stored = append(stored, snapshotItem{
+			ID:         item.ID,
+			Version:    item.Version,
+			ItemCreate: *itemState(item),
+		})
+		if item.ImageItem != nil && !seen[item.ImageItem.AssetID] {
+			seen[item.ImageItem.AssetID] = true
+			snapshotAssets = append(snapshotAssets, schemas.SnapshotAsset{
+				AssetID:     item.ImageItem.AssetID,
+				WorkspaceID: workspaceID,
+			})
+		}
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return schemas.WorkspaceSnapshot{}, err
+	}
+
+	snapshot := schemas.WorkspaceSnapshot{
+		WorkspaceID: workspaceID,
+		Name:        name,
+		Automatic:   automatic,
+		Revision:    counter.Revision,
+		ItemCount:   len(stored),
+		Items:       string(data),
+		Assets:      snapshotAssets,
+	}
+	return snapshot, tx.Create(&snapshot).Error
+}
+
+// Snapshot the workspace before it changes, unless an automatic snapshot was
+// taken within the snapshot interval or nothing changed since the last one.
+// Must run in the changing transaction, before the change.
+func autoSnapshot(ctx context.Context, tx *gorm.DB, workspaceID uint) error {
+	if snapshotInterval <= 0 {
+		return nil
+	}
+
+	var latest []schemas.WorkspaceSnapshot
+	if err := tx.Select("id", "revision", "created_at").
+		Where("workspace_id = ? AND automatic", workspaceID).
+		Order("id DESC").
+		Limit(1).
+		Find(&latest).Error; err != nil {
+		return err
+	}
+	if len(latest) > 0 && time.Since(latest[0].CreatedAt) < snapshotInterval {
+		return nil
+	}
+
+	counter, err := workspaceCounter(tx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if counter.Revision == 0 || (len(latest) > 0 && counter.Revision == latest[0].Revision) {
+		return nil
+	}
+
+	if _, err := takeSnapshot(tx, workspaceID, "", true); err != nil {
+		return err
+	}
+	return pruneSnapshots(ctx, tx, workspaceID)
+}
+
+// Expire automatic snapshots past the retention period or beyond the number
+// kept
+func pruneSnapshots(ctx context.Context, tx *gorm.DB, workspaceID uint) error {
+	var expired []uint
+	if err := tx.Model(&schemas.WorkspaceSnapshot{}).
+		Where("workspace_id = ? AND automatic AND created_at < ?", workspaceID, time.Now().Add(-snapshotRetention)).
+		Pluck("id", &expired).Error; err != nil {
+		return err
+	}
+
+	var surplus []uint
+	if err := tx.Model(&schemas.WorkspaceSnapshot{}).
+		Where("workspace_id = ? AND automatic", workspaceID).
+		Order("id DESC").
+		Offset(snapshotKeep).
+		Pluck("id", &surplus).Error; err != nil {
+		return err
+	}
+
+	return deleteSnapshots(ctx, tx, append(expired, surplus...))
+}
+
+// Delete snapshots and release the images only they kept
+func deleteSnapshots(ctx context.Context, tx *gorm.DB, snapshotIDs []uint) error {
+	if len(snapshotIDs) == 0 {
+		return nil
+	}
+
+	var assetIDs []string
+	if err := tx.Model(&schemas.SnapshotAsset{}).
+		Where("snapshot_id IN ?", snapshotIDs).
+		Distinct().
+		Pluck("asset_id", &assetIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("snapshot_id IN ?", snapshotIDs).Delete(&schemas.SnapshotAsset{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Delete(&schemas.WorkspaceSnapshot{}, snapshotIDs).Error; err != nil {
+		return err
+	}
+	return assets.Release(ctx, tx, assetIDs)
+}
+
+func findSnapshot(db *gorm.DB, workspaceID uint, snapshotID int) (schemas.WorkspaceSnapshot, error) {
+	var snapshot schemas.WorkspaceSnapshot
+	err := db.First(&snapshot, "id = ? AND workspace_id = ?", snapshotID, workspaceID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return snapshot, fiber.NewError(fiber.StatusNotFound, "snapshot not found")
+	}
+	return snapshot, err
+}
+
+func snapshotItems(snapshot schemas.WorkspaceSnapshot) ([]snapshotItem, error) {
+	var items []snapshotItem
+	err := json.Unmarshal([]byte(snapshot.Items), &items)
+	return items, err
+}
+
+func newSnapshotInfoRead(snapshot schemas.WorkspaceSnapshot) models.SnapshotInfoRead {
+	return models.SnapshotInfoRead{
+		ID:        snapshot.ID,
+		Name:      snapshot.Name,
+		Automatic: snapshot.Automatic,
+		Revision:  snapshot.Revision,
+		ItemCount: snapshot.ItemCount,
+		CreatedAt: snapshot.CreatedAt,
+	}
+}
+
+// Parse the snapshot id path parameter
+func snapshotIDParam(c *fiber.Ctx) (int, error) {
+	snapshotID, err := c.ParamsInt("snapshot_id")
+	if err != nil || snapshotID < 1 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "invalid snapshot id")
+	}
+	return snapshotID, nil
+}
+
+// @Summary List the snapshots of a workspace
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Success 200 {array} models.SnapshotInfoRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/snapshots [get]
+func ListWorkspaceSnapshots(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return listWorkspaceSnapshots(c, uint(workspaceID))
+}
+
+// @Summary List the snapshots of the user's workspace
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.SnapshotInfoRead
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/snapshots [get]
+func ListMyWorkspaceSnapshots(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return listWorkspaceSnapshots(c, workspaceID)
+}
+
+func listWorkspaceSnapshots(c *fiber.Ctx, workspaceID uint) error {
+	var snapshots []schemas.WorkspaceSnapshot
+	if err := database.DB.
+		Omit("items").
+		Where("workspace_id = ?", workspaceID).
+		Order("id DESC").
+		Find(&snapshots).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to list snapshots",
+		})
+	}
+
+	snapshotReads := make([]models.SnapshotInfoRead, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		snapshotReads = append(snapshotReads, newSnapshotInfoRead(snapshot))
+	}
+	return c.Status(fiber.StatusOK).JSON(snapshotReads)
+}
+
+// @Summary Take a named snapshot of a workspace
+// @Description Named snapshots are kept until deleted. Requires at least the editor role
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param snapshot body models.SnapshotCreate true "Snapshot name"
+// @Success 201 {object} models.SnapshotInfoRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/snapshots [post]
+func CreateWorkspaceSnapshot(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return createWorkspaceSnapshot(c, uint(workspaceID))
+}
+
+// @Summary Take a named snapshot of the user's workspace
+// @Description Named snapshots are kept until deleted
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param snapshot body models.SnapshotCreate true "Snapshot name"
+// @Success 201 {object} models.SnapshotInfoRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/snapshots [post]
+func CreateMyWorkspaceSnapshot(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return createWorkspaceSnapshot(c, workspaceID)
+}
+
+func createWorkspaceSnapshot(c *fiber.Ctx, workspaceID uint) error {
+	var snapshotCreate models.SnapshotCreate
+	if err := c.BodyParser(&snapshotCreate); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid request body",
+		})
+	}
+
+	name := strings.TrimSpace(snapshotCreate.Name)
+	if name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "snapshot name cannot be empty",
+		})
+	}
+
+	var snapshot schemas.WorkspaceSnapshot
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		snapshot, err = takeSnapshot(tx, workspaceID, name, false)
+		return err
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to take snapshot",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(newSnapshotInfoRead(snapshot))
+}
+
+// @Summary View a workspace as it was when a snapshot was taken
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param snapshot_id path int true "Snapshot ID"
+// @Success 200 {object} models.SnapshotRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/snapshots/{snapshot_id} [get]
+func GetWorkspaceSnapshot(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return getWorkspaceSnapshot(c, uint(workspaceID))
+}
+
+// @Summary View the user's workspace as it was when a snapshot was taken
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Param snapshot_id path int true "Snapshot ID"
+// @Success 200 {object} models.SnapshotRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/snapshots/{snapshot_id} [get]
+func GetMyWorkspaceSnapshot(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return getWorkspaceSnapshot(c, workspaceID)
+}
+
+func getWorkspaceSnapshot(c *fiber.Ctx, workspaceID uint) error {
+	snapshotID, err := snapshotIDParam(c)
+	if err != nil {
+		return errorResponse(c, err, "invalid snapshot id")
+	}
+
+	snapshot, err := findSnapshot(database.DB, workspaceID, snapshotID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find snapshot")
+	}
+
+	items, err := snapshotItems(snapshot)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to read snapshot",
+		})
+	}
+
+	// Images are looked up for their thumbnails
+	var snapshotAssets []schemas.Asset
+	if err := database.DB.
+		Preload("Variants", orderByID).
+		Where("id IN (?)", database.DB.Model(&schemas.SnapshotAsset{}).
+			Select("asset_id").
+			Where("snapshot_id = ?", snapshot.ID)).
+		Find(&snapshotAssets).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to read snapshot",
+		})
+	}
+	assetsByID := make(map[string]*schemas.Asset, len(snapshotAssets))
+	for i := range snapshotAssets {
+		assetsByID[snapshotAssets[i].ID] = &snapshotAssets[i]
+	}
+
+	itemReads := make([]models.ItemRead, 0, len(items))
+	for _, stored := range items {
+		item := itemFromState(workspaceID, &stored.ItemCreate)
+		item.ID = stored.ID
+		item.Version = stored.Version
+		if item.ImageItem != nil {
+			item.ImageItem.Asset = assetsByID[item.ImageItem.AssetID]
+		}
+		itemReads = append(itemReads, newItemRead(item))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.SnapshotRead{
+		SnapshotInfoRead: newSnapshotInfoRead(snapshot),
+		Items:            itemReads,
+	})
+}
+
+// @Summary Roll a workspace back to a snapshot
+// @Description Bring every item back to its state in the snapshot, in one transaction:
+// @Description items added since are deleted and deleted ones come back. The board
+// @Description is snapshotted first, and the restore can be undone.
+// @Description Requires at least the editor role
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param snapshot_id path int true "Snapshot ID"
+// @Success 200 {object} models.HistoryRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/snapshots/{snapshot_id}/restore [post]
+func RestoreWorkspaceSnapshot(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return restoreWorkspaceSnapshot(c, uint(workspaceID))
+}
+
+// @Summary Roll the user's workspace back to a snapshot
+// @Description Bring every item back to its state in the snapshot, in one transaction:
+// @Description items added since are deleted and deleted ones come back. The board
+// @Description is snapshotted first, and the restore can be undone
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Param snapshot_id path int true "Snapshot ID"
+// @Success 200 {object} models.HistoryRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/snapshots/{snapshot_id}/restore [post]
+func RestoreMyWorkspaceSnapshot(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return restoreWorkspaceSnapshot(c, workspaceID)
+}
+
+func restoreWorkspaceSnapshot(c *fiber.Ctx, workspaceID uint) error {
+	snapshotID, err := snapshotIDParam(c)
+	if err != nil {
+		return errorResponse(c, err, "invalid snapshot id")
+	}
+
+	userID, _ := c.Locals(middleware.IDKey).(uint)
+	response := models.HistoryRead{
+		Items:   []models.ItemRead{},
+		Deleted: []uint{},
+	}
+	var restored []schemas.Item
+	var recreated []bool
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		snapshot, err := findSnapshot(tx, workspaceID, snapshotID)
+		if err != nil {
+			return err
+		}
+		items, err := snapshotItems(snapshot)
+		if err != nil {
+			return err
+		}
+
+		// The board as it is now can be restored in turn
+		if _, err := takeSnapshot(tx, workspaceID, fmt.Sprintf("Before restoring snapshot %d", snapshot.ID), true); err != nil {
+			return err
+		}
+
+		var live []schemas.Item
+		if err := preloadItemRecords(tx, "").
+			Where("workspace_id = ?", workspaceID).
+			Order("id").
+			Find(&live).Error; err != nil {
+			return err
+		}
+		liveByID := make(map[uint]*schemas.Item, len(live))
+		for i := range live {
+			liveByID[live[i].ID] = &live[i]
+		}
+
+		var changes []itemChange
+		for _, stored := range items {
+			current := liveByID[stored.ID]
+			delete(liveByID, stored.ID)
+
+			var from *models.ItemCreate
+			if current != nil {
+				from = itemState(*current)
+				changed, err := stateChanged(from, &stored.ItemCreate)
+				if err != nil {
+					return err
+				}
+				if !changed {
+					continue
+				}
+			}
+
+			item, err := restoreItemState(c.Context(), tx, workspaceID, userID, stored.ID, from, &stored.ItemCreate, stored.Version)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, newItemChange(current, item))
+			restored = append(restored, *item)
+			recreated = append(recreated, current == nil)
+		}
+
+		// Whatever is left was added after the snapshot
+		for _, current := range live {
+			if _, added := liveByID[current.ID]; !added {
+				continue
+			}
+			change, err := deleteItem(tx, workspaceID, current.ID, nil)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, change)
+			response.Deleted = append(response.Deleted, current.ID)
+		}
+
+		if err := recordHistory(tx, workspaceID, userID, changes); err != nil {
+			return err
+		}
+		return pruneSnapshots(c.Context(), tx, workspaceID)
+	})
+	if err != nil {
+		return errorResponse(c, err, "failed to restore snapshot")
+	}
+
+	for i := range restored {
+		response.Items = append(response.Items, newItemRead(restored[i]))
+		eventType := realtime.ItemUpdated
+		if recreated[i] {
+			eventType = realtime.ItemCreated
+		}
+		publishItemEvent(eventType, workspaceID, restored[i].ID, &restored[i])
+	}
+	for _, id := range response.Deleted {
+		publishItemEvent(realtime.ItemDeleted, workspaceID, id, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// @Summary Delete a snapshot of a workspace
+// @Description Requires the owner role
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param snapshot_id path int true "Snapshot ID"
+// @Success 200 {object} models.MessageResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/snapshots/{snapshot_id} [delete]
+func DeleteWorkspaceSnapshot(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return deleteWorkspaceSnapshot(c, uint(workspaceID))
+}
+
+// @Summary Delete a snapshot of the user's workspace
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Param snapshot_id path int true "Snapshot ID"
+// @Success 200 {object} models.MessageResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/snapshots/{snapshot_id} [delete]
+func DeleteMyWorkspaceSnapshot(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return deleteWorkspaceSnapshot(c, workspaceID)
+}
+
+func deleteWorkspaceSnapshot(c *fiber.Ctx, workspaceID uint) error {
+	snapshotID, err := snapshotIDParam(c)
+	if err != nil {
+		return errorResponse(c, err, "invalid snapshot id")
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		snapshot, err := findSnapshot(tx, workspaceID, snapshotID)
+		if err != nil {
+			return err
+		}
+		return deleteSnapshots(c.Context(), tx, []uint{snapshot.ID})
+	})
+	if err != nil {
+		return errorResponse(c, err, "failed to delete snapshot")
+	}
+	collectBlobs(c.Context())
+
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "snapshot deleted successfully",
+	})
+}
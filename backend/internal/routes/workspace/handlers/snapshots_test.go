@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/models"
+	"backend/internal/storage"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMyWorkspaceSnapshots(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	store, err := storage.NewFileStore(t.TempDir())
+	assert.NoError(t, err)
+	storage.Default = store
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Post("/workspaces/my/images", UploadMyWorkspaceImage)
+	app.Post("/workspaces/my/items", AppendMyWorkspaceItem)
+	app.Patch("/workspaces/my/items/:item_id", UpdateMyWorkspaceItem)
+	app.Delete("/workspaces/my/items/:item_id", DeleteMyWorkspaceItem)
+	app.Post("/workspaces/my/undo", UndoMyWorkspace)
+	app.Get("/workspaces/my/snapshots", ListMyWorkspaceSnapshots)
+	app.Post("/workspaces/my/snapshots", CreateMyWorkspaceSnapshot)
+	app.Get("/workspaces/my/snapshots/:snapshot_id", GetMyWorkspaceSnapshot)
+	app.Delete("/workspaces/my/snapshots/:snapshot_id", DeleteMyWorkspaceSnapshot)
+	app.Post("/workspaces/my/snapshots/:snapshot_id/restore", RestoreMyWorkspaceSnapshot)
+
+	send := func(method, path, payload string) *http.Response {
+		req := httptest.NewRequest(method, path, strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+	listSnapshots := func() []models.SnapshotInfoRead {
+		var snapshots []models.SnapshotInfoRead
+		resp := send("GET", "/workspaces/my/snapshots", "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&snapshots))
+		return snapshots
+	}
+	countRows := func(model any) int64 {
+		var count int64
+		assert.NoError(t, database.DB.Model(model).Count(&count).Error)
+		return count
+	}
+
+	// An image to check that snapshots keep it
+	var encoded bytes.Buffer
+	assert.NoError(t, png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 10, 10))))
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	part, _ := w.CreateFormFile("image", "image.png")
+	part.Write(encoded.Bytes())
+	w.Close()
+	req := httptest.NewRequest("POST", "/workspaces/my/images", body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	var asset models.AssetRead
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&asset))
+
+	assert.Equal(t, fiber.StatusCreated, send("POST", "/workspaces/my/items", `{"text": {"content": "Note"}, "position_x": 1}`).StatusCode)
+	assert.Equal(t, fiber.StatusCreated, send("POST", "/workspaces/my/items", fmt.Sprintf(`{"image": {"asset_id": %q}}`, asset.ID)).StatusCode)
+
+	t.Run("Changes are snapshotted automatically", func(t *testing.T) {
+		snapshots := listSnapshots()
+		if assert.Equal(t, 1, len(snapshots), "An empty board needs no snapshot") {
+			assert.True(t, snapshots[0].Automatic)
+			assert.Equal(t, 1, snapshots[0].ItemCount, "The board is snapshotted before it changes")
+		}
+	})
+
+	var named models.SnapshotInfoRead
+	t.Run("Take a named snapshot", func(t *testing.T) {
+		resp := send("POST", "/workspaces/my/snapshots", `{"name": "  "}`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+		resp = send("POST", "/workspaces/my/snapshots", `{"name": "Before the redesign"}`)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&named))
+		assert.Equal(t, "Before the redesign", named.Name)
+		assert.False(t, named.Automatic)
+		assert.Equal(t, 2, named.ItemCount)
+		assert.NotZero(t, named.Revision)
+	})
+
+	t.Run("Snapshots keep deleted images", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, send("PATCH", "/workspaces/my/items/1", `{"text": {"content": "Edited"}}`).StatusCode)
+		assert.Equal(t, fiber.StatusOK, send("DELETE", "/workspaces/my/items/2", "").StatusCode)
+		assert.Equal(t, fiber.StatusCreated, send("POST", "/workspaces/my/items", `{"shape": {"name": "circle"}}`).StatusCode)
+
+		assert.Equal(t, 2, len(listSnapshots()), "Changes within the interval are not snapshotted")
+		assert.NotZero(t, countRows(&schemas.Asset{}), "Snapshots should keep the image")
+	})
+
+	t.Run("View a snapshot", func(t *testing.T) {
+		resp := send("GET", fmt.Sprintf("/workspaces/my/snapshots/%d", named.ID), "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var snapshot models.SnapshotRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&snapshot))
+		assert.Equal(t, named.ID, snapshot.ID)
+		if assert.Equal(t, 2, len(snapshot.Items)) {
+			assert.Equal(t, uint(1), snapshot.Items[0].ID)
+			assert.Equal(t, "Note", snapshot.Items[0].TextItem.Content)
+			assert.Equal(t, asset.ID, snapshot.Items[1].ImageItem.AssetID)
+		}
+
+		resp = send("GET", "/workspaces/my/snapshots/999", "")
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Restore a snapshot", func(t *testing.T) {
+		resp := send("POST", fmt.Sprintf("/workspaces/my/snapshots/%d/restore", named.ID), "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var restored models.HistoryRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&restored))
+		assert.Equal(t, []uint{3}, restored.Deleted, "Items added since should be removed")
+		assert.Equal(t, 2, len(restored.Items))
+
+		var items []schemas.Item
+		assert.NoError(t, preloadItemRecords(database.DB, "").Order("id").Find(&items).Error)
+		if assert.Equal(t, 2, len(items)) {
+			assert.Equal(t, "Note", items[0].TextItem.Content)
+			if assert.NotNil(t, items[1].ImageItem, "Deleted image should come back") {
+				assert.Equal(t, asset.ID, items[1].ImageItem.AssetID)
+			}
+		}
+		assert.Equal(t, 3, len(listSnapshots()), "The board should be snapshotted before a restore")
+	})
+
+	t.Run("A restore can be undone", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, send("POST", "/workspaces/my/undo", "").StatusCode)
+
+		var shapes int64
+		database.DB.Model(&schemas.Item{}).Joins("JOIN shape_items ON shape_items.item_id = items.id").Count(&shapes)
+		assert.Equal(t, int64(1), shapes)
+		assert.Equal(t, int64(2), countRows(&schemas.Item{}))
+	})
+
+	t.Run("Automatic snapshots are pruned", func(t *testing.T) {
+		interval, keep := snapshotInterval, snapshotKeep
+		snapshotInterval, snapshotKeep = time.Nanosecond, 1
+		defer func() { snapshotInterval, snapshotKeep = interval, keep }()
+
+		assert.Equal(t, fiber.StatusOK, send("PATCH", "/workspaces/my/items/1", `{"position_y": 5}`).StatusCode)
+		assert.Equal(t, fiber.StatusOK, send("PATCH", "/workspaces/my/items/1", `{"position_y": 6}`).StatusCode)
+
+		automatic, kept := 0, 0
+		for _, snapshot := range listSnapshots() {
+			if snapshot.Automatic {
+				automatic++
+			} else {
+				kept++
+			}
+		}
+		assert.Equal(t, 1, automatic)
+		assert.Equal(t, 1, kept, "Named snapshots should be kept")
+	})
+
+	t.Run("Delete a snapshot", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, send("DELETE", fmt.Sprintf("/workspaces/my/snapshots/%d", named.ID), "").StatusCode)
+		assert.Equal(t, fiber.StatusNotFound, send("GET", fmt.Sprintf("/workspaces/my/snapshots/%d", named.ID), "").StatusCode)
+	})
+}
@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"backend/config"
+	"backend/internal/assets"
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	middleware "backend/internal/middlewares"
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var trashRetention = config.C.TrashRetention
+
+const (
+	trashPurgeInterval = time.Hour
+	trashPurgeBatch    = 500
+)
+
+// Load a trashed item with its sub-records, locked until the transaction ends
+func lockTrashedItem(tx *gorm.DB, workspaceID, itemID uint) (schemas.Item, error) {
+	var item schemas.Item
+	err := preloadItemRecords(tx.Unscoped(), "").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&item, "id = ? AND workspace_id = ? AND deleted_at IS NOT NULL", itemID, workspaceID).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return item, fiber.NewError(fiber.StatusNotFound, "item not found in trash")
+	}
+	return item, err
+}
+
+// Take an item out of the trash and put it back on the board, at a version
+// past minVersion and newer than any copy a client kept
+func restoreTrashedItem(tx *gorm.DB, workspaceID, itemID uint, minVersion uint64) (schemas.Item, error) {
+	item, err := lockTrashedItem(tx, workspaceID, itemID)
+	if err != nil {
+		return item, err
+	}
+
+	item.DeletedAt = gorm.DeletedAt{}
+	item.Version = max(item.Version, minVersion) + 1
+	if err := tx.Unscoped().Omit(clause.Associations).Save(&item).Error; err != nil {
+		return item, err
+	}
+	err = tx.
+		Where("workspace_id = ? AND item_id = ?", workspaceID, itemID).
+		Delete(&schemas.ItemTombstone{}).
+		Error
+	return item, err
+}
+
+// Permanently delete items with their sub-records and release the images
+// only they showed
+func purgeItems(ctx context.Context, tx *gorm.DB, items []schemas.Item) error {
+	var assetIDs []string
+	for _, item := range items {
+		if err := schemas.PurgeItem(tx, item.WorkspaceID, item.ID); err != nil {
+			return err
+		}
+		if item.ImageItem != nil {
+			assetIDs = append(assetIDs, item.ImageItem.AssetID)
+		}
+	}
+	return assets.Release(ctx, tx, assetIDs)
+}
+
+// Permanently delete the items trashed before a point in time and return how
+// many were purged
+func PurgeTrash(ctx context.Context, db *gorm.DB, before time.Time) (int, error) {
+	purged := 0
+	for {
+		var items []schemas.Item
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Unscoped().
+				Preload("ImageItem").
+				Where("deleted_at < ?", before).
+				Order("deleted_at").
+				Limit(trashPurgeBatch).
+				Find(&items).Error; err != nil {
+				return err
+			}
+			return purgeItems(ctx, tx, items)
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged += len(items)
+		if len(items) < trashPurgeBatch {
+			return purged, nil
+		}
+	}
+}
+
+// Remove the blobs that a committed transaction left unused. Whatever fails
+// here is left to the purger's next sweep.
+func collectBlobs(ctx context.Context) {
+	if _, err := assets.CollectGarbage(ctx, database.DB); err != nil {
+		log.Error().Err(err).Msg("failed to collect unused blobs")
+	}
+}
+
+// Purge expired items from the trash every trashPurgeInterval until the
+// context is done, unless trashed items are kept for good, and sweep the
+// blobs left unused by then.
+func RunTrashPurger(ctx context.Context) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+	for {
+		if trashRetention > 0 {
+			purged, err := PurgeTrash(ctx, database.DB.WithContext(ctx), time.Now().Add(-trashRetention))
+			if err != nil {
+				log.Error().Err(err).Msg("failed to purge trash")
+			} else if purged > 0 {
+				log.Info().Int("items", purged).Msg("purged trash")
+			}
+		}
+		if collected, err := assets.CollectGarbage(ctx, database.DB.WithContext(ctx)); err != nil {
+			log.Error().Err(err).Msg("failed to collect unused blobs")
+		} else if collected > 0 {
+			log.Info().Int("blobs", collected).Msg("collected unused blobs")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Parse the trashed item id path parameter
+func trashItemIDParam(c *fiber.Ctx) (uint, error) {
+	itemID, err := c.ParamsInt("item_id")
+	if err != nil || itemID < 1 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "invalid item id")
+	}
+	return uint(itemID), nil
+}
+
+// @Summary List the trashed items of a workspace
+// @Description Deleted items stay in the trash until restored or purged, newest first
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Success 200 {array} models.TrashItemRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/trash [get]
+func ListWorkspaceTrash(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return listWorkspaceTrash(c, uint(workspaceID))
+}
+
+// @Summary List the trashed items of the user's workspace
+// @Description Deleted items stay in the trash until restored or purged, newest first
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.TrashItemRead
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/trash [get]
+func ListMyWorkspaceTrash(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return listWorkspaceTrash(c, workspaceID)
+}
+
+func listWorkspaceTrash(c *fiber.Ctx, workspaceID uint) error {
+	var items []schemas.Item
+	if err := preloadItemRecords(database.DB.Unscoped(), "").
+		Where("workspace_id = ? AND deleted_at IS NOT NULL", workspaceID).
+		Order("deleted_at DESC").
+		Find(&items).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to list trash",
+		})
+	}
+
+	trashReads := make([]models.TrashItemRead, 0, len(items))
+	for _, item := range items {
+		trashRead := models.TrashItemRead{
+			ItemRead:  newItemRead(item),
+			DeletedAt: item.DeletedAt.Time,
+		}
+		if trashRetention > 0 {
+			purgeAt := item.DeletedAt.Time.Add(trashRetention)
+			trashRead.PurgeAt = &purgeAt
+		}
+		trashReads = append(trashReads, trashRead)
+	}
+	return c.Status(fiber.StatusOK).JSON(trashReads)
+}
+
+// @Summary Restore a trashed item of a workspace
+// @Description Put the item back on the board; this can be undone. Requires at least the editor role
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param item_id path int true "Item ID"
+// @Success 200 {object} models.ItemRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/trash/{item_id}/restore [post]
+func RestoreWorkspaceTrashItem(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return restoreWorkspaceTrashItem(c, uint(workspaceID))
+}
+
+// @Summary Restore a trashed item of the user's workspace
+// @Description Put the item back on the board; this can be undone
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Param item_id path int true "Item ID"
+// @Success 200 {object} models.ItemRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/trash/{item_id}/restore [post]
+func RestoreMyWorkspaceTrashItem(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return restoreWorkspaceTrashItem(c, workspaceID)
+}
+
+func restoreWorkspaceTrashItem(c *fiber.Ctx, workspaceID uint) error {
+	itemID, err := trashItemIDParam(c)
+	if err != nil {
+		return errorResponse(c, err, "invalid item id")
+	}
+
+	userID, _ := c.Locals(middleware.IDKey).(uint)
+	var item schemas.Item
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := autoSnapshot(c.Context(), tx, workspaceID); err != nil {
+			return err
+		}
+		var err error
+		if item, err = restoreTrashedItem(tx, workspaceID, itemID, 0); err != nil {
+			return err
+		}
+		return recordHistory(tx, workspaceID, userID, []itemChange{newItemChange(nil, &item)})
+	})
+	if err != nil {
+		return errorResponse(c, err, "failed to restore item")
+	}
+
+	publishItemEvent(realtime.ItemCreated, workspaceID, item.ID, &item)
+
+	c.Set(fiber.HeaderETag, itemETag(item.Version))
+	return c.Status(fiber.StatusOK).JSON(newItemRead(item))
+}
+
+// @Summary Permanently delete a trashed item of a workspace
+// @Description The item can no longer be restored. Requires at least the editor role
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param item_id path int true "Item ID"
+// @Success 200 {object} models.MessageResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/trash/{item_id} [delete]
+func PurgeWorkspaceTrashItem(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return purgeWorkspaceTrashItem(c, uint(workspaceID))
+}
+
+// @Summary Permanently delete a trashed item of the user's workspace
+// @Description The item can no longer be restored
+// @Tags workspaces
+// @Produce json
+// @Security BearerAuth
+// @Param item_id path int true "Item ID"
+// @Success 200 {object} models.MessageResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/trash/{item_id} [delete]
+func PurgeMyWorkspaceTrashItem(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return purgeWorkspaceTrashItem(c, workspaceID)
+}
+
+func purgeWorkspaceTrashItem(c *fiber.Ctx, workspaceID uint) error {
+	itemID, err := trashItemIDParam(c)
+	if err != nil {
+		return errorResponse(c, err, "invalid item id")
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		item, err := lockTrashedItem(tx, workspaceID, itemID)
+		if err != nil {
+			return err
+		}
+		return purgeItems(c.Context(), tx, []schemas.Item{item})
+	})
+	if err != nil {
+		return errorResponse(c, err, "failed to purge item")
+	}
+	collectBlobs(c.Context())
+
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "item purged successfully",
+	})
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMyWorkspaceTrash(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Post("/workspaces/my/items", AppendMyWorkspaceItem)
+	app.Delete("/workspaces/my/items/:item_id", DeleteMyWorkspaceItem)
+	app.Post("/workspaces/my/undo", UndoMyWorkspace)
+	app.Get("/workspaces/my/trash", ListMyWorkspaceTrash)
+	app.Post("/workspaces/my/trash/:item_id/restore", RestoreMyWorkspaceTrashItem)
+	app.Delete("/workspaces/my/trash/:item_id", PurgeMyWorkspaceTrashItem)
+
+	send := func(method, path, payload string) *http.Response {
+		req := httptest.NewRequest(method, path, strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+	listTrash := func() []models.TrashItemRead {
+		var trash []models.TrashItemRead
+		resp := send("GET", "/workspaces/my/trash", "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&trash))
+		return trash
+	}
+	countRows := func(model any, query string, args ...any) int64 {
+		var count int64
+		assert.NoError(t, database.DB.Unscoped().Model(model).Where(query, args...).Count(&count).Error)
+		return count
+	}
+	storedPoints := func(itemID uint) schemas.Points {
+		var drawing schemas.DrawingItem
+		assert.NoError(t, database.DB.Where("item_id = ?", itemID).First(&drawing).Error)
+		return drawing.Points
+	}
+
+	assert.Equal(t, fiber.StatusCreated, send("POST", "/workspaces/my/items", `{"drawing": {"points": [{"x": 1, "y": 2}, {"x": 3, "y": 4}]}}`).StatusCode)
+	assert.Equal(t, fiber.StatusCreated, send("POST", "/workspaces/my/items", `{"todo_list": [{"text": {"content": "Task"}}]}`).StatusCode)
+	assert.Equal(t, fiber.StatusOK, send("DELETE", "/workspaces/my/items/1", "").StatusCode)
+
+	t.Run("Deleted items go to the trash", func(t *testing.T) {
+		assert.Equal(t, int64(0), countRows(&schemas.Item{}, "id = 1 AND deleted_at IS NULL"))
+		assert.Equal(t, 2, len(storedPoints(1)), "Sub-records are kept in the trash")
+
+		trash := listTrash()
+		if assert.Equal(t, 1, len(trash)) {
+			assert.Equal(t, uint(1), trash[0].ID)
+			assert.Equal(t, 2, len(trash[0].DrawingItem.Points))
+			if assert.NotNil(t, trash[0].PurgeAt) {
+				assert.Equal(t, trashRetention, trash[0].PurgeAt.Sub(trash[0].DeletedAt))
+			}
+		}
+	})
+
+	t.Run("Restore an item", func(t *testing.T) {
+		resp := send("POST", "/workspaces/my/trash/1/restore", "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var item models.ItemRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&item))
+		assert.Equal(t, uint(1), item.ID)
+		assert.Equal(t, uint64(2), item.Version)
+		assert.Equal(t, `"2"`, resp.Header.Get(fiber.HeaderETag))
+
+		assert.Empty(t, listTrash())
+		assert.Equal(t, int64(0), countRows(&schemas.ItemTombstone{}, "item_id = 1"))
+
+		resp = send("POST", "/workspaces/my/trash/1/restore", "")
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "Only trashed items can be restored")
+	})
+
+	t.Run("A restore can be undone", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, send("POST", "/workspaces/my/undo", "").StatusCode)
+		assert.Equal(t, 1, len(listTrash()))
+	})
+
+	t.Run("Purge an item", func(t *testing.T) {
+		resp := send("DELETE", "/workspaces/my/trash/2", "")
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "Items on the board cannot be purged")
+
+		resp = send("DELETE", "/workspaces/my/trash/1", "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Empty(t, listTrash())
+		assert.Equal(t, int64(0), countRows(&schemas.Item{}, "id = 1"))
+		assert.Equal(t, int64(0), countRows(&schemas.DrawingItem{}, "item_id = 1"))
+	})
+
+	t.Run("Undo a delete after the purge", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, send("POST", "/workspaces/my/undo", "").StatusCode)
+		assert.Equal(t, int64(1), countRows(&schemas.Item{}, "id = 1 AND deleted_at IS NULL"), "Purged items are made again")
+		assert.Equal(t, 2, len(storedPoints(1)))
+	})
+
+	t.Run("Expired items are purged", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, send("DELETE", "/workspaces/my/items/1", "").StatusCode)
+		assert.Equal(t, fiber.StatusOK, send("DELETE", "/workspaces/my/items/2", "").StatusCode)
+		assert.NoError(t, database.DB.Unscoped().Model(&schemas.Item{}).
+			Where("id = 2").
+			Update("deleted_at", time.Now().Add(-2*trashRetention)).Error)
+
+		purged, err := PurgeTrash(context.Background(), database.DB, time.Now().Add(-trashRetention))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, purged)
+
+		trash := listTrash()
+		if assert.Equal(t, 1, len(trash), "Recently trashed items are kept") {
+			assert.Equal(t, uint(1), trash[0].ID)
+		}
+		assert.Equal(t, int64(0), countRows(&schemas.TodoListItem{}, "item_id = 2"))
+		assert.Equal(t, int64(0), countRows(&schemas.TodoListField{}, "todo_list_item_id = 2"))
+	})
+}
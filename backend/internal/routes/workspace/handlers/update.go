@@ -1,33 +1,42 @@
 package handlers
 
 import (
+	"backend/internal/assets"
 	"backend/internal/database"
 	"backend/internal/database/schemas"
 	middleware "backend/internal/middlewares"
 	"backend/internal/models"
+	"backend/internal/realtime"
+	"context"
 	"errors"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // @Summary Append a workspace item
+// @Description Requires at least the editor role
 // @Tags workspaces
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param item body models.ItemCreate true "Item to create"
-// @Param user_id path int true "User ID"
+// @Param workspace_id path int true "Workspace ID"
 // @Success 201 {object} models.CreatedResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /workspaces/{user_id}/items [post]
+// @Router /workspaces/{workspace_id}/items [post]
 func AppendWorkspaceItem(c *fiber.Ctx) error {
-    // Validate user_id
-    userID, err := c.ParamsInt("user_id")
-    if err != nil || userID < 1 {
+    // Validate workspace_id
+    workspaceID, err := c.ParamsInt("workspace_id")
+    if err != nil || workspaceID < 1 {
         return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-            Error: "invalid user ID",
+            Error: "invalid workspace id",
         })
     }
 
@@ -39,133 +48,73 @@ func AppendWorkspaceItem(c *fiber.Ctx) error {
         })
     }
 
-	// Validate exactly one item type is provided
-	itemTypes := 0
-	if itemCreate.TextItem != nil { itemTypes++ }
-	if itemCreate.ImageItem != nil { itemTypes++ }
-	if itemCreate.TodoList != nil { itemTypes++ }
-	if itemCreate.ShapeItem != nil { itemTypes++ }
-	if itemCreate.DrawingItem != nil { itemTypes++ }
-	
-	if itemTypes != 1 {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error: "must provide exactly one item type (text, image, todo list, shape, or drawing)",
-		})
-	}
-
-	// Create base item
-	item := schemas.Item{
-		WorkspaceID: uint(userID),
-		PositionX:   itemCreate.PositionX,
-		PositionY:   itemCreate.PositionY,
-		ZIndex:      itemCreate.ZIndex,
-		Color:       itemCreate.Color,
-		Scale:       itemCreate.Scale,
-	}
+	userID, _ := c.Locals(middleware.IDKey).(uint)
 
-	// Handle different item types except ShapeItem
-	switch {
-	case itemCreate.TextItem != nil:
-		
-		if itemCreate.TextItem.Content == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-				Error: "cannot create am empty text item",
-			})
-		}
-		
-		item.TextItem = &schemas.TextItem{
-			Content: itemCreate.TextItem.Content,
-		}
-		
-	case itemCreate.ImageItem != nil:
-		item.ImageItem = &schemas.ImageItem{
-			Bytes: itemCreate.ImageItem.Bytes,
-		}
-		
-	case itemCreate.TodoList != nil:
-		var fields []schemas.TodoListField
-		for _, f := range *itemCreate.TodoList {
-			fields = append(fields, schemas.TodoListField{
-				Done: f.Done,
-				TextItem: &schemas.TextItem{
-					Content: f.TextItem.Content,
-				},
-			})
-		}
-		item.ListItem = &schemas.TodoListItem{
-			TodoListFields: fields,
-		}
-	case itemCreate.ShapeItem != nil:
-		item.ShapeItem = &schemas.ShapeItem{
-			Name:        itemCreate.ShapeItem.Name,
-		}
-	case itemCreate.DrawingItem != nil:
-		points := make([]schemas.Point, 0, len(itemCreate.DrawingItem.Points))
-		for _, p := range itemCreate.DrawingItem.Points {
-			points = append(points, schemas.Point{
-				X: p.X,
-				Y: p.Y,
-			})
-		}
-		item.DrawingItem = &schemas.DrawingItem{
-			Points: points,
-		}
-	}
+    // Validate the item and build it with its typed sub-record
+    item, imageAsset, err := newItem(database.DB, uint(workspaceID), userID, &itemCreate)
+    if err != nil {
+        return errorResponse(c, err, "failed to create item")
+    }
 
-    // Find workspace
-    var workspace schemas.Workspace
-    if err := database.DB.
-        Preload("Items").
-        First(&workspace, "user_id = ?", userID).
-        Error; err != nil {
-        
-        if errors.Is(err, gorm.ErrRecordNotFound) {
-            return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
-                Error: "workspace not found",
-            })
+    // Create item in the workspace and make it undoable
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        // Verify workspace exists
+        var workspace schemas.Workspace
+        if err := tx.Select("id").First(&workspace, item.WorkspaceID).Error; err != nil {
+            if errors.Is(err, gorm.ErrRecordNotFound) {
+                return fiber.NewError(fiber.StatusNotFound, "workspace not found")
+            }
+            return err
         }
-        return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-            Error: "failed to find workspace",
-        })
+        if err := autoSnapshot(c.Context(), tx, item.WorkspaceID); err != nil {
+            return err
+        }
+        if err := tx.Create(&item).Error; err != nil {
+            return err
+        }
+        return recordHistory(tx, item.WorkspaceID, userID, []itemChange{newItemChange(nil, &item)})
+    })
+    if err != nil {
+        return errorResponse(c, err, "failed to create item")
     }
 
-    // Create item
-    if err := database.DB.Create(&item).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-            Error: "failed to create item",
-        })
+    if imageAsset != nil {
+        item.ImageItem.Asset = imageAsset
     }
 
-    // Associate with workspace
-    if err := database.DB.Model(&workspace).Association("Items").Append(&item); err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-            Error: "failed to associate item with workspace",
-        })
-    }
+    publishItemEvent(realtime.ItemCreated, item.WorkspaceID, item.ID, &item)
 
+    c.Set(fiber.HeaderETag, itemETag(item.Version))
     return c.Status(fiber.StatusCreated).JSON(models.CreatedResponse{
         Message: "item created successfully",
         ID:      item.ID,
     })
 }
 
-// @Summary Delete a workspace item by item ID and user ID
+// @Summary Delete a workspace item by item ID and workspace ID
+// @Description Requires at least the editor role
 // @Tags workspaces
 // @Accept json
 // @Produce json
-// @Param user_id path int true "User ID"
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
 // @Param item_id path int true "Item ID"
+// @Param If-Match header string false "ETag of the item the change is based on"
+// @Param version query int false "Version the change is based on, if If-Match is not sent"
 // @Success 200 {object} models.MessageResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ConflictResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /workspaces/{user_id}/items/{item_id} [delete]
+// @Router /workspaces/{workspace_id}/items/{item_id} [delete]
 func DeleteWorkspaceItem(c *fiber.Ctx) error {
     // Validate parameters
-    userID, err := c.ParamsInt("user_id")
-    if err != nil || userID < 1 {
+    workspaceID, err := c.ParamsInt("workspace_id")
+    if err != nil || workspaceID < 1 {
         return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-            Error: "invalid user id",
+            Error: "invalid workspace id",
         })
     }
 
@@ -176,40 +125,40 @@ func DeleteWorkspaceItem(c *fiber.Ctx) error {
         })
     }
 
+    version, err := expectedVersion(c, nil)
+    if err != nil {
+        return errorResponse(c, err, "failed to delete item")
+    }
+    userID, _ := c.Locals(middleware.IDKey).(uint)
+
     // Execute in transaction
     err = database.DB.Transaction(func(tx *gorm.DB) error {
         // Verify workspace exists
         var workspace schemas.Workspace
-        if err := tx.Select("user_id").First(&workspace, "user_id = ?", userID).Error; err != nil {
+        if err := tx.Select("id").First(&workspace, workspaceID).Error; err != nil {
             if errors.Is(err, gorm.ErrRecordNotFound) {
                 return fiber.NewError(fiber.StatusNotFound, "workspace not found")
             }
             return err
         }
-
-        // Delete item with workspace verification
-        result := tx.Where("id = ? AND workspace_id = ?", itemID, userID).Delete(&schemas.Item{})
-        if result.Error != nil {
-            return result.Error
-        }
-        
-        if result.RowsAffected == 0 {
-            return fiber.NewError(fiber.StatusNotFound, "item not found in workspace")
+        if err := autoSnapshot(c.Context(), tx, uint(workspaceID)); err != nil {
+            return err
         }
 
-        return nil
+        change, err := deleteItem(tx, uint(workspaceID), uint(itemID), version)
+        if err != nil {
+            return err
+        }
+        return recordHistory(tx, uint(workspaceID), userID, []itemChange{change})
     })
 
     // Handle transaction errors
     if err != nil {
-        if e, ok := err.(*fiber.Error); ok {
-            return c.Status(e.Code).JSON(models.ErrorResponse{Error: e.Message})
-        }
-        return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-            Error: "failed to delete item",
-        })
+        return errorResponse(c, err, "failed to delete item")
     }
 
+    publishItemEvent(realtime.ItemDeleted, uint(workspaceID), uint(itemID), nil)
+
     return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
         Message: "item deleted successfully",
     })
@@ -235,6 +184,11 @@ func AppendMyWorkspaceItem(c *fiber.Ctx) error {
 		})
 	}
 
+    workspaceID, err := myWorkspaceID(userID)
+    if err != nil {
+        return errorResponse(c, err, "failed to find workspace")
+    }
+
     // Parse request
     var itemCreate models.ItemCreate
     if err := c.BodyParser(&itemCreate); err != nil {
@@ -243,112 +197,41 @@ func AppendMyWorkspaceItem(c *fiber.Ctx) error {
         })
     }
 
-    // Validate exactly one item type is provided
-    itemTypes := 0
-    if itemCreate.TextItem != nil { itemTypes++ }
-    if itemCreate.ImageItem != nil { itemTypes++ }
-    if itemCreate.TodoList != nil { itemTypes++ }
-    if itemCreate.ShapeItem != nil { itemTypes++ }
-    if itemCreate.DrawingItem != nil { itemTypes++ }
-    
-    if itemTypes != 1 {
-        return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-            Error: "must provide exactly one item type (text, image, or todo list)",
-        })
-    }
-
-    // Create base item
-    item := schemas.Item{
-        WorkspaceID: uint(userID),
-        PositionX:   itemCreate.PositionX,
-        PositionY:   itemCreate.PositionY,
-        ZIndex:      itemCreate.ZIndex,
-        Color:       itemCreate.Color,
-        Scale:       itemCreate.Scale,
-        Width:       itemCreate.Width,
-        Height:      itemCreate.Height,
+    // Validate the item and build it with its typed sub-record
+    item, imageAsset, err := newItem(database.DB, workspaceID, userID, &itemCreate)
+    if err != nil {
+        return errorResponse(c, err, "failed to create item")
     }
 
-    // Handle different item types except ShapeItem
-    switch {
-    case itemCreate.TextItem != nil:
-		
-		if itemCreate.TextItem.Content == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-				Error: "cannot create am empty text item",
-			})
-		}
-		
-        item.TextItem = &schemas.TextItem{
-            Content: itemCreate.TextItem.Content,
-        }
-        
-    case itemCreate.ImageItem != nil:
-        item.ImageItem = &schemas.ImageItem{
-            Bytes: itemCreate.ImageItem.Bytes,
-        }
-        
-    case itemCreate.TodoList != nil:
-        var fields []schemas.TodoListField
-        for _, f := range *itemCreate.TodoList {
-            fields = append(fields, schemas.TodoListField{
-                Done: f.Done,
-                TextItem: &schemas.TextItem{
-                    Content: f.TextItem.Content,
-                },
-            })
-        }
-        item.ListItem = &schemas.TodoListItem{
-            TodoListFields: fields,
-        }
-    case itemCreate.ShapeItem != nil:
-        item.ShapeItem = &schemas.ShapeItem{
-            Name:        itemCreate.ShapeItem.Name,
-        }
-    case itemCreate.DrawingItem != nil:
-        var points []schemas.Point
-        for _, p := range itemCreate.DrawingItem.Points {
-            points = append(points, schemas.Point{
-                X: p.X,
-                Y: p.Y,
-            })
+    // Create item in the workspace and make it undoable
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        // Verify workspace exists
+        var workspace schemas.Workspace
+        if err := tx.Select("id").First(&workspace, item.WorkspaceID).Error; err != nil {
+            if errors.Is(err, gorm.ErrRecordNotFound) {
+                return fiber.NewError(fiber.StatusNotFound, "workspace not found")
+            }
+            return err
         }
-        item.DrawingItem = &schemas.DrawingItem{
-            Points: points,
+        if err := autoSnapshot(c.Context(), tx, item.WorkspaceID); err != nil {
+            return err
         }
-    }
-
-    // Find workspace
-    var workspace schemas.Workspace
-    if err := database.DB.
-        Preload("Items").
-        First(&workspace, "user_id = ?", userID).
-        Error; err != nil {
-        
-        if errors.Is(err, gorm.ErrRecordNotFound) {
-            return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
-                Error: "workspace not found",
-            })
+        if err := tx.Create(&item).Error; err != nil {
+            return err
         }
-        return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-            Error: "failed to find workspace",
-        })
+        return recordHistory(tx, item.WorkspaceID, userID, []itemChange{newItemChange(nil, &item)})
+    })
+    if err != nil {
+        return errorResponse(c, err, "failed to create item")
     }
 
-    // Create item
-    if err := database.DB.Create(&item).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-            Error: "failed to create item",
-        })
+    if imageAsset != nil {
+        item.ImageItem.Asset = imageAsset
     }
 
-    // Associate with workspace
-    if err := database.DB.Model(&workspace).Association("Items").Append(&item); err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-            Error: "failed to associate item with workspace",
-        })
-    }
+    publishItemEvent(realtime.ItemCreated, item.WorkspaceID, item.ID, &item)
 
+    c.Set(fiber.HeaderETag, itemETag(item.Version))
     return c.Status(fiber.StatusCreated).JSON(models.CreatedResponse{
         Message: "item created successfully",
         ID:      item.ID,
@@ -361,9 +244,12 @@ func AppendMyWorkspaceItem(c *fiber.Ctx) error {
 // @Produce json
 // @Security BearerAuth
 // @Param item_id path int true "Item ID"
+// @Param If-Match header string false "ETag of the item the change is based on"
+// @Param version query int false "Version the change is based on, if If-Match is not sent"
 // @Success 200 {object} models.MessageResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ConflictResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /workspaces/my/items/{item_id} [delete]
 func DeleteMyWorkspaceItem(c *fiber.Ctx) error {
@@ -375,6 +261,11 @@ func DeleteMyWorkspaceItem(c *fiber.Ctx) error {
 		})
 	}
 
+    workspaceID, err := myWorkspaceID(userID)
+    if err != nil {
+        return errorResponse(c, err, "failed to find workspace")
+    }
+
     itemID, err := c.ParamsInt("item_id")
     if err != nil || itemID < 1 {
         return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
@@ -382,41 +273,383 @@ func DeleteMyWorkspaceItem(c *fiber.Ctx) error {
         })
     }
 
+    version, err := expectedVersion(c, nil)
+    if err != nil {
+        return errorResponse(c, err, "failed to delete item")
+    }
+
     // Execute in transaction
     err = database.DB.Transaction(func(tx *gorm.DB) error {
         // Verify workspace exists
         var workspace schemas.Workspace
-        if err := tx.Select("user_id").First(&workspace, "user_id = ?", userID).Error; err != nil {
+        if err := tx.Select("id").First(&workspace, workspaceID).Error; err != nil {
             if errors.Is(err, gorm.ErrRecordNotFound) {
                 return fiber.NewError(fiber.StatusNotFound, "workspace not found")
             }
             return err
         }
-
-        // Delete item with workspace verification
-        result := tx.Where("id = ? AND workspace_id = ?", itemID, userID).Delete(&schemas.Item{})
-        if result.Error != nil {
-            return result.Error
-        }
-        
-        if result.RowsAffected == 0 {
-            return fiber.NewError(fiber.StatusNotFound, "item not found in workspace")
+        if err := autoSnapshot(c.Context(), tx, uint(workspaceID)); err != nil {
+            return err
         }
 
-        return nil
+        change, err := deleteItem(tx, uint(workspaceID), uint(itemID), version)
+        if err != nil {
+            return err
+        }
+        return recordHistory(tx, uint(workspaceID), userID, []itemChange{change})
     })
 
     // Handle transaction errors
     if err != nil {
-        if e, ok := err.(*fiber.Error); ok {
-            return c.Status(e.Code).JSON(models.ErrorResponse{Error: e.Message})
-        }
-        return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-            Error: "failed to delete item",
-        })
+        return errorResponse(c, err, "failed to delete item")
     }
 
+    publishItemEvent(realtime.ItemDeleted, workspaceID, uint(itemID), nil)
+
     return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
         Message: "item deleted successfully",
     })
-}
\ No newline at end of file
+}
+// @Summary Update a workspace item by item ID and workspace ID
+// @Description Partially update an item; omitted fields are left unchanged.
+// @Description Requires at least the editor role
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param item_id path int true "Item ID"
+// @Param item body models.ItemUpdate true "Fields to update"
+// @Param If-Match header string false "ETag of the item the change is based on"
+// @Success 200 {object} models.ItemRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ConflictResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/items/{item_id} [patch]
+func UpdateWorkspaceItem(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	return updateWorkspaceItem(c, uint(workspaceID))
+}
+
+// @Summary Update an item in the user's workspace
+// @Description Partially update an item; omitted fields are left unchanged
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param item_id path int true "Item ID"
+// @Param item body models.ItemUpdate true "Fields to update"
+// @Param If-Match header string false "ETag of the item the change is based on"
+// @Success 200 {object} models.ItemRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ConflictResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/my/items/{item_id} [patch]
+func UpdateMyWorkspaceItem(c *fiber.Ctx) error {
+	userID, ok := c.Locals(middleware.IDKey).(uint)
+
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error: "unauthorized",
+		})
+	}
+
+	workspaceID, err := myWorkspaceID(userID)
+	if err != nil {
+		return errorResponse(c, err, "failed to find workspace")
+	}
+
+	return updateWorkspaceItem(c, workspaceID)
+}
+
+func updateWorkspaceItem(c *fiber.Ctx, workspaceID uint) error {
+	itemID, err := c.ParamsInt("item_id")
+	if err != nil || itemID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid item id",
+		})
+	}
+
+	var itemUpdate models.ItemUpdate
+	if err := c.BodyParser(&itemUpdate); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid request body",
+		})
+	}
+
+	version, err := expectedVersion(c, itemUpdate.Version)
+	if err != nil {
+		return errorResponse(c, err, "failed to update item")
+	}
+	itemUpdate.Version = version
+
+	userID, _ := c.Locals(middleware.IDKey).(uint)
+	if err := validateItemUpdate(database.DB, workspaceID, userID, &itemUpdate); err != nil {
+		return errorResponse(c, err, "failed to find asset")
+	}
+
+	var item schemas.Item
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := autoSnapshot(c.Context(), tx, workspaceID); err != nil {
+			return err
+		}
+		var change itemChange
+		item, change, err = updateItem(c.Context(), tx, workspaceID, uint(itemID), &itemUpdate)
+		if err != nil {
+			return err
+		}
+		return recordHistory(tx, workspaceID, userID, []itemChange{change})
+	})
+	if err != nil {
+		return errorResponse(c, err, "failed to update item")
+	}
+
+	publishItemEvent(realtime.ItemUpdated, workspaceID, item.ID, &item)
+
+	c.Set(fiber.HeaderETag, itemETag(item.Version))
+	return c.Status(fiber.StatusOK).JSON(newItemRead(item))
+}
+
+// Check an update before applying it: at most one typed sub-record may be
+// replaced, text may not be emptied, images must show a usable asset and
+// strokes must have a valid style
+func validateItemUpdate(db *gorm.DB, workspaceID, userID uint, itemUpdate *models.ItemUpdate) error {
+	itemTypes := 0
+	if itemUpdate.TextItem != nil { itemTypes++ }
+	if itemUpdate.ImageItem != nil { itemTypes++ }
+	if itemUpdate.TodoList != nil { itemTypes++ }
+	if itemUpdate.ShapeItem != nil { itemTypes++ }
+	if itemUpdate.DrawingItem != nil { itemTypes++ }
+
+	if itemTypes > 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "must provide at most one item type (text, image, todo list, shape, or drawing)")
+	}
+
+	if itemUpdate.Scale != nil && *itemUpdate.Scale <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "scale must be positive")
+	}
+
+	if itemUpdate.TextItem != nil && itemUpdate.TextItem.Content == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "cannot update to an empty text item")
+	}
+
+	if itemUpdate.ImageItem != nil {
+		if err := storeInlineImage(db, itemUpdate.ImageItem, userID, workspaceID); err != nil {
+			return err
+		}
+		if _, err := checkImageAsset(db, itemUpdate.ImageItem.AssetID, workspaceID, userID); err != nil {
+			return err
+		}
+	}
+
+	if itemUpdate.DrawingItem != nil {
+		return validateStroke(itemUpdate.DrawingItem)
+	}
+	return nil
+}
+
+// Apply a partial update to an item and its typed sub-record in one transaction.
+// The sub-record in the update must match the item's existing type, and the
+// item must still be at the update's version if it has one. Returns the item
+// and the change to record.
+func updateItem(ctx context.Context, db *gorm.DB, workspaceID, itemID uint, itemUpdate *models.ItemUpdate) (schemas.Item, itemChange, error) {
+	var item schemas.Item
+	var before *models.ItemCreate
+	var beforeVersion uint64
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if item, err = lockItem(tx, workspaceID, itemID); err != nil {
+			return err
+		}
+		if err := checkItemVersion(item, itemUpdate.Version); err != nil {
+			return err
+		}
+		before, beforeVersion = itemState(item), item.Version
+		item.Version++
+
+		// Geometry and style
+		if itemUpdate.PositionX != nil { item.PositionX = *itemUpdate.PositionX }
+		if itemUpdate.PositionY != nil { item.PositionY = *itemUpdate.PositionY }
+		if itemUpdate.ZIndex != nil { item.ZIndex = *itemUpdate.ZIndex }
+		if itemUpdate.Color != nil { item.Color = *itemUpdate.Color }
+		if itemUpdate.Scale != nil { item.Scale = *itemUpdate.Scale }
+		if itemUpdate.Width != nil { item.Width = *itemUpdate.Width }
+		if itemUpdate.Height != nil { item.Height = *itemUpdate.Height }
+		// New points change the item's bounds, derived as it is saved
+		if itemUpdate.DrawingItem != nil && item.DrawingItem != nil {
+			updateDrawingItem(item.DrawingItem, itemUpdate.DrawingItem)
+		}
+
+		if err := tx.Omit(clause.Associations).Save(&item).Error; err != nil {
+			return err
+		}
+
+		switch {
+		case itemUpdate.TextItem != nil:
+			if item.TextItem == nil {
+				return fiber.NewError(fiber.StatusBadRequest, "item is not a text item")
+			}
+			item.TextItem.Content = itemUpdate.TextItem.Content
+			return tx.Save(item.TextItem).Error
+
+		case itemUpdate.ImageItem != nil:
+			if item.ImageItem == nil {
+				return fiber.NewError(fiber.StatusBadRequest, "item is not an image item")
+			}
+			previous := item.ImageItem.AssetID
+			item.ImageItem.AssetID = itemUpdate.ImageItem.AssetID
+			if err := tx.Omit(clause.Associations).Save(item.ImageItem).Error; err != nil {
+				return err
+			}
+			// With a history, the entry recorded for this change keeps the
+			// previous image until it expires
+			if previous != item.ImageItem.AssetID && historyDepth <= 0 {
+				if err := assets.Release(ctx, tx, []string{previous}); err != nil {
+					return err
+				}
+			}
+			// Reload the new asset's thumbnails
+			item.ImageItem.Asset = nil
+			return tx.Preload("Asset.Variants", orderByID).
+				First(item.ImageItem, "item_id = ? AND workspace_id = ?", item.ID, workspaceID).Error
+
+		case itemUpdate.TodoList != nil:
+			if item.ListItem == nil {
+				return fiber.NewError(fiber.StatusBadRequest, "item is not a todo list")
+			}
+			// Replace the whole list; fields are renumbered from 1
+			if err := tx.
+				Where("todo_list_item_id = ? AND workspace_id = ?", item.ID, workspaceID).
+				Delete(&schemas.TodoListField{}).
+				Error; err != nil {
+				return err
+			}
+			fields := newTodoListFields(*itemUpdate.TodoList)
+			for i := range fields {
+				fields[i].TodoListItemID = item.ID
+				fields[i].WorkspaceID = workspaceID
+			}
+			if len(fields) > 0 {
+				if err := tx.Create(&fields).Error; err != nil {
+					return err
+				}
+			}
+			item.ListItem.TodoListFields = fields
+
+		case itemUpdate.ShapeItem != nil:
+			if item.ShapeItem == nil {
+				return fiber.NewError(fiber.StatusBadRequest, "item is not a shape item")
+			}
+			item.ShapeItem.Name = itemUpdate.ShapeItem.Name
+			return tx.Save(item.ShapeItem).Error
+
+		case itemUpdate.DrawingItem != nil:
+			if item.DrawingItem == nil {
+				return fiber.NewError(fiber.StatusBadRequest, "item is not a drawing item")
+			}
+			return tx.Save(item.DrawingItem).Error
+		}
+
+		return nil
+	})
+	if err != nil {
+		return item, itemChange{}, err
+	}
+
+	change := newItemChange(nil, &item)
+	change.Before, change.BeforeVersion = before, beforeVersion
+	return item, change, nil
+}
+
+// Move an item to the trash, unless the client's version of it is stale.
+// Images it showed are kept until it is purged. Returns the change to record.
+func deleteItem(tx *gorm.DB, workspaceID, itemID uint, version *uint64) (itemChange, error) {
+	item, err := lockItem(tx, workspaceID, itemID)
+	if err != nil {
+		return itemChange{}, err
+	}
+	// Refuse to delete changes the client has not seen
+	if err := checkItemVersion(item, version); err != nil {
+		return itemChange{}, err
+	}
+
+	if _, err := schemas.TrashItem(tx, workspaceID, itemID); err != nil {
+		return itemChange{}, err
+	}
+	return newItemChange(&item, nil), nil
+}
+
+// @Summary Rename or describe a workspace
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param workspace body models.WorkspaceUpdate true "Fields to update"
+// @Success 200 {object} models.WorkspaceInfoRead
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id} [patch]
+func UpdateWorkspace(c *fiber.Ctx) error {
+	workspaceID, err := c.ParamsInt("workspace_id")
+	if err != nil || workspaceID < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid workspace id",
+		})
+	}
+
+	workspaceUpdate := new(models.WorkspaceUpdate)
+	if err := c.BodyParser(workspaceUpdate); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid request body",
+		})
+	}
+
+	var workspace schemas.Workspace
+	if err := database.DB.First(&workspace, workspaceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+				Error: "workspace not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to find workspace",
+		})
+	}
+
+	if workspaceUpdate.Name != nil {
+		name := strings.TrimSpace(*workspaceUpdate.Name)
+		if name == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error: "workspace name cannot be empty",
+			})
+		}
+		workspace.Name = name
+	}
+	if workspaceUpdate.Description != nil {
+		workspace.Description = *workspaceUpdate.Description
+	}
+
+	if err := database.DB.Omit(clause.Associations).Save(&workspace).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error: "failed to update workspace",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newWorkspaceInfoRead(workspace))
+}
@@ -6,8 +6,10 @@ import (
 	"backend/internal/models"
 	"bytes"
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
@@ -25,6 +27,23 @@ func TestAppendMyWorkspaceItem(t *testing.T) {
 	err := schemas.CreateUserWithWorkspace(database.DB, user)
 	assert.NoError(t, err)
 
+	asset := schemas.Asset{
+		ID:          "test-asset",
+		OwnerID:     user.ID,
+		WorkspaceID: user.WorkspaceID,
+		ContentType: "image/png",
+		Size:        4,
+		Variants: []schemas.Asset{{
+			ID:          "test-asset-small",
+			OwnerID:     user.ID,
+			WorkspaceID: user.WorkspaceID,
+			ContentType: "image/png",
+			Size:        2,
+			Variant:     "small",
+		}},
+	}
+	assert.NoError(t, database.DB.Create(&asset).Error)
+
 	app := fiber.New()
 	app.Use(mockAuthMiddleware(user.ID))
 	app.Post("/workspaces/my/items", AppendMyWorkspaceItem)
@@ -53,11 +72,29 @@ func TestAppendMyWorkspaceItem(t *testing.T) {
 				PositionY: 25,
 				ZIndex:    2,
 				ImageItem: &models.ImageItemCreate{
-					Bytes: "imagebytes",
+					AssetID: "test-asset",
 				},
 			},
 			expectedStatus: fiber.StatusCreated,
 		},
+		{
+			name: "Unknown asset error",
+			payload: models.ItemCreate{
+				ImageItem: &models.ImageItemCreate{
+					AssetID: "missing-asset",
+				},
+			},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name: "Thumbnail asset error",
+			payload: models.ItemCreate{
+				ImageItem: &models.ImageItemCreate{
+					AssetID: "test-asset-small",
+				},
+			},
+			expectedStatus: fiber.StatusBadRequest,
+		},
 		{
 			name: "Create TodoList",
 			payload: models.ItemCreate{
@@ -110,7 +147,7 @@ func TestAppendMyWorkspaceItem(t *testing.T) {
 					Content: "text",
 				},
 				ImageItem: &models.ImageItemCreate{
-					Bytes: "bytes",
+					AssetID: "test-asset",
 				},
 			},
 			expectedStatus: fiber.StatusBadRequest,
@@ -137,6 +174,32 @@ func TestAppendMyWorkspaceItem(t *testing.T) {
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
 		})
 	}
+
+	t.Run("Each append takes one revision", func(t *testing.T) {
+		var before []schemas.Item
+		assert.NoError(t, database.DB.Order("id").Find(&before).Error)
+		for i := 0; i < 2; i++ {
+			var counter schemas.WorkspaceCounter
+			assert.NoError(t, database.DB.First(&counter, user.WorkspaceID).Error)
+
+			body, _ := json.Marshal(models.ItemCreate{TextItem: &models.TextItemCreate{Content: "More"}})
+			req := httptest.NewRequest("POST", "/workspaces/my/items", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+			var after schemas.WorkspaceCounter
+			assert.NoError(t, database.DB.First(&after, user.WorkspaceID).Error)
+			assert.Equal(t, counter.Revision+1, after.Revision)
+		}
+
+		var items []schemas.Item
+		assert.NoError(t, database.DB.Order("id").Limit(len(before)).Find(&items).Error)
+		for i := range before {
+			assert.Equal(t, before[i].Revision, items[i].Revision, "Other items should not be saved again")
+		}
+	})
 }
 
 func TestDeleteMyWorkspaceItem(t *testing.T) {
@@ -151,7 +214,7 @@ func TestDeleteMyWorkspaceItem(t *testing.T) {
 
 	// Create an item to delete
 	item := schemas.Item{
-		WorkspaceID: user.ID,
+		WorkspaceID: user.WorkspaceID,
 		PositionX:   10,
 		PositionY:   20,
 		ZIndex:      1,
@@ -171,6 +234,12 @@ func TestDeleteMyWorkspaceItem(t *testing.T) {
 		resp, err := app.Test(req)
 		assert.NoError(t, err)
 		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var count int64
+		database.DB.Model(&schemas.Item{}).Where("id = ?", item.ID).Count(&count)
+		assert.Equal(t, int64(0), count, "Item should leave the board")
+		database.DB.Model(&schemas.TextItem{}).Where("item_id = ?", item.ID).Count(&count)
+		assert.Equal(t, int64(1), count, "Sub-record should be kept in the trash")
 	})
 
 	t.Run("Delete non-existent item", func(t *testing.T) {
@@ -196,3 +265,254 @@ func TestDeleteMyWorkspaceItem(t *testing.T) {
 		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
 	})
 }
+
+func TestUpdateMyWorkspaceItem(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	err := schemas.CreateUserWithWorkspace(database.DB, user)
+	assert.NoError(t, err)
+
+	// Create one item of each editable type
+	items := []schemas.Item{
+		{
+			WorkspaceID: user.WorkspaceID,
+			TextItem:    &schemas.TextItem{Content: "Edit me"},
+		},
+		{
+			WorkspaceID: user.WorkspaceID,
+			ListItem: &schemas.TodoListItem{
+				TodoListFields: []schemas.TodoListField{{ID: 1, Content: "Task 1"}},
+			},
+		},
+		{
+			WorkspaceID: user.WorkspaceID,
+			DrawingItem: &schemas.DrawingItem{
+				Points: []schemas.Point{{X: 1, Y: 1}},
+			},
+		},
+	}
+	for i := range items {
+		err = database.DB.Create(&items[i]).Error
+		assert.NoError(t, err)
+	}
+	textID, listID, drawingID := items[0].ID, items[1].ID, items[2].ID
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Patch("/workspaces/my/items/:item_id", UpdateMyWorkspaceItem)
+
+	patch := func(itemID uint, payload string) (*http.Response, models.ItemRead) {
+		req := httptest.NewRequest("PATCH", "/workspaces/my/items/"+strconv.FormatUint(uint64(itemID), 10), strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+
+		var itemRead models.ItemRead
+		if resp.StatusCode == fiber.StatusOK {
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&itemRead))
+		}
+		return resp, itemRead
+	}
+
+	t.Run("Move and recolor", func(t *testing.T) {
+		resp, itemRead := patch(textID, `{"position_x": 42, "width": 100, "color": "#000000"}`)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, textID, itemRead.ID)
+		assert.Equal(t, float64(42), itemRead.PositionX)
+		assert.Equal(t, float64(100), itemRead.Width)
+		assert.Equal(t, "#000000", itemRead.Color)
+		assert.Equal(t, "Edit me", itemRead.TextItem.Content)
+	})
+
+	t.Run("Edit text content", func(t *testing.T) {
+		resp, itemRead := patch(textID, `{"text": {"content": "Edited"}}`)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "Edited", itemRead.TextItem.Content)
+		assert.Equal(t, float64(42), itemRead.PositionX)
+	})
+
+	t.Run("Replace todo list fields", func(t *testing.T) {
+		resp, itemRead := patch(listID, `{"todo_list": [{"text": {"content": "A"}, "done": true}, {"text": {"content": "B"}}]}`)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, len(itemRead.TodoListItem))
+		assert.Equal(t, "A", itemRead.TodoListItem[0].TextItemRead.Content)
+		assert.True(t, itemRead.TodoListItem[0].Done)
+		assert.Equal(t, "B", itemRead.TodoListItem[1].TextItemRead.Content)
+
+		var count int64
+		database.DB.Model(&schemas.TodoListField{}).Where("todo_list_item_id = ?", listID).Count(&count)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("Replace drawing points", func(t *testing.T) {
+		resp, itemRead := patch(drawingID, `{"drawing": {"points": [{"x": 5, "y": 6}, {"x": 7, "y": 8}]}}`)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, len(itemRead.DrawingItem.Points))
+		assert.Equal(t, float64(7), itemRead.DrawingItem.Points[1].X)
+
+		var drawing schemas.DrawingItem
+		assert.NoError(t, database.DB.Where("item_id = ?", drawingID).First(&drawing).Error)
+		assert.Equal(t, schemas.Points{{X: 5, Y: 6}, {X: 7, Y: 8}}, drawing.Points)
+	})
+
+	t.Run("Mismatched item type", func(t *testing.T) {
+		resp, _ := patch(textID, `{"shape": {"name": "circle"}}`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Multiple item types", func(t *testing.T) {
+		resp, _ := patch(textID, `{"text": {"content": "a"}, "shape": {"name": "circle"}}`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Non-existent item", func(t *testing.T) {
+		resp, _ := patch(9999, `{"position_x": 1}`)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		appNoAuth := fiber.New()
+		appNoAuth.Patch("/workspaces/my/items/:item_id", UpdateMyWorkspaceItem)
+		req := httptest.NewRequest("PATCH", "/workspaces/my/items/1", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := appNoAuth.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+func TestItemVersionConflicts(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, user))
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Post("/workspaces/my/items", AppendMyWorkspaceItem)
+	app.Patch("/workspaces/my/items/:item_id", UpdateMyWorkspaceItem)
+	app.Delete("/workspaces/my/items/:item_id", DeleteMyWorkspaceItem)
+
+	send := func(method, path, ifMatch, payload string) *http.Response {
+		req := httptest.NewRequest(method, path, strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	resp := send("POST", "/workspaces/my/items", "", `{"text": {"content": "Sticky note"}}`)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+	var created models.CreatedResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	path := "/workspaces/my/items/" + strconv.FormatUint(uint64(created.ID), 10)
+
+	// Two devices both start from version 1
+	t.Run("First edit wins", func(t *testing.T) {
+		resp := send("PATCH", path, `"1"`, `{"text": {"content": "From the laptop"}}`)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+
+		var itemRead models.ItemRead
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&itemRead))
+		assert.Equal(t, uint64(2), itemRead.Version)
+	})
+
+	t.Run("Stale edit is refused with the current copy", func(t *testing.T) {
+		for _, tt := range []struct{ ifMatch, payload string }{
+			{`"1"`, `{"text": {"content": "From the phone"}}`},
+			{`W/"1"`, `{"text": {"content": "From the phone"}}`},
+			{"", `{"version": 1, "text": {"content": "From the phone"}}`},
+		} {
+			resp := send("PATCH", path, tt.ifMatch, tt.payload)
+			assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+			assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+
+			var conflict models.ConflictResponse
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&conflict))
+			assert.Equal(t, uint64(2), conflict.Item.Version)
+			assert.Equal(t, "From the laptop", conflict.Item.TextItem.Content)
+		}
+	})
+
+	t.Run("Edits without a version still apply", func(t *testing.T) {
+		resp := send("PATCH", path, "", `{"position_x": 10}`)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		resp = send("PATCH", path, "*", `{"position_x": 20}`)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, `"4"`, resp.Header.Get("ETag"))
+	})
+
+	t.Run("Invalid If-Match", func(t *testing.T) {
+		resp := send("PATCH", path, `"four"`, `{"position_x": 10}`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Stale delete", func(t *testing.T) {
+		resp := send("DELETE", path, `"3"`, "")
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+		resp = send("DELETE", path+"?version=3", "", "")
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+
+		var count int64
+		database.DB.Model(&schemas.Item{}).Where("id = ?", created.ID).Count(&count)
+		assert.Equal(t, int64(1), count, "Item should be kept")
+	})
+
+	t.Run("Current delete", func(t *testing.T) {
+		resp := send("DELETE", path+"?version=4", "", "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestUpdateWorkspace(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	err := schemas.CreateUserWithWorkspace(database.DB, user)
+	assert.NoError(t, err)
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Patch("/workspaces/:workspace_id", UpdateWorkspace)
+
+	patch := func(payload string) (*http.Response, models.WorkspaceInfoRead) {
+		req := httptest.NewRequest("PATCH", "/workspaces/"+strconv.FormatUint(uint64(user.WorkspaceID), 10), strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+
+		var workspaceRead models.WorkspaceInfoRead
+		if resp.StatusCode == fiber.StatusOK {
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&workspaceRead))
+		}
+		return resp, workspaceRead
+	}
+
+	resp, workspaceRead := patch(`{"name": "Renamed", "description": "About"}`)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Renamed", workspaceRead.Name)
+	assert.Equal(t, "About", workspaceRead.Description)
+
+	resp, workspaceRead = patch(`{"description": ""}`)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Renamed", workspaceRead.Name, "Omitted fields should be kept")
+	assert.Equal(t, "", workspaceRead.Description)
+
+	resp, _ = patch(`{"name": ""}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	middleware "backend/internal/middlewares"
+	"backend/internal/realtime"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+const wsWriteTimeout = 10 * time.Second
+
+// How often sockets are pinged, and their token and access checked again
+var wsPingInterval = 30 * time.Second
+
+// Reject plain HTTP requests to websocket routes
+func RequireWebSocketUpgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+	return c.Next()
+}
+
+// @Summary Subscribe to live changes of a workspace
+// @Description Upgrades to a WebSocket that receives a subscribed event, then item.created, item.updated and item.deleted events.
+// @Description Browsers that cannot set headers may pass the access token as a subprotocol, offering "bearer" followed by the token.
+// @Description The socket is closed once the token is revoked or expires, or the user loses access to the workspace.
+// @Tags workspaces
+// @Security BearerAuth
+// @Param workspace_id path int true "Workspace ID"
+// @Param Sec-WebSocket-Protocol header string false "bearer, then the access token"
+// @Success 101 {object} realtime.Event
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 426 {object} models.ErrorResponse
+// @Router /workspaces/{workspace_id}/ws [get]
+func WorkspaceSocket(c *fiber.Ctx) error {
+	// The connection only keeps locals under string keys
+	if userID, ok := c.Locals(middleware.IDKey).(uint); ok {
+		c.Locals("user_id", userID)
+	}
+	if tokenID, ok := c.Locals(middleware.TokenIDKey).(string); ok {
+		c.Locals("token_id", tokenID)
+	}
+	if expires, ok := c.Locals(middleware.TokenExpiresKey).(time.Time); ok {
+		c.Locals("token_expires", expires)
+	}
+	c.Locals("admin", middleware.IsAdmin(c))
+	return workspaceSocket(c)
+}
+
+var workspaceSocket = websocket.New(serveWorkspaceSocket, websocket.Config{
+	// Accepted so that browsers can pass their token after it
+	Subprotocols: []string{middleware.BearerSubprotocol},
+})
+
+// Forward hub events for the workspace until either side goes away
+func serveWorkspaceSocket(conn *websocket.Conn) {
+	workspaceID, err := strconv.ParseUint(conn.Params("workspace_id"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	userID, _ := conn.Locals("user_id").(uint)
+	tokenID, _ := conn.Locals("token_id").(string)
+	expires, _ := conn.Locals("token_expires").(time.Time)
+	admin, _ := conn.Locals("admin").(bool)
+	session := realtime.DefaultSessions.Open(userID, uint(workspaceID), tokenID)
+	defer session.Close()
+
+	sub := realtime.DefaultHub.Subscribe(uint(workspaceID))
+	defer sub.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := conn.WriteJSON(realtime.Event{
+		Type:        realtime.Subscribed,
+		WorkspaceID: uint(workspaceID),
+	}); err != nil {
+		return
+	}
+
+	// Clients only listen; reading still has to happen to notice closes
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				// Dropped by the hub for falling behind
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"),
+					time.Now().Add(wsWriteTimeout))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-session.Revoked():
+			closeRevokedSocket(conn, session.Reason())
+			return
+		case <-ping.C:
+			if !expires.IsZero() && time.Now().After(expires) {
+				closeRevokedSocket(conn, "token expired")
+				return
+			}
+			// Tokens revoked and members removed on other instances are
+			// only seen here
+			if tokenID != "" {
+				if revoked, err := database.IsTokenRevoked(tokenID); err == nil && revoked {
+					closeRevokedSocket(conn, realtime.ReasonTokenRevoked)
+					return
+				}
+			}
+			if !admin {
+				role, err := middleware.WorkspaceRole(database.DB, userID, uint(workspaceID))
+				if err == nil && !schemas.WorkspaceRoleAtLeast(role, schemas.WorkspaceRoleViewer) {
+					closeRevokedSocket(conn, realtime.ReasonAccessRevoked)
+					return
+				}
+			}
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+func closeRevokedSocket(conn *websocket.Conn, reason string) {
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason),
+		time.Now().Add(wsWriteTimeout))
+}
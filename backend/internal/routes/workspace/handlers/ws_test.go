@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"backend/internal/database"
+	"backend/internal/database/schemas"
+	middleware "backend/internal/middlewares"
+	"backend/internal/realtime"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspaceSocket(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	user := &schemas.User{
+		Login:        "testuser",
+		PasswordHash: "hashedpassword",
+	}
+	err := schemas.CreateUserWithWorkspace(database.DB, user)
+	assert.NoError(t, err)
+
+	app := fiber.New()
+	app.Use(mockAuthMiddleware(user.ID))
+	app.Get("/workspaces/:workspace_id/ws", RequireWebSocketUpgrade, WorkspaceSocket)
+	app.Post("/workspaces/my/items", AppendMyWorkspaceItem)
+	app.Patch("/workspaces/my/items/:item_id", UpdateMyWorkspaceItem)
+	app.Delete("/workspaces/my/items/:item_id", DeleteMyWorkspaceItem)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	url := fmt.Sprintf("ws://%s/workspaces/%d/ws", ln.Addr(), user.WorkspaceID)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to dial websocket: %v", err)
+	}
+	defer conn.Close()
+
+	next := func() realtime.Event {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var event realtime.Event
+		assert.NoError(t, conn.ReadJSON(&event))
+		return event
+	}
+
+	send := func(method, path, body string) {
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Less(t, resp.StatusCode, 300)
+	}
+
+	event := next()
+	assert.Equal(t, realtime.Subscribed, event.Type)
+	assert.Equal(t, user.WorkspaceID, event.WorkspaceID)
+
+	send("POST", "/workspaces/my/items", `{"text": {"content": "Live"}}`)
+	event = next()
+	assert.Equal(t, realtime.ItemCreated, event.Type)
+	assert.Equal(t, user.WorkspaceID, event.WorkspaceID)
+	if assert.NotNil(t, event.Item) {
+		assert.Equal(t, "Live", event.Item.TextItem.Content)
+	}
+	itemPath := "/workspaces/my/items/" + strconv.FormatUint(uint64(event.ItemID), 10)
+
+	send("PATCH", itemPath, `{"position_x": 5}`)
+	event = next()
+	assert.Equal(t, realtime.ItemUpdated, event.Type)
+	if assert.NotNil(t, event.Item) {
+		assert.Equal(t, float64(5), event.Item.PositionX)
+	}
+
+	send("DELETE", itemPath, "")
+	event = next()
+	assert.Equal(t, realtime.ItemDeleted, event.Type)
+	assert.Nil(t, event.Item)
+
+	t.Run("Plain HTTP is rejected", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/workspaces/1/ws", nil)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusUpgradeRequired, resp.StatusCode)
+	})
+
+	t.Run("Revoking the token closes the socket", func(t *testing.T) {
+		app := fiber.New()
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals(middleware.IDKey, user.ID)
+			c.Locals(middleware.TokenIDKey, "socket-jti")
+			return c.Next()
+		})
+		app.Get("/workspaces/:workspace_id/ws", RequireWebSocketUpgrade, WorkspaceSocket)
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		go app.Listener(ln)
+		defer app.Shutdown()
+
+		dialer := websocket.Dialer{Subprotocols: []string{middleware.BearerSubprotocol, "token"}}
+		url := fmt.Sprintf("ws://%s/workspaces/%d/ws", ln.Addr(), user.WorkspaceID)
+		conn, resp, err := dialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("failed to dial websocket: %v", err)
+		}
+		defer conn.Close()
+		assert.Equal(t, middleware.BearerSubprotocol, resp.Header.Get("Sec-WebSocket-Protocol"),
+			"The token should not be echoed back")
+
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var event realtime.Event
+		assert.NoError(t, conn.ReadJSON(&event))
+		assert.Equal(t, realtime.Subscribed, event.Type)
+
+		realtime.DefaultSessions.RevokeToken("socket-jti")
+		_, _, err = conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation))
+	})
+}
+
+func TestWorkspaceSocketAccess(t *testing.T) {
+	database.DB = setupTestDB(t)
+
+	owner := &schemas.User{Login: "owner", PasswordHash: "hashedpassword"}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, owner))
+	member := &schemas.User{Login: "member", PasswordHash: "hashedpassword"}
+	assert.NoError(t, schemas.CreateUserWithWorkspace(database.DB, member))
+
+	defer func(interval time.Duration) { wsPingInterval = interval }(wsPingInterval)
+
+	// Dial the owner's workspace as a viewer whose token expires at expires
+	var app *fiber.App
+	dial := func(t *testing.T, expires time.Time) *websocket.Conn {
+		assert.NoError(t, database.DB.Save(&schemas.WorkspaceMember{
+			WorkspaceID: owner.WorkspaceID, UserID: member.ID, Role: schemas.WorkspaceRoleViewer,
+		}).Error)
+
+		app = fiber.New()
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals(middleware.IDKey, member.ID)
+			if !expires.IsZero() {
+				c.Locals(middleware.TokenExpiresKey, expires)
+			}
+			return c.Next()
+		})
+		app.Get("/workspaces/:workspace_id/ws", RequireWebSocketUpgrade, WorkspaceSocket)
+		app.Delete("/workspaces/:workspace_id/members/:user_id", RemoveWorkspaceMember)
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		go app.Listener(ln)
+		t.Cleanup(func() { app.Shutdown() })
+
+		url := fmt.Sprintf("ws://%s/workspaces/%d/ws", ln.Addr(), owner.WorkspaceID)
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("failed to dial websocket: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var event realtime.Event
+		assert.NoError(t, conn.ReadJSON(&event))
+		assert.Equal(t, realtime.Subscribed, event.Type)
+		return conn
+	}
+
+	// Skip pings until the socket is closed, and check why
+	closedWith := func(t *testing.T, conn *websocket.Conn, reason string) {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		for {
+			_, _, err := conn.ReadMessage()
+			if err == nil {
+				continue
+			}
+			var closeErr *websocket.CloseError
+			if assert.ErrorAs(t, err, &closeErr) {
+				assert.Equal(t, websocket.ClosePolicyViolation, closeErr.Code)
+				assert.Equal(t, reason, closeErr.Text)
+			}
+			return
+		}
+	}
+
+	t.Run("Removing the member closes the socket", func(t *testing.T) {
+		wsPingInterval = time.Hour
+		conn := dial(t, time.Time{})
+
+		path := fmt.Sprintf("/workspaces/%d/members/%d", owner.WorkspaceID, member.ID)
+		resp, err := app.Test(httptest.NewRequest("DELETE", path, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		closedWith(t, conn, realtime.ReasonAccessRevoked)
+	})
+
+	t.Run("Access lost elsewhere is noticed on the next ping", func(t *testing.T) {
+		wsPingInterval = 20 * time.Millisecond
+		conn := dial(t, time.Time{})
+
+		// As another instance would, without signalling this one
+		assert.NoError(t, database.DB.
+			Where("workspace_id = ? AND user_id = ?", owner.WorkspaceID, member.ID).
+			Delete(&schemas.WorkspaceMember{}).Error)
+		closedWith(t, conn, realtime.ReasonAccessRevoked)
+	})
+
+	t.Run("Expired tokens close the socket", func(t *testing.T) {
+		wsPingInterval = 20 * time.Millisecond
+		conn := dial(t, time.Now().Add(100*time.Millisecond))
+		closedWith(t, conn, "token expired")
+	})
+}
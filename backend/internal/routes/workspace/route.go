@@ -1,16 +1,84 @@
 package workspace
 
 import (
+	"backend/internal/database/schemas"
+	middleware "backend/internal/middlewares"
 	"backend/internal/routes/workspace/handlers"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// Imports may be larger than other requests. Set up before the default limit.
+func SetupWorkspaceBodyLimits(app *fiber.App) {
+	app.Use([]string{"/workspaces/my/import", "/workspaces/:workspace_id/import"}, middleware.BodyLimit(middleware.ImportBodyLimit))
+}
+
 func SetupWorkspaceRoutes(app *fiber.App) {
-	app.Get("/workspaces/my", handlers.GetMyWorkspace)
-	app.Post("/workspaces/my/items", handlers.AppendMyWorkspaceItem)
-	app.Delete("/workspaces/my/items/:item_id", handlers.DeleteMyWorkspaceItem)
-	app.Get("/workspaces/:user_id", handlers.GetWorkspace)
-	app.Post("/workspaces/:user_id/items", handlers.AppendWorkspaceItem)
-	app.Delete("/workspaces/:user_id/items/:item_id", handlers.DeleteWorkspaceItem)
-}
\ No newline at end of file
+	access := middleware.RequireWorkspaceAccess("workspace_id")
+	editor := middleware.RequireWorkspaceRole("workspace_id", schemas.WorkspaceRoleEditor)
+	owner := middleware.RequireWorkspaceOwner("workspace_id")
+
+	app.Get("/workspaces", middleware.RequireAuth, handlers.ListWorkspaces)
+	app.Post("/workspaces", middleware.RequireAuth, handlers.CreateWorkspace)
+	app.Get("/workspaces/shared-with-me", middleware.RequireAuth, handlers.ListSharedWorkspaces)
+	app.Get("/workspaces/my", middleware.RequireAuth, handlers.GetMyWorkspace)
+	app.Post("/workspaces/my/images", middleware.RequireAuth, handlers.UploadMyWorkspaceImage)
+	app.Get("/workspaces/my/changes", middleware.RequireAuth, handlers.ListMyWorkspaceChanges)
+	app.Get("/workspaces/my/export.svg", middleware.RequireAuth, handlers.ExportMyWorkspaceSVG)
+	app.Get("/workspaces/my/export.png", middleware.RequireAuth, handlers.ExportMyWorkspacePNG)
+	app.Get("/workspaces/my/export.pdf", middleware.RequireAuth, handlers.ExportMyWorkspacePDF)
+	app.Get("/workspaces/my/export.json", middleware.RequireAuth, handlers.ExportMyWorkspaceJSON)
+	app.Post("/workspaces/my/import", middleware.RequireAuth, handlers.ImportMyWorkspace)
+	app.Get("/workspaces/my/export.excalidraw", middleware.RequireAuth, handlers.ExportMyWorkspaceExcalidraw)
+	app.Post("/workspaces/my/import/excalidraw", middleware.RequireAuth, handlers.ImportMyWorkspaceExcalidraw)
+	app.Get("/workspaces/my/items", middleware.RequireAuth, handlers.ListMyWorkspaceItems)
+	app.Post("/workspaces/my/items", middleware.RequireAuth, handlers.AppendMyWorkspaceItem)
+	app.Post("/workspaces/my/items\\:batch", middleware.RequireAuth, handlers.BatchMyWorkspaceItems)
+	app.Patch("/workspaces/my/items/:item_id", middleware.RequireAuth, handlers.UpdateMyWorkspaceItem)
+	app.Delete("/workspaces/my/items/:item_id", middleware.RequireAuth, handlers.DeleteMyWorkspaceItem)
+	app.Post("/workspaces/my/undo", middleware.RequireAuth, handlers.UndoMyWorkspace)
+	app.Post("/workspaces/my/redo", middleware.RequireAuth, handlers.RedoMyWorkspace)
+	app.Get("/workspaces/my/trash", middleware.RequireAuth, handlers.ListMyWorkspaceTrash)
+	app.Post("/workspaces/my/trash/:item_id/restore", middleware.RequireAuth, handlers.RestoreMyWorkspaceTrashItem)
+	app.Delete("/workspaces/my/trash/:item_id", middleware.RequireAuth, handlers.PurgeMyWorkspaceTrashItem)
+	app.Get("/workspaces/my/snapshots", middleware.RequireAuth, handlers.ListMyWorkspaceSnapshots)
+	app.Post("/workspaces/my/snapshots", middleware.RequireAuth, handlers.CreateMyWorkspaceSnapshot)
+	app.Get("/workspaces/my/snapshots/:snapshot_id", middleware.RequireAuth, handlers.GetMyWorkspaceSnapshot)
+	app.Delete("/workspaces/my/snapshots/:snapshot_id", middleware.RequireAuth, handlers.DeleteMyWorkspaceSnapshot)
+	app.Post("/workspaces/my/snapshots/:snapshot_id/restore", middleware.RequireAuth, handlers.RestoreMyWorkspaceSnapshot)
+	app.Get("/workspaces/:workspace_id", access, handlers.GetWorkspace)
+	app.Patch("/workspaces/:workspace_id", owner, handlers.UpdateWorkspace)
+	app.Delete("/workspaces/:workspace_id", owner, handlers.DeleteWorkspace)
+	app.Get("/workspaces/:workspace_id/members", access, handlers.ListWorkspaceMembers)
+	app.Post("/workspaces/:workspace_id/members", owner, handlers.InviteWorkspaceMember)
+	app.Patch("/workspaces/:workspace_id/members/:user_id", owner, handlers.UpdateWorkspaceMember)
+	app.Delete("/workspaces/:workspace_id/members/:user_id", access, handlers.RemoveWorkspaceMember)
+	app.Get("/workspaces/:workspace_id/changes", access, handlers.ListWorkspaceChanges)
+	app.Get("/workspaces/:workspace_id/export.svg", access, handlers.ExportWorkspaceSVG)
+	app.Get("/workspaces/:workspace_id/export.png", access, handlers.ExportWorkspacePNG)
+	app.Get("/workspaces/:workspace_id/export.pdf", access, handlers.ExportWorkspacePDF)
+	app.Get("/workspaces/:workspace_id/export.json", access, handlers.ExportWorkspaceJSON)
+	app.Post("/workspaces/:workspace_id/import", editor, handlers.ImportWorkspace)
+	app.Get("/workspaces/:workspace_id/export.excalidraw", access, handlers.ExportWorkspaceExcalidraw)
+	app.Post("/workspaces/:workspace_id/import/excalidraw", editor, handlers.ImportWorkspaceExcalidraw)
+	app.Get("/workspaces/:workspace_id/items", access, handlers.ListWorkspaceItems)
+	app.Post("/workspaces/:workspace_id/items", editor, handlers.AppendWorkspaceItem)
+	app.Post("/workspaces/:workspace_id/items\\:batch", editor, handlers.BatchWorkspaceItems)
+	app.Patch("/workspaces/:workspace_id/items/:item_id", editor, handlers.UpdateWorkspaceItem)
+	app.Delete("/workspaces/:workspace_id/items/:item_id", editor, handlers.DeleteWorkspaceItem)
+	app.Post("/workspaces/:workspace_id/undo", editor, handlers.UndoWorkspace)
+	app.Post("/workspaces/:workspace_id/redo", editor, handlers.RedoWorkspace)
+	app.Get("/workspaces/:workspace_id/trash", access, handlers.ListWorkspaceTrash)
+	app.Post("/workspaces/:workspace_id/trash/:item_id/restore", editor, handlers.RestoreWorkspaceTrashItem)
+	app.Delete("/workspaces/:workspace_id/trash/:item_id", editor, handlers.PurgeWorkspaceTrashItem)
+	app.Get("/workspaces/:workspace_id/snapshots", access, handlers.ListWorkspaceSnapshots)
+	app.Post("/workspaces/:workspace_id/snapshots", editor, handlers.CreateWorkspaceSnapshot)
+	app.Get("/workspaces/:workspace_id/snapshots/:snapshot_id", access, handlers.GetWorkspaceSnapshot)
+	app.Delete("/workspaces/:workspace_id/snapshots/:snapshot_id", owner, handlers.DeleteWorkspaceSnapshot)
+	app.Post("/workspaces/:workspace_id/snapshots/:snapshot_id/restore", editor, handlers.RestoreWorkspaceSnapshot)
+	app.Get("/workspaces/:workspace_id/ws",
+		handlers.RequireWebSocketUpgrade,
+		access,
+		handlers.WorkspaceSocket,
+	)
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"backend/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// A flat key/value store for binary objects such as uploaded images
+type ObjectStore interface {
+	// Store the object under key, replacing any previous one; size is the
+	// exact length of r
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open the object stored under key; ErrNotFound if there is none
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Remove the object stored under key; removing a missing key is not an
+	// error
+	Delete(ctx context.Context, key string) error
+}
+
+// The store used by the application, set up by Init
+var Default ObjectStore
+
+// Set up Default from the STORAGE_* settings
+func Init() error {
+	var err error
+	switch config.C.StorageBackend {
+	case "fs":
+		Default, err = NewFileStore(config.C.StoragePath)
+	case "s3":
+		Default, err = NewS3Store(context.Background(), S3Config{
+			Endpoint:  config.C.S3Endpoint,
+			Bucket:    config.C.S3Bucket,
+			AccessKey: config.C.S3AccessKey,
+			SecretKey: config.C.S3SecretKey,
+			Region:    config.C.S3Region,
+			UseSSL:    config.C.S3UseSSL,
+		})
+	default:
+		return fmt.Errorf("unknown STORAGE_BACKEND %q; expected fs or s3", config.C.StorageBackend)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to set up object store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/stretchr/testify/assert"
+)
+
+// Behaviour every ObjectStore must share
+func testObjectStore(t *testing.T, store ObjectStore) {
+	ctx := context.Background()
+	data := []byte("\x89PNG\r\n\x1a\nnot really a png")
+
+	_, err := store.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	err = store.Put(ctx, "object", bytes.NewReader(data), int64(len(data)), "image/png")
+	assert.NoError(t, err)
+
+	r, err := store.Get(ctx, "object")
+	if assert.NoError(t, err) {
+		got, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, data, got)
+		r.Close()
+	}
+
+	err = store.Put(ctx, "object", strings.NewReader("replaced"), 8, "text/plain")
+	assert.NoError(t, err)
+	r, err = store.Get(ctx, "object")
+	if assert.NoError(t, err) {
+		got, _ := io.ReadAll(r)
+		assert.Equal(t, "replaced", string(got))
+		r.Close()
+	}
+
+	assert.NoError(t, store.Delete(ctx, "object"))
+	_, err = store.Get(ctx, "object")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, store.Delete(ctx, "object"), "Deleting a missing object should succeed")
+}
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	assert.NoError(t, err)
+	testObjectStore(t, store)
+
+	err = store.Put(context.Background(), "../escape", strings.NewReader("x"), 1, "text/plain")
+	assert.Error(t, err, "Keys must not leave the root directory")
+}
+
+func TestS3Store(t *testing.T) {
+	// In-memory stand-in for MinIO
+	faker := gofakes3.New(s3mem.New())
+	server := httptest.NewServer(faker.Server())
+	defer server.Close()
+
+	store, err := NewS3Store(context.Background(), S3Config{
+		Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+		Bucket:    "assets",
+		AccessKey: "minioadmin",
+		SecretKey: "minioadmin",
+		Region:    "us-east-1",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	testObjectStore(t, store)
+
+	// Connecting again reuses the bucket
+	_, err = NewS3Store(context.Background(), S3Config{
+		Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+		Bucket:    "assets",
+		AccessKey: "minioadmin",
+		SecretKey: "minioadmin",
+		Region:    "us-east-1",
+	})
+	assert.NoError(t, err)
+}